@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
+	_ "time/tzdata" // Hotel opening hours are in IANA time zones, also on hosts without a zoneinfo database
 
 	"mcd/config"
+	"mcd/domain"
 
 	"github.com/labstack/echo/v4"
 	"gorm.io/driver/mysql"
@@ -14,7 +17,11 @@ import (
 	"gorm.io/plugin/dbresolver"
 
 	mcddelivery "mcd/mcd/delivery/http"
+	"mcd/mcd/notifier"
+	"mcd/mcd/payment"
+	memoryrepository "mcd/mcd/repository/memory"
 	mcdrepository "mcd/mcd/repository/mysql"
+	"mcd/mcd/search"
 	mcdusecase "mcd/mcd/usecase"
 )
 
@@ -23,33 +30,106 @@ var (
 )
 
 func init() {
-	//Initialize config
-	config.InitializeConfig()
 	e = echo.New()
 }
 
 func main() {
-	//Load Database config from config.yml
-	err := config.GetDatabaseConfig()
+	//Initialize config from config.yml and the environment
+	err := config.InitializeConfig()
 	if err != nil {
-		log.Println(err.Error())
+		log.Fatal(err)
+	}
+	appConfig, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	// rdb := cacheServices.InitRedisCacheService()
+	// cacheService := cacheServices.NewRedisCacheService(rdb)
+
+	// res, err := cacheService.CheckRedisConnection()
+
+	// if err != nil {
+	// 	fmt.Println("Redis not connected properly", err)
+	// 	return
+	// } else {
+	// 	fmt.Println("Redis connected succesfully....", res)
+	// }
+
+	options := mcdusecase.Options{
+		Notifier:             newNotifier(appConfig.Notifier),
+		RequireVerifiedLogin: appConfig.Features.RequireVerifiedLogin,
+		SigningKeyID:         appConfig.JWT.ActiveKeyID,
+		SigningKeys:          appConfig.JWT.Keys,
+		AccessTokenTTL:       appConfig.JWT.AccessTokenTTL,
+		RefreshTokenTTL:      appConfig.JWT.RefreshTokenTTL,
+		PaymentTimeout:       appConfig.Orders.PaymentTimeout,
+		IdempotencyKeyTTL:    appConfig.Orders.IdempotencyKeyTTL,
+		IdempotencyLease:     appConfig.Orders.IdempotencyLease,
+		PaymentGateway:       newPaymentGateway(appConfig.Payment),
+		RefundRetryAfter:     appConfig.Payment.RefundRetryAfter,
+		Currency:             appConfig.Payment.Currency,
+		SearchIndex:          newSearchIndex(appConfig.Search),
+	}
+	usecase := mcdusecase.NewUseCase(newRepository(appConfig), options)
+	indexed, err := usecase.RebuildSearchIndex()
+	if err != nil {
+		log.Fatal("failed to build the search index: ", err)
+	}
+	log.Printf("Indexed %d hotels and products for search", indexed)
+	// The index only sees changes made through this instance, rebuild it to catch up with the others
+	go runEvery(context.Background(), appConfig.Search.RebuildInterval, func() {
+		if _, err := usecase.RebuildSearchIndex(); err != nil {
+			log.Printf("Error rebuilding the search index: %v", err)
+		}
+	})
+	// Cancel unpaid orders past their payment deadline so the stock they reserved can be sold again
+	go runEvery(context.Background(), appConfig.Orders.ReservationSweepInterval, func() {
+		if _, err := usecase.ReleaseExpiredReservations(); err != nil {
+			log.Printf("Error releasing expired stock reservations: %v", err)
+		}
+	})
+	// Settle refunds whose request failed after reserving them, the gateway refunds each only
+	// once, and drop the authorizations of ended orders the gateway could not be told about
+	go runEvery(context.Background(), appConfig.Payment.RefundRetryAfter, func() {
+		if _, err := usecase.RetryPendingRefunds(); err != nil {
+			log.Printf("Error retrying pending refunds: %v", err)
+		}
+		if _, err := usecase.RetryPendingVoids(); err != nil {
+			log.Printf("Error retrying pending voids: %v", err)
+		}
+	})
+	go runEvery(context.Background(), appConfig.Orders.IdempotencyKeyTTL, func() {
+		if _, err := usecase.PurgeExpiredIdempotencyKeys(); err != nil {
+			log.Printf("Error purging expired idempotency keys: %v", err)
+		}
+	})
+	mcddelivery.NewMCDHandler(e, usecase)
+	// bbDelivery.NewBBHandler(e, bbUsecase.NewUser(bbRepository.NewUser(db), cacheService))
+	e.Server.ReadTimeout = appConfig.Server.ReadTimeout
+	e.Server.WriteTimeout = appConfig.Server.WriteTimeout
+	e.Server.IdleTimeout = appConfig.Server.IdleTimeout
+	log.Fatal(e.Start(":" + appConfig.Server.Port))
+
+}
+
+// newRepository builds the repository selected by DB_TYPE
+func newRepository(appConfig config.AppConfig) domain.MCDRepository {
+	if appConfig.Database.Type == "memory" {
+		log.Println("Using the in-memory repository, data is lost on restart")
+		return memoryrepository.NewRepository()
 	}
 
 	// Establish data base connection
-	db, err := gorm.Open(mysql.Open(config.DatabaseConfig.DatabaseURL), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	db, err := gorm.Open(mysql.Open(appConfig.Database.DatabaseURL), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
 	if err != nil {
-		log.Println(err.Error())
+		log.Fatal("failed to connect to database: ", err)
 	}
 
 	// Specifying DB Reader and Writer
-	err = db.Use(dbresolver.Register(dbresolver.Config{
-		Sources:  []gorm.Dialector{mysql.Open(config.DatabaseConfig.DatabaseWriteURL)},
-		Replicas: []gorm.Dialector{mysql.Open(config.DatabaseConfig.DatabaseReadURL)},
-		Policy:   dbresolver.RandomPolicy{},
-	}))
-
+	err = db.Use(newResolver(appConfig.Database, appConfig.Replica))
 	if err != nil {
-		log.Println(err.Error())
+		log.Fatal("failed to register database resolver: ", err)
 	}
 
 	sqlDB, err := db.DB()
@@ -57,30 +137,80 @@ func main() {
 		log.Fatal("failed to get database handle: ", err)
 	}
 
-	sqlDB.SetMaxOpenConns(15)                  // Maximum number of open connections
-	sqlDB.SetMaxIdleConns(15)                  // Maximum number of idle connections
-	sqlDB.SetConnMaxLifetime(15 * time.Minute) // Maximum connection lifetime
-	sqlDB.SetConnMaxIdleTime(2 * time.Minute)  // Maximum idle time before connection is reused
+	sqlDB.SetMaxOpenConns(appConfig.Pool.MaxOpenConns)       // Maximum number of open connections
+	sqlDB.SetMaxIdleConns(appConfig.Pool.MaxIdleConns)       // Maximum number of idle connections
+	sqlDB.SetConnMaxLifetime(appConfig.Pool.ConnMaxLifetime) // Maximum connection lifetime
+	sqlDB.SetConnMaxIdleTime(appConfig.Pool.ConnMaxIdleTime) // Maximum idle time before connection is reused
 
 	fmt.Println("DATABASE CONNECTED SUCCESSFULLY")
 
-	// rdb := cacheServices.InitRedisCacheService()
-	// cacheService := cacheServices.NewRedisCacheService(rdb)
+	return mcdrepository.NewRepository(db, appConfig.Replica.ReadYourWritesWindow)
+}
 
-	// res, err := cacheService.CheckRedisConnection()
+// runEvery calls task on every interval until ctx is done
+func runEvery(ctx context.Context, interval time.Duration, task func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		task()
+	}
+}
 
-	// if err != nil {
-	// 	fmt.Println("Redis not connected properly", err)
-	// 	return
-	// } else {
-	// 	fmt.Println("Redis connected succesfully....", res)
-	// }
+// newNotifier builds the notifier selected by NOTIFIER_TYPE
+func newNotifier(notifierConfig config.NotifierConfig) domain.Notifier {
+	switch notifierConfig.Type {
+	case "file":
+		return notifier.NewFileNotifier(notifierConfig.FilePath)
+	case "smtp":
+		return notifier.NewSMTPNotifier(notifier.SMTPConfig{
+			Host:     notifierConfig.SMTPHost,
+			Port:     notifierConfig.SMTPPort,
+			Username: notifierConfig.SMTPUsername,
+			Password: notifierConfig.SMTPPassword,
+			From:     notifierConfig.SMTPFrom,
+		})
+	default:
+		return notifier.NewLogNotifier()
+	}
+}
 
-	mcddelivery.NewMCDHandler(e, mcdusecase.NewUseCase(mcdrepository.NewRepository(db)))
-	// bbDelivery.NewBBHandler(e, bbUsecase.NewUser(bbRepository.NewUser(db), cacheService))
-	// e.Use(echojwt.WithConfig(echojwt.Config{
-	// 	SigningKey: []byte("dinesh-bali"),
-	// }))
-	log.Fatal(e.Start(":" + "8888"))
+// newPaymentGateway builds the gateway selected by PAYMENT_GATEWAY, fake is the only one so far
+func newPaymentGateway(paymentConfig config.PaymentConfig) domain.PaymentGateway {
+	log.Println("Using the fake payment gateway, no money is moved")
+	return payment.NewFakeGateway(paymentConfig.WebhookSecrets, paymentConfig.WebhookTolerance)
+}
+
+// newSearchIndex builds the index selected by SEARCH_INDEX, local is the only one so far
+func newSearchIndex(searchConfig config.SearchConfig) domain.SearchIndex {
+	return search.NewLocalIndex()
+}
+
+// newResolver routes writes to the primary and reads to the healthy replicas. The primary is
+// registered after the replicas so reads fall back to it when every replica is unhealthy.
+func newResolver(dbConfig config.DBConfig, replicaConfig config.ReplicaConfig) *dbresolver.DBResolver {
+	resolverConfig := dbresolver.Config{
+		Sources: []gorm.Dialector{mysql.Open(dbConfig.DatabaseWriteURL)},
+		Policy:  dbresolver.RandomPolicy{},
+	}
+	if len(dbConfig.DatabaseReadURLs) == 0 {
+		return dbresolver.Register(resolverConfig)
+	}
+
+	replicaPolicy, err := mcdrepository.NewReplicaPolicy(dbConfig.DatabaseReadURLs, replicaConfig.MaxLag)
+	if err != nil {
+		log.Fatal("failed to set up replica health checks: ", err)
+	}
+	go replicaPolicy.Monitor(context.Background(), replicaConfig.CheckInterval)
 
+	for _, readURL := range dbConfig.DatabaseReadURLs {
+		resolverConfig.Replicas = append(resolverConfig.Replicas, mysql.Open(readURL))
+	}
+	resolverConfig.Replicas = append(resolverConfig.Replicas, mysql.Open(dbConfig.DatabaseWriteURL))
+	resolverConfig.Policy = replicaPolicy
+	return dbresolver.Register(resolverConfig)
 }
@@ -0,0 +1,32 @@
+// Command webhooksign signs a payment webhook payload the way the gateway does, so events can
+// be sent to a local server by hand:
+//
+//	PAYMENT_WEBHOOK_SECRET=... go run ./app/webhooksign < event.json
+//
+// It prints the Payment-Signature header for the payload read from stdin, signed now with the
+// first secret when PAYMENT_WEBHOOK_SECRET lists several.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"mcd/domain"
+	"mcd/mcd/payment"
+)
+
+func main() {
+	secret, _, _ := strings.Cut(os.Getenv("PAYMENT_WEBHOOK_SECRET"), ",")
+	if secret = strings.TrimSpace(secret); secret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET is required")
+	}
+	payload, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatal("failed to read payload: ", err)
+	}
+	fmt.Printf("%s: %s\n", domain.PaymentSignatureHeader, payment.SignWebhook([]byte(secret), payload, time.Now()))
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -18,64 +20,104 @@ type DBConfig struct {
 	DatabaseName     string
 	Password         string
 	DatabaseURL      string
-	DatabaseReadURL  string
+	DatabaseReadURLs []string // One per replica, empty when reads go to the primary
 	DatabaseWriteURL string
 }
 
-// DatabaseConfig
-var DatabaseConfig DBConfig
-
-// GetDatabaseConfig
-func GetDatabaseConfig() (err error) {
-	//Load the database configuration in the database struct
+// ReplicaConfig - How read replicas are health checked and how long reads stick to the primary after a write
+type ReplicaConfig struct {
+	MaxLag               time.Duration
+	CheckInterval        time.Duration
+	ReadYourWritesWindow time.Duration
+}
 
+// LoadDatabaseConfig - Loads the database configuration from DB_MYSQL_SECRETS or the individual DB_* settings
+func LoadDatabaseConfig() (dbConfig DBConfig, err error) {
 	DB_CREDENTIALS := viper.GetString("DB_MYSQL_SECRETS")
 	if len(DB_CREDENTIALS) > 0 {
-		if err = json.Unmarshal([]byte(DB_CREDENTIALS), &DatabaseConfig); err != nil {
-			fmt.Println("Error:", err)
-			return
+		if err = json.Unmarshal([]byte(DB_CREDENTIALS), &dbConfig); err != nil {
+			return dbConfig, fmt.Errorf("DB_MYSQL_SECRETS is not valid JSON: %w", err)
 		}
-		fmt.Println(DatabaseConfig)
-
 	} else {
-		DatabaseConfig.Type = viper.GetString("DB_TYPE")
-		DatabaseConfig.Username = viper.GetString("DB_USERNAME")
-		DatabaseConfig.Password = viper.GetString("DB_PASSWORD")
-		DatabaseConfig.DatabaseName = viper.GetString("DB_NAME")
-		DatabaseConfig.Host = viper.GetString("DB_HOST")
-		DatabaseConfig.WriteHost = viper.GetString("DB_HOST_WRITE")
-		DatabaseConfig.ReadHost = viper.GetString("DB_HOST_READ")
-		DatabaseConfig.Port = viper.GetString("PORT")
+		dbConfig.Type = viper.GetString("DB_TYPE")
+		dbConfig.Username = viper.GetString("DB_USERNAME")
+		dbConfig.Password = viper.GetString("DB_PASSWORD")
+		dbConfig.DatabaseName = viper.GetString("DB_NAME")
+		dbConfig.Host = viper.GetString("DB_HOST")
+		dbConfig.WriteHost = viper.GetString("DB_HOST_WRITE")
+		dbConfig.ReadHost = viper.GetString("DB_HOST_READ")
+		dbConfig.Port = viper.GetString("DB_PORT")
+	}
+
+	// The in-memory repository needs no connection settings
+	if dbConfig.Type == "memory" {
+		return dbConfig, nil
+	}
+
+	if dbConfig.Host == "" || dbConfig.Port == "" || dbConfig.Username == "" || dbConfig.DatabaseName == "" {
+		return dbConfig, fmt.Errorf("DB_HOST, DB_PORT, DB_USERNAME and DB_NAME are required")
+	}
+
+	// Writes go to DB_HOST_WRITE when set and to DB_HOST otherwise
+	writeHost := dbConfig.WriteHost
+	if writeHost == "" {
+		writeHost = dbConfig.Host
 	}
 
-	DatabaseConfig.DatabaseURL, err = generatedDatabaseURL(DatabaseConfig.Host)
+	dbConfig.DatabaseURL, err = dbConfig.generatedDatabaseURL(writeHost)
 	if err != nil {
-		return err
+		return dbConfig, err
 	}
 
-	DatabaseConfig.DatabaseReadURL, err = generatedDatabaseURL(DatabaseConfig.Host)
+	dbConfig.DatabaseWriteURL, err = dbConfig.generatedDatabaseURL(writeHost)
 	if err != nil {
-		return err
+		return dbConfig, err
 	}
 
-	DatabaseConfig.DatabaseWriteURL, err = generatedDatabaseURL(DatabaseConfig.Host)
-	return err
+	// DB_HOST_READ is a comma separated list of replica hosts
+	dbConfig.DatabaseReadURLs = nil
+	for _, readHost := range strings.Split(dbConfig.ReadHost, ",") {
+		readHost = strings.TrimSpace(readHost)
+		if readHost == "" {
+			continue
+		}
+		readURL, err := dbConfig.generatedDatabaseURL(readHost)
+		if err != nil {
+			return dbConfig, err
+		}
+		dbConfig.DatabaseReadURLs = append(dbConfig.DatabaseReadURLs, readURL)
+	}
+	return dbConfig, nil
+}
+
+func loadReplicaConfig() (replica ReplicaConfig, err error) {
+	replica.MaxLag = viper.GetDuration("DB_REPLICA_MAX_LAG")
+	replica.CheckInterval = viper.GetDuration("DB_REPLICA_CHECK_INTERVAL")
+	replica.ReadYourWritesWindow = viper.GetDuration("DB_READ_YOUR_WRITES_WINDOW")
+
+	if replica.MaxLag <= 0 || replica.CheckInterval <= 0 {
+		return replica, fmt.Errorf("DB_REPLICA_MAX_LAG and DB_REPLICA_CHECK_INTERVAL must be positive durations")
+	}
+	if replica.ReadYourWritesWindow < 0 {
+		return replica, fmt.Errorf("DB_READ_YOUR_WRITES_WINDOW must not be negative")
+	}
+	return replica, nil
 }
 
 // GeneratedDatabaseURL is the function that generated db url
-func generatedDatabaseURL(host string) (databaseURL string, err error) {
+func (dbConfig DBConfig) generatedDatabaseURL(host string) (databaseURL string, err error) {
 
-	if DatabaseConfig.Type == "mysql" {
+	if dbConfig.Type == "mysql" {
 		databaseURL = fmt.Sprintf(
 			"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			DatabaseConfig.Username,
-			DatabaseConfig.Password,
+			dbConfig.Username,
+			dbConfig.Password,
 			host,
-			DatabaseConfig.Port,
-			DatabaseConfig.DatabaseName,
+			dbConfig.Port,
+			dbConfig.DatabaseName,
 		)
 		return
 	}
-	err = fmt.Errorf("invalid database type: %s", DatabaseConfig.Type)
+	err = fmt.Errorf("invalid database type: %s", dbConfig.Type)
 	return
 }
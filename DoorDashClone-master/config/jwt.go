@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// minSigningKeyLength is the shortest HS256 secret accepted, in bytes
+const minSigningKeyLength = 32
+
+// JWTConfig - Token signing settings. Tokens are signed with the active key and
+// carry its id in the kid header, every key in Keys is accepted for verification
+// so old keys can stay valid while tokens signed with them expire.
+type JWTConfig struct {
+	ActiveKeyID     string
+	Keys            map[string][]byte
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+func loadJWTConfig() (jwtConfig JWTConfig, err error) {
+	jwtConfig.ActiveKeyID = viper.GetString("JWT_ACTIVE_KEY_ID")
+	jwtConfig.AccessTokenTTL = viper.GetDuration("JWT_ACCESS_TOKEN_TTL")
+	jwtConfig.RefreshTokenTTL = viper.GetDuration("JWT_REFRESH_TOKEN_TTL")
+
+	jwtConfig.Keys, err = parseSigningKeys(viper.GetString("JWT_SIGNING_KEYS"))
+	if err != nil {
+		return jwtConfig, err
+	}
+	if _, ok := jwtConfig.Keys[jwtConfig.ActiveKeyID]; !ok {
+		return jwtConfig, fmt.Errorf("JWT_ACTIVE_KEY_ID %q must be one of the ids in JWT_SIGNING_KEYS", jwtConfig.ActiveKeyID)
+	}
+	if jwtConfig.AccessTokenTTL <= 0 || jwtConfig.RefreshTokenTTL <= 0 {
+		return jwtConfig, fmt.Errorf("JWT_ACCESS_TOKEN_TTL and JWT_REFRESH_TOKEN_TTL must be positive durations")
+	}
+	return jwtConfig, nil
+}
+
+// parseSigningKeys parses "kid1:secret1,kid2:secret2" into a key set
+func parseSigningKeys(raw string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kid, secret, found := strings.Cut(pair, ":")
+		if !found || kid == "" {
+			return nil, fmt.Errorf("JWT_SIGNING_KEYS entries must look like kid:secret")
+		}
+		if len(secret) < minSigningKeyLength {
+			return nil, fmt.Errorf("JWT signing key %q must be at least %d bytes", kid, minSigningKeyLength)
+		}
+		keys[kid] = []byte(secret)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWT_SIGNING_KEYS is required")
+	}
+	return keys, nil
+}
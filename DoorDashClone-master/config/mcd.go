@@ -1,15 +1,125 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"errors"
+	"fmt"
+	"mcd/domain"
+	"os"
 
-// InitializeConfig initializes the config
-func InitializeConfig() {
+	"github.com/spf13/viper"
+)
+
+// AppConfig - Every setting the service needs, loaded once at startup
+type AppConfig struct {
+	Server   ServerConfig
+	Database DBConfig
+	Pool     PoolConfig
+	Replica  ReplicaConfig
+	JWT      JWTConfig
+	Notifier NotifierConfig
+	Orders   OrderConfig
+	Payment  PaymentConfig
+	Search   SearchConfig
+	Features FeatureFlags
+}
+
+// FeatureFlags - Switches for optional behaviour
+type FeatureFlags struct {
+	RequireVerifiedLogin bool
+}
+
+// InitializeConfig points viper at the config file and the environment.
+// The file is optional so that every value can come from the environment alone.
+func InitializeConfig() error {
+	configFile := os.Getenv("CONFIG_FILE")
+	if configFile == "" {
+		configFile = `../config.yml`
+	}
 	// Set configuration file which will be used to get/set config values
-	viper.SetConfigFile(`../config.yml`)
+	viper.SetConfigFile(configFile)
 	// Ask viper to overwrite any configuration values with their corresponding environment counterparts
 	viper.AutomaticEnv()
+	setDefaults()
+
 	err := viper.ReadInConfig()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to read config file %s: %w", configFile, err)
 	}
+	return nil
+}
+
+// setDefaults registers the values used when neither the file nor the environment sets them
+func setDefaults() {
+	viper.SetDefault("SERVER_PORT", "8888")
+	viper.SetDefault("SERVER_READ_TIMEOUT", "15s")
+	viper.SetDefault("SERVER_WRITE_TIMEOUT", "15s")
+	viper.SetDefault("SERVER_IDLE_TIMEOUT", "60s")
+
+	viper.SetDefault("DB_MAX_OPEN_CONNS", 15)
+	viper.SetDefault("DB_MAX_IDLE_CONNS", 15)
+	viper.SetDefault("DB_CONN_MAX_LIFETIME", "15m")
+	viper.SetDefault("DB_CONN_MAX_IDLE_TIME", "2m")
+	viper.SetDefault("DB_REPLICA_MAX_LAG", "5s")
+	viper.SetDefault("DB_REPLICA_CHECK_INTERVAL", "10s")
+	viper.SetDefault("DB_READ_YOUR_WRITES_WINDOW", "10s")
+
+	viper.SetDefault("JWT_ACCESS_TOKEN_TTL", "1h")
+	viper.SetDefault("JWT_REFRESH_TOKEN_TTL", "720h")
+
+	viper.SetDefault("NOTIFIER_TYPE", "log")
+
+	viper.SetDefault("PAYMENT_TIMEOUT", "15m")
+	viper.SetDefault("RESERVATION_SWEEP_INTERVAL", "1m")
+	viper.SetDefault("IDEMPOTENCY_KEY_TTL", "24h")
+	viper.SetDefault("IDEMPOTENCY_LEASE", "5m")
+
+	viper.SetDefault("PAYMENT_GATEWAY", "fake")
+	viper.SetDefault("PAYMENT_WEBHOOK_TOLERANCE", domain.DefaultWebhookTolerance)
+	viper.SetDefault("CURRENCY", "USD")
+	viper.SetDefault("REFUND_RETRY_AFTER", "5m")
+
+	viper.SetDefault("SEARCH_INDEX", "local")
+	viper.SetDefault("SEARCH_REBUILD_INTERVAL", "5m")
+}
+
+// Load reads and validates the whole configuration. Every invalid setting is
+// reported at once so a misconfigured deployment fails fast with a clear message.
+func Load() (AppConfig, error) {
+	var app AppConfig
+	var err error
+	var errs []error
+
+	if app.Server, err = loadServerConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Database, err = LoadDatabaseConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Pool, err = loadPoolConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Replica, err = loadReplicaConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.JWT, err = loadJWTConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Notifier, err = loadNotifierConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Orders, err = loadOrderConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Payment, err = loadPaymentConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Search, err = loadSearchConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	app.Features.RequireVerifiedLogin = viper.GetBool("REQUIRE_VERIFIED_LOGIN")
+
+	return app, errors.Join(errs...)
 }
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// NotifierConfig - Configuration of the notifier used to deliver OTP codes
+type NotifierConfig struct {
+	Type         string // log, file or smtp
+	FilePath     string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+}
+
+func loadNotifierConfig() (notifierConfig NotifierConfig, err error) {
+	notifierConfig.Type = viper.GetString("NOTIFIER_TYPE")
+	notifierConfig.FilePath = viper.GetString("NOTIFIER_FILE_PATH")
+	notifierConfig.SMTPHost = viper.GetString("SMTP_HOST")
+	notifierConfig.SMTPPort = viper.GetString("SMTP_PORT")
+	notifierConfig.SMTPUsername = viper.GetString("SMTP_USERNAME")
+	notifierConfig.SMTPPassword = viper.GetString("SMTP_PASSWORD")
+	notifierConfig.SMTPFrom = viper.GetString("SMTP_FROM")
+
+	switch notifierConfig.Type {
+	case "", "log":
+		notifierConfig.Type = "log"
+	case "file":
+		if notifierConfig.FilePath == "" {
+			return notifierConfig, fmt.Errorf("NOTIFIER_FILE_PATH is required for the file notifier")
+		}
+	case "smtp":
+		if notifierConfig.SMTPHost == "" || notifierConfig.SMTPPort == "" || notifierConfig.SMTPFrom == "" {
+			return notifierConfig, fmt.Errorf("SMTP_HOST, SMTP_PORT and SMTP_FROM are required for the smtp notifier")
+		}
+	default:
+		return notifierConfig, fmt.Errorf("invalid notifier type: %s", notifierConfig.Type)
+	}
+	return notifierConfig, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// OrderConfig - How long placed orders may hold stock while waiting for payment, and how
+// long retried order requests are recognised by their Idempotency-Key
+type OrderConfig struct {
+	PaymentTimeout           time.Duration // Stock of an unpaid order is released after this
+	ReservationSweepInterval time.Duration
+	IdempotencyKeyTTL        time.Duration
+	IdempotencyLease         time.Duration // A retry takes over the key of a request in flight for longer
+}
+
+func loadOrderConfig() (orders OrderConfig, err error) {
+	orders.PaymentTimeout = viper.GetDuration("PAYMENT_TIMEOUT")
+	orders.ReservationSweepInterval = viper.GetDuration("RESERVATION_SWEEP_INTERVAL")
+	orders.IdempotencyKeyTTL = viper.GetDuration("IDEMPOTENCY_KEY_TTL")
+	orders.IdempotencyLease = viper.GetDuration("IDEMPOTENCY_LEASE")
+
+	// Without a timeout an order that is never paid would hold its stock forever
+	if orders.PaymentTimeout <= 0 {
+		return orders, fmt.Errorf("PAYMENT_TIMEOUT must be a positive duration")
+	}
+	if orders.ReservationSweepInterval <= 0 {
+		return orders, fmt.Errorf("RESERVATION_SWEEP_INTERVAL must be a positive duration")
+	}
+	if orders.IdempotencyKeyTTL <= 0 {
+		return orders, fmt.Errorf("IDEMPOTENCY_KEY_TTL must be a positive duration")
+	}
+	if orders.IdempotencyLease <= 0 {
+		return orders, fmt.Errorf("IDEMPOTENCY_LEASE must be a positive duration")
+	}
+	return orders, nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"mcd/domain"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// PaymentConfig - Payment gateway the service charges orders through
+type PaymentConfig struct {
+	Gateway          string        // fake is the only gateway so far
+	WebhookSecrets   [][]byte      // Shared secrets webhooks may be signed with, the current one first
+	WebhookTolerance time.Duration // Webhooks signed further from now than this are refused as replays
+	Currency         string        // ISO 4217 code of prices created without a currency
+	RefundRetryAfter time.Duration // Refunds and voids still pending after this are retried, as often as this
+}
+
+func loadPaymentConfig() (paymentConfig PaymentConfig, err error) {
+	paymentConfig.Gateway = viper.GetString("PAYMENT_GATEWAY")
+	// A comma separated list, so the old secret keeps working while the gateway switches to a new one
+	for _, secret := range strings.Split(viper.GetString("PAYMENT_WEBHOOK_SECRET"), ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			paymentConfig.WebhookSecrets = append(paymentConfig.WebhookSecrets, []byte(secret))
+		}
+	}
+	paymentConfig.WebhookTolerance = viper.GetDuration("PAYMENT_WEBHOOK_TOLERANCE")
+	paymentConfig.Currency = strings.ToUpper(viper.GetString("CURRENCY"))
+	paymentConfig.RefundRetryAfter = viper.GetDuration("REFUND_RETRY_AFTER")
+
+	if paymentConfig.Gateway != "fake" {
+		return paymentConfig, fmt.Errorf("invalid payment gateway: %s", paymentConfig.Gateway)
+	}
+	if len(paymentConfig.WebhookSecrets) == 0 {
+		return paymentConfig, fmt.Errorf("PAYMENT_WEBHOOK_SECRET is required")
+	}
+	for _, secret := range paymentConfig.WebhookSecrets {
+		if len(secret) < minSigningKeyLength {
+			return paymentConfig, fmt.Errorf("every PAYMENT_WEBHOOK_SECRET must be at least %d bytes", minSigningKeyLength)
+		}
+	}
+	if paymentConfig.WebhookTolerance <= 0 {
+		return paymentConfig, fmt.Errorf("PAYMENT_WEBHOOK_TOLERANCE must be a positive duration")
+	}
+	if paymentConfig.RefundRetryAfter <= 0 {
+		return paymentConfig, fmt.Errorf("REFUND_RETRY_AFTER must be a positive duration")
+	}
+	if !domain.ValidCurrency(paymentConfig.Currency) {
+		return paymentConfig, fmt.Errorf("invalid currency: %s", paymentConfig.Currency)
+	}
+	return paymentConfig, nil
+}
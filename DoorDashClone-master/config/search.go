@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// SearchConfig - Index backing the menu and restaurant search
+type SearchConfig struct {
+	Index           string        // local is the only index so far, it lives in the process and is rebuilt at startup
+	RebuildInterval time.Duration // How often the index is rebuilt to pick up changes made through other instances
+}
+
+func loadSearchConfig() (searchConfig SearchConfig, err error) {
+	searchConfig.Index = viper.GetString("SEARCH_INDEX")
+	searchConfig.RebuildInterval = viper.GetDuration("SEARCH_REBUILD_INTERVAL")
+
+	if searchConfig.Index != "local" {
+		return searchConfig, fmt.Errorf("invalid search index: %s", searchConfig.Index)
+	}
+	if searchConfig.RebuildInterval <= 0 {
+		return searchConfig, fmt.Errorf("SEARCH_REBUILD_INTERVAL must be a positive duration")
+	}
+	return searchConfig, nil
+}
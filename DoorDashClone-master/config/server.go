@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ServerConfig - HTTP listener settings
+type ServerConfig struct {
+	Port         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+}
+
+// PoolConfig - Database connection pool limits
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func loadServerConfig() (server ServerConfig, err error) {
+	server.Port = viper.GetString("SERVER_PORT")
+	server.ReadTimeout = viper.GetDuration("SERVER_READ_TIMEOUT")
+	server.WriteTimeout = viper.GetDuration("SERVER_WRITE_TIMEOUT")
+	server.IdleTimeout = viper.GetDuration("SERVER_IDLE_TIMEOUT")
+
+	if server.Port == "" {
+		return server, fmt.Errorf("SERVER_PORT is required")
+	}
+	if server.ReadTimeout <= 0 || server.WriteTimeout <= 0 || server.IdleTimeout <= 0 {
+		return server, fmt.Errorf("SERVER_READ_TIMEOUT, SERVER_WRITE_TIMEOUT and SERVER_IDLE_TIMEOUT must be positive durations")
+	}
+	return server, nil
+}
+
+func loadPoolConfig() (pool PoolConfig, err error) {
+	pool.MaxOpenConns = viper.GetInt("DB_MAX_OPEN_CONNS")
+	pool.MaxIdleConns = viper.GetInt("DB_MAX_IDLE_CONNS")
+	pool.ConnMaxLifetime = viper.GetDuration("DB_CONN_MAX_LIFETIME")
+	pool.ConnMaxIdleTime = viper.GetDuration("DB_CONN_MAX_IDLE_TIME")
+
+	if pool.MaxOpenConns <= 0 {
+		return pool, fmt.Errorf("DB_MAX_OPEN_CONNS must be positive")
+	}
+	if pool.MaxIdleConns < 0 || pool.MaxIdleConns > pool.MaxOpenConns {
+		return pool, fmt.Errorf("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if pool.ConnMaxLifetime <= 0 || pool.ConnMaxIdleTime <= 0 {
+		return pool, fmt.Errorf("DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME must be positive durations")
+	}
+	return pool, nil
+}
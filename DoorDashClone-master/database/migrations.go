@@ -2,17 +2,53 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+
+	"mcd/config"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/spf13/viper"
 )
 
+// minorUnitsMigration is the version converting amounts to minor units of USD
+const minorUnitsMigration = 21
+
+// checkMinorUnitsMigration refuses to convert existing amounts to USD cents when the service is
+// configured for another currency, which the conversion would get wrong. A new database has no
+// amounts to convert.
+func checkMinorUnitsMigration(m *migrate.Migrate) error {
+	version, _, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	currency := strings.ToUpper(viper.GetString("CURRENCY"))
+	if version < minorUnitsMigration && currency != "USD" {
+		return fmt.Errorf("migration %05d stores existing amounts as USD cents but CURRENCY is %s, convert them by hand first", minorUnitsMigration, currency)
+	}
+	return nil
+}
+
 func main() {
-	// Open a database connection
-	db, err := sql.Open("mysql", "admin:BaLiDiNeSh5@tcp(database-1.c1w2ua0029vr.us-east-2.rds.amazonaws.com:3306)/doordash?multiStatements=true")
+	// Load the database settings from config.yml and the environment
+	if err := config.InitializeConfig(); err != nil {
+		log.Fatal(err)
+	}
+	dbConfig, err := config.LoadDatabaseConfig()
+	if err != nil {
+		log.Fatalf("invalid database configuration: %v", err)
+	}
+
+	// Open a database connection, migrations run against the primary
+	db, err := sql.Open("mysql", dbConfig.DatabaseWriteURL+"&multiStatements=true")
 	if err != nil {
 		log.Fatalf("Error opening database: %v", err)
 	}
@@ -34,8 +70,12 @@ func main() {
 		log.Fatalf("Error creating migrate instance: %v", err)
 	}
 
-	// Run migrations (change steps as needed)
-	err = m.Steps(7)
+	if err := checkMinorUnitsMigration(m); err != nil {
+		log.Fatal(err)
+	}
+
+	// Run all pending migrations
+	err = m.Up()
 	if err != nil {
 		if err == migrate.ErrNoChange {
 			log.Println("No changes to apply")
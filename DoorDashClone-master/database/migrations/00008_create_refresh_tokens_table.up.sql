@@ -0,0 +1,14 @@
+create table `refresh_tokens` (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `family_id` VARCHAR(64) NOT NULL COMMENT 'Shared by every token rotated from the same login',
+    `token_hash` CHAR(64) NOT NULL COMMENT 'SHA-256 of the refresh token, the raw token is never stored',
+    `expires_at` TIMESTAMP NOT NULL,
+    `revoked_at` TIMESTAMP NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `token_hash` (`token_hash`),
+    KEY `family_id` (`family_id`),
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT ='Table to store refresh tokens issued at login';
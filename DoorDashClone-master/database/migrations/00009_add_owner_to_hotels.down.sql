@@ -0,0 +1,3 @@
+ALTER TABLE `hotels`
+DROP FOREIGN KEY `hotels_owner_id_fk`,
+DROP COLUMN `owner_id`;
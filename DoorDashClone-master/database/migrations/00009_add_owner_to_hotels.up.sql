@@ -0,0 +1,6 @@
+ALTER TABLE `hotels`
+ADD COLUMN `owner_id` INT UNSIGNED NULL DEFAULT NULL COMMENT 'User with the restaurant_owner role who manages the hotel',
+ADD CONSTRAINT `hotels_owner_id_fk` FOREIGN KEY (`owner_id`) REFERENCES users(`id`);
+
+-- Roles used to be free-form, anything that is not a known role becomes a customer
+UPDATE `users` SET `role` = 'customer' WHERE `role` NOT IN ('customer', 'restaurant_owner', 'admin');
@@ -0,0 +1 @@
+DROP TABLE otps;
\ No newline at end of file
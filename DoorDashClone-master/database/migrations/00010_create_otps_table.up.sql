@@ -0,0 +1,16 @@
+create table `otps` (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` INT UNSIGNED NOT NULL,
+    `channel` VARCHAR(10) NOT NULL COMMENT 'Represents how the code was delivered (email, sms)',
+    `destination` VARCHAR(100) NOT NULL COMMENT 'Email address or phone number the code was sent to',
+    `code_hash` CHAR(64) NOT NULL,
+    `attempts` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Number of failed verification attempts',
+    `max_attempts` INT UNSIGNED NOT NULL DEFAULT 5,
+    `expires_at` TIMESTAMP NOT NULL,
+    `consumed_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Set once the code is verified or superseded',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `user_id_created_at` (`user_id`, `created_at`),
+    FOREIGN KEY (`user_id`) REFERENCES users(`id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT ='Table to store one time passwords for user verification';
@@ -0,0 +1,3 @@
+-- Fails if duplicate emails already exist, they have to be merged by hand first
+ALTER TABLE `users`
+ADD UNIQUE KEY `email` (`email`);
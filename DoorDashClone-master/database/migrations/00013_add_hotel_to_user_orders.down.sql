@@ -0,0 +1,3 @@
+ALTER TABLE `user_orders`
+DROP FOREIGN KEY `user_orders_hotel_id_fk`,
+DROP COLUMN `hotel_id`;
@@ -0,0 +1,4 @@
+-- Orders placed before this migration are not linked to a hotel
+ALTER TABLE `user_orders`
+ADD COLUMN `hotel_id` INT UNSIGNED NULL DEFAULT NULL COMMENT 'Represents the hotel the order was placed at' AFTER `user_id`,
+ADD CONSTRAINT `user_orders_hotel_id_fk` FOREIGN KEY (`hotel_id`) REFERENCES hotels(`id`);
@@ -0,0 +1,5 @@
+ALTER TABLE `user_orders`
+DROP KEY `order_status_payment_due_at`,
+DROP COLUMN `payment_due_at`;
+
+DROP TABLE stock_reservations;
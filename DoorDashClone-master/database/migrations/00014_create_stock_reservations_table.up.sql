@@ -0,0 +1,17 @@
+create table stock_reservations(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `order_id` int unsigned not null,
+    `product_id` int unsigned not null,
+    `quantity` int unsigned not null,
+    `status` varchar(20) not null default 'reserved' COMMENT 'reserved while the order holds the stock, released once it was put back',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY(`id`),
+    KEY `order_id_status` (`order_id`, `status`),
+    FOREIGN KEY(`order_id`) REFERENCES user_orders(`id`),
+    FOREIGN KEY(`product_id`) REFERENCES products(`id`)
+)ENGINE=InnoDB;
+
+ALTER TABLE `user_orders`
+ADD COLUMN `payment_due_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Reserved stock is released if the order is still pending after this',
+ADD KEY `order_status_payment_due_at` (`order_status`, `payment_due_at`);
@@ -0,0 +1,8 @@
+DROP TABLE order_status_history;
+
+ALTER TABLE `user_orders`
+DROP FOREIGN KEY `user_orders_courier_id_fk`,
+DROP COLUMN `courier_id`,
+MODIFY `order_status` VARCHAR(40) NOT NULL DEFAULT 'pending' COMMENT 'Represents the status of the order (e.g., cancelled, pending)';
+
+UPDATE `user_orders` SET `order_status` = 'pending' WHERE `order_status` = 'placed';
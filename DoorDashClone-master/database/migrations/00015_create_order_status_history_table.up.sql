@@ -0,0 +1,22 @@
+-- Orders used to start as pending, the state machine starts them as placed
+UPDATE `user_orders` SET `order_status` = 'placed' WHERE `order_status` = 'pending';
+
+ALTER TABLE `user_orders`
+MODIFY `order_status` VARCHAR(40) NOT NULL DEFAULT 'placed' COMMENT 'placed, accepted, preparing, ready, picked_up, delivered, cancelled, rejected or refunded',
+ADD COLUMN `courier_id` INT UNSIGNED NULL DEFAULT NULL COMMENT 'Courier who picked the order up' AFTER `hotel_id`,
+ADD CONSTRAINT `user_orders_courier_id_fk` FOREIGN KEY (`courier_id`) REFERENCES users(`id`);
+
+create table order_status_history(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `order_id` int unsigned not null,
+    `from_status` varchar(40) not null default '' COMMENT 'Empty for the entry written when the order was placed',
+    `to_status` varchar(40) not null,
+    `changed_by` int unsigned null default null COMMENT 'NULL when the system made the change',
+    `changed_by_role` varchar(20) not null default '',
+    `reason` varchar(255) not null default '',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(`id`),
+    KEY `order_id` (`order_id`),
+    FOREIGN KEY(`order_id`) REFERENCES user_orders(`id`),
+    FOREIGN KEY(`changed_by`) REFERENCES users(`id`)
+)ENGINE=InnoDB;
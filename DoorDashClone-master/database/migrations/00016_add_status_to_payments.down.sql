@@ -0,0 +1,3 @@
+ALTER TABLE `PAYMENTS`
+DROP KEY `order_id_status`,
+DROP COLUMN `status`;
@@ -0,0 +1,3 @@
+ALTER TABLE `PAYMENTS`
+ADD COLUMN `status` VARCHAR(20) NOT NULL DEFAULT 'captured' COMMENT 'captured, or refund_pending once the order was cancelled or rejected',
+ADD KEY `order_id_status` (`order_id`, `status`);
@@ -0,0 +1,14 @@
+create table idempotency_keys(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `user_id` int unsigned not null,
+    `idempotency_key` varchar(255) not null,
+    `request_hash` char(64) not null COMMENT 'SHA-256 of the method, path and body of the first request',
+    `status_code` smallint unsigned not null default 0 COMMENT '0 while the first request is being handled',
+    `response_body` mediumblob null,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` TIMESTAMP NOT NULL,
+    PRIMARY KEY(`id`),
+    UNIQUE KEY `user_id_idempotency_key` (`user_id`, `idempotency_key`),
+    KEY `expires_at` (`expires_at`),
+    FOREIGN KEY(`user_id`) REFERENCES users(`id`)
+)ENGINE=InnoDB;
@@ -0,0 +1,15 @@
+-- Unpaid orders had no state of their own before payment intents
+UPDATE `user_orders` SET `order_status` = 'placed' WHERE `order_status` = 'payment_pending';
+
+ALTER TABLE `user_orders`
+MODIFY `order_status` VARCHAR(40) NOT NULL DEFAULT 'placed' COMMENT 'placed, accepted, preparing, ready, picked_up, delivered, cancelled, rejected or refunded';
+
+-- Only captured payments were stored before
+DELETE FROM `PAYMENTS` WHERE `status` IN ('authorized', 'voided');
+
+ALTER TABLE `PAYMENTS`
+DROP KEY `transaction_id`,
+DROP COLUMN `updated_at`,
+DROP COLUMN `created_at`,
+MODIFY `status` VARCHAR(20) NOT NULL DEFAULT 'captured' COMMENT 'captured, or refund_pending once the order was cancelled or rejected',
+MODIFY `id` INT UNSIGNED NOT NULL;
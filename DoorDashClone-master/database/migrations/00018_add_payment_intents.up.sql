@@ -0,0 +1,11 @@
+-- Payments are now created by the service when an order is placed, with the id assigned by MySQL
+ALTER TABLE `PAYMENTS`
+MODIFY `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+MODIFY `status` VARCHAR(20) NOT NULL DEFAULT 'authorized' COMMENT 'authorized, captured, voided, or refund_pending once the order was cancelled or rejected after capture',
+ADD COLUMN `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+ADD COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+ADD UNIQUE KEY `transaction_id` (`transaction_id`);
+
+-- Orders wait in payment_pending until their payment is captured
+ALTER TABLE `user_orders`
+MODIFY `order_status` VARCHAR(40) NOT NULL DEFAULT 'payment_pending' COMMENT 'payment_pending, placed, accepted, preparing, ready, picked_up, delivered, cancelled, rejected or refunded';
@@ -0,0 +1,8 @@
+DROP TABLE ledger_entries;
+DROP TABLE refund_items;
+DROP TABLE refunds;
+
+UPDATE `PAYMENTS` SET `status` = 'captured' WHERE `status` IN ('partially_refunded', 'refunded');
+
+ALTER TABLE `PAYMENTS`
+MODIFY `status` VARCHAR(20) NOT NULL DEFAULT 'authorized' COMMENT 'authorized, captured, voided, or refund_pending once the order was cancelled or rejected after capture';
@@ -0,0 +1,51 @@
+ALTER TABLE `PAYMENTS`
+MODIFY `status` VARCHAR(20) NOT NULL DEFAULT 'authorized' COMMENT 'authorized, captured, voided, refund_pending, partially_refunded or refunded';
+
+create table refunds(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `payment_id` int unsigned not null,
+    `order_id` int unsigned not null,
+    `amount` int unsigned not null,
+    `reason` varchar(255) not null default '',
+    `status` varchar(20) not null default 'pending' COMMENT 'pending while the gateway is called, then succeeded or failed',
+    `created_by` int unsigned not null,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY(`id`),
+    KEY `payment_id_status` (`payment_id`, `status`),
+    FOREIGN KEY(`payment_id`) REFERENCES PAYMENTS(`id`),
+    FOREIGN KEY(`order_id`) REFERENCES user_orders(`id`),
+    FOREIGN KEY(`created_by`) REFERENCES users(`id`)
+)ENGINE=InnoDB;
+
+create table refund_items(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `refund_id` int unsigned not null,
+    `order_product_id` int unsigned not null,
+    `product_id` int unsigned not null,
+    `quantity` int unsigned not null,
+    `amount` int unsigned not null,
+    PRIMARY KEY(`id`),
+    KEY `order_product_id` (`order_product_id`),
+    FOREIGN KEY(`refund_id`) REFERENCES refunds(`id`) ON DELETE CASCADE,
+    FOREIGN KEY(`order_product_id`) REFERENCES order_products(`id`)
+)ENGINE=InnoDB;
+
+-- Double-entry ledger, every journal_id has entries whose debits and credits are equal
+create table ledger_entries(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `journal_id` varchar(64) not null,
+    `order_id` int unsigned not null,
+    `payment_id` int unsigned not null,
+    `refund_id` int unsigned null default null,
+    `kind` varchar(20) not null COMMENT 'charge, refund or adjustment',
+    `account` varchar(40) not null COMMENT 'gateway or customer_orders',
+    `debit` int unsigned not null default 0,
+    `credit` int unsigned not null default 0,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(`id`),
+    KEY `journal_id` (`journal_id`),
+    KEY `order_id` (`order_id`),
+    FOREIGN KEY(`payment_id`) REFERENCES PAYMENTS(`id`),
+    FOREIGN KEY(`refund_id`) REFERENCES refunds(`id`)
+)ENGINE=InnoDB;
@@ -0,0 +1,11 @@
+create table webhook_events(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `event_id` varchar(100) not null COMMENT 'Id the gateway gave the event, redeliveries reuse it',
+    `type` varchar(50) not null,
+    `transaction_id` varchar(100) not null default '',
+    `payload` mediumblob null,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(`id`),
+    UNIQUE KEY `event_id` (`event_id`),
+    KEY `transaction_id` (`transaction_id`)
+)ENGINE=InnoDB;
@@ -0,0 +1,41 @@
+-- Back to whole units of the implied currency, the currency columns are dropped.
+-- Amounts that were not whole units are rounded, except price_at_purchase which keeps its cents.
+
+UPDATE `ledger_entries` SET `debit_amount` = ROUND(`debit_amount` / 100), `credit_amount` = ROUND(`credit_amount` / 100);
+ALTER TABLE `ledger_entries`
+DROP `debit_currency`,
+DROP `credit_currency`,
+CHANGE `debit_amount` `debit` int unsigned not null default 0,
+CHANGE `credit_amount` `credit` int unsigned not null default 0;
+
+UPDATE `refund_items` SET `amount` = ROUND(`amount` / 100);
+ALTER TABLE `refund_items`
+DROP `currency`,
+MODIFY `amount` int unsigned not null;
+
+UPDATE `refunds` SET `amount` = ROUND(`amount` / 100);
+ALTER TABLE `refunds`
+DROP `currency`,
+MODIFY `amount` int unsigned not null;
+
+UPDATE `PAYMENTS` SET `amount` = ROUND(`amount` / 100);
+ALTER TABLE `PAYMENTS`
+DROP `currency`,
+MODIFY `amount` int unsigned not null;
+
+ALTER TABLE `order_products` ADD `price_at_purchase` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `quantity`;
+UPDATE `order_products` SET `price_at_purchase` = `price_at_purchase_amount` / 100;
+ALTER TABLE `order_products`
+DROP `price_at_purchase_currency`,
+DROP `price_at_purchase_amount`,
+ALTER `price_at_purchase` DROP DEFAULT;
+
+UPDATE `user_orders` SET `order_total_amount` = ROUND(`order_total_amount` / 100);
+ALTER TABLE `user_orders`
+DROP `order_total_currency`,
+CHANGE `order_total_amount` `order_total` INT UNSIGNED NOT NULL DEFAULT 0;
+
+UPDATE `products` SET `price_amount` = ROUND(`price_amount` / 100);
+ALTER TABLE `products`
+DROP `price_currency`,
+CHANGE `price_amount` `price` int unsigned not null;
@@ -0,0 +1,43 @@
+-- Amounts were whole units of a single implied currency (price_at_purchase a DECIMAL of them).
+-- They become BIGINT minor units, cents for USD, next to an ISO 4217 currency column.
+-- This assumes every existing amount is in USD: it is labelled USD and multiplied by 100, which
+-- is only right for a currency with two decimal places. database/migrations.go refuses to run
+-- it over existing data unless CURRENCY is USD; convert the amounts by hand for any other.
+
+ALTER TABLE `products`
+CHANGE `price` `price_amount` BIGINT NOT NULL COMMENT 'minor units of price_currency',
+ADD `price_currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `price_amount`;
+UPDATE `products` SET `price_amount` = `price_amount` * 100;
+
+ALTER TABLE `user_orders`
+CHANGE `order_total` `order_total_amount` BIGINT NOT NULL DEFAULT 0 COMMENT 'minor units of order_total_currency',
+ADD `order_total_currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `order_total_amount`;
+UPDATE `user_orders` SET `order_total_amount` = `order_total_amount` * 100;
+
+ALTER TABLE `order_products`
+ADD `price_at_purchase_amount` BIGINT NOT NULL DEFAULT 0 COMMENT 'minor units of price_at_purchase_currency' AFTER `price_at_purchase`,
+ADD `price_at_purchase_currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `price_at_purchase_amount`;
+UPDATE `order_products` SET `price_at_purchase_amount` = ROUND(`price_at_purchase` * 100);
+ALTER TABLE `order_products` DROP `price_at_purchase`;
+
+ALTER TABLE `PAYMENTS`
+MODIFY `amount` BIGINT NOT NULL COMMENT 'minor units of currency',
+ADD `currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `amount`;
+UPDATE `PAYMENTS` SET `amount` = `amount` * 100;
+
+ALTER TABLE `refunds`
+MODIFY `amount` BIGINT NOT NULL COMMENT 'minor units of currency',
+ADD `currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `amount`;
+UPDATE `refunds` SET `amount` = `amount` * 100;
+
+ALTER TABLE `refund_items`
+MODIFY `amount` BIGINT NOT NULL COMMENT 'minor units of currency',
+ADD `currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `amount`;
+UPDATE `refund_items` SET `amount` = `amount` * 100;
+
+ALTER TABLE `ledger_entries`
+CHANGE `debit` `debit_amount` BIGINT NOT NULL DEFAULT 0 COMMENT 'minor units of debit_currency',
+ADD `debit_currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `debit_amount`,
+CHANGE `credit` `credit_amount` BIGINT NOT NULL DEFAULT 0 COMMENT 'minor units of credit_currency',
+ADD `credit_currency` CHAR(3) NOT NULL DEFAULT 'USD' AFTER `credit_amount`;
+UPDATE `ledger_entries` SET `debit_amount` = `debit_amount` * 100, `credit_amount` = `credit_amount` * 100;
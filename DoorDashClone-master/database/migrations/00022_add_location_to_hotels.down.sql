@@ -0,0 +1,5 @@
+ALTER TABLE `hotels`
+DROP KEY `latitude_longitude`,
+DROP COLUMN `delivery_radius_km`,
+DROP COLUMN `longitude`,
+DROP COLUMN `latitude`;
@@ -0,0 +1,5 @@
+ALTER TABLE `hotels`
+ADD COLUMN `latitude` DECIMAL(9, 6) NULL DEFAULT NULL COMMENT 'Hotels without a location are left out of nearby searches',
+ADD COLUMN `longitude` DECIMAL(9, 6) NULL DEFAULT NULL,
+ADD COLUMN `delivery_radius_km` DECIMAL(6, 2) NOT NULL DEFAULT 0 COMMENT '0 means pickup only',
+ADD KEY `latitude_longitude` (`latitude`, `longitude`);
@@ -0,0 +1,6 @@
+DROP TABLE hotel_closures;
+DROP TABLE hotel_opening_hours;
+
+ALTER TABLE `hotels`
+DROP COLUMN `paused`,
+DROP COLUMN `time_zone`;
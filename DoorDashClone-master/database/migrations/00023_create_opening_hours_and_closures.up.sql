@@ -0,0 +1,29 @@
+ALTER TABLE `hotels`
+ADD COLUMN `time_zone` VARCHAR(64) NOT NULL DEFAULT 'UTC' COMMENT 'IANA time zone of the opening hours',
+ADD COLUMN `paused` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Set by the restaurant to stop taking orders';
+
+-- A hotel without any rows here is open around the clock
+create table hotel_opening_hours(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `hotel_id` int unsigned not null,
+    `weekday` tinyint unsigned not null COMMENT '0 is Sunday',
+    `opens` char(5) not null COMMENT 'HH:MM local time',
+    `closes` char(5) not null COMMENT 'HH:MM local time, not after opens means past midnight',
+    PRIMARY KEY(`id`),
+    KEY `hotel_id_weekday` (`hotel_id`, `weekday`),
+    FOREIGN KEY(`hotel_id`) REFERENCES hotels(`id`) ON DELETE CASCADE
+)ENGINE=InnoDB;
+
+create table hotel_closures(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `hotel_id` int unsigned not null,
+    `starts_at` TIMESTAMP NOT NULL,
+    `ends_at` TIMESTAMP NOT NULL,
+    `reason` varchar(255) not null default '',
+    `created_by` int unsigned not null,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(`id`),
+    KEY `hotel_id_ends_at` (`hotel_id`, `ends_at`),
+    FOREIGN KEY(`hotel_id`) REFERENCES hotels(`id`) ON DELETE CASCADE,
+    FOREIGN KEY(`created_by`) REFERENCES users(`id`)
+)ENGINE=InnoDB;
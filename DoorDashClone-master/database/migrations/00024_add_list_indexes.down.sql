@@ -0,0 +1,13 @@
+ALTER TABLE `user_orders`
+DROP KEY `user_id_order_status_id`,
+DROP KEY `user_id_created_at_id`;
+
+ALTER TABLE `products`
+DROP KEY `hotel_id_category_id`,
+DROP KEY `hotel_id_name_id`,
+DROP KEY `hotel_id_price_amount_id`;
+
+ALTER TABLE `hotels`
+DROP KEY `name_id`,
+DROP KEY `state_id`,
+DROP KEY `city_id`;
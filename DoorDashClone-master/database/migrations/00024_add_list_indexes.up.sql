@@ -0,0 +1,14 @@
+-- Keyset pagination orders by the sort column then id, these back the filters and sorts of the list endpoints
+ALTER TABLE `hotels`
+ADD KEY `city_id` (`city`, `id`),
+ADD KEY `state_id` (`state`, `id`),
+ADD KEY `name_id` (`name`, `id`);
+
+ALTER TABLE `products`
+ADD KEY `hotel_id_price_amount_id` (`hotel_id`, `price_amount`, `id`),
+ADD KEY `hotel_id_name_id` (`hotel_id`, `name`, `id`),
+ADD KEY `hotel_id_category_id` (`hotel_id`, `category`, `id`);
+
+ALTER TABLE `user_orders`
+ADD KEY `user_id_created_at_id` (`user_id`, `created_at`, `id`),
+ADD KEY `user_id_order_status_id` (`user_id`, `order_status`, `id`);
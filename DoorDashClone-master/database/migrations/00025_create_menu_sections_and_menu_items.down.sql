@@ -0,0 +1,15 @@
+-- Names longer than the old column are cut to fit it
+UPDATE `products` SET `name` = LEFT(`name`, 15);
+
+ALTER TABLE `products`
+DROP FOREIGN KEY `products_section_id`,
+DROP COLUMN `available`,
+DROP COLUMN `spice_level`,
+DROP COLUMN `allergens`,
+DROP COLUMN `dietary_tags`,
+DROP COLUMN `image_url`,
+DROP COLUMN `description`,
+DROP COLUMN `section_id`,
+MODIFY COLUMN `name` VARCHAR(15) NOT NULL;
+
+DROP TABLE menu_sections;
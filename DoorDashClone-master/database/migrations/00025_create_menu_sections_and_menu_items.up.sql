@@ -0,0 +1,21 @@
+create table menu_sections(
+    `id` int unsigned not null AUTO_INCREMENT,
+    `hotel_id` int unsigned not null,
+    `name` varchar(100) not null,
+    `description` varchar(1000) not null default '',
+    `position` int not null default 0 COMMENT 'Sections are listed by position, then id',
+    PRIMARY KEY(`id`),
+    KEY `hotel_id_position_id` (`hotel_id`, `position`, `id`),
+    FOREIGN KEY(`hotel_id`) REFERENCES hotels(`id`) ON DELETE CASCADE
+)ENGINE=InnoDB;
+
+ALTER TABLE `products`
+MODIFY COLUMN `name` VARCHAR(100) NOT NULL,
+ADD COLUMN `section_id` INT UNSIGNED NULL DEFAULT NULL COMMENT 'Products without a section are listed last under Other',
+ADD COLUMN `description` VARCHAR(1000) NOT NULL DEFAULT '',
+ADD COLUMN `image_url` VARCHAR(2048) NOT NULL DEFAULT '',
+ADD COLUMN `dietary_tags` JSON NULL COMMENT 'Array of vegetarian, vegan and gluten_free',
+ADD COLUMN `allergens` JSON NULL COMMENT 'Array of the major allergens the dish contains',
+ADD COLUMN `spice_level` TINYINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '0 is not spicy, 3 is hot',
+ADD COLUMN `available` TINYINT(1) NOT NULL DEFAULT 1 COMMENT 'Products off sale stay on the menu but cannot be ordered',
+ADD CONSTRAINT `products_section_id` FOREIGN KEY (`section_id`) REFERENCES menu_sections(`id`) ON DELETE SET NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE `refunds`
+DROP KEY `status_created_at`;
+
+ALTER TABLE `ledger_entries`
+DROP FOREIGN KEY `ledger_entries_created_by_fk`,
+DROP COLUMN `reason`,
+DROP COLUMN `created_by`;
@@ -0,0 +1,8 @@
+ALTER TABLE `ledger_entries`
+ADD COLUMN `created_by` INT UNSIGNED NULL DEFAULT NULL COMMENT 'Who made a manual adjustment, null for charges and refunds' AFTER `credit_currency`,
+ADD COLUMN `reason` VARCHAR(255) NOT NULL DEFAULT '' AFTER `created_by`,
+ADD CONSTRAINT `ledger_entries_created_by_fk` FOREIGN KEY (`created_by`) REFERENCES users(`id`);
+
+-- Pending refunds are swept by age to be retried
+ALTER TABLE `refunds`
+ADD KEY `status_created_at` (`status`, `created_at`);
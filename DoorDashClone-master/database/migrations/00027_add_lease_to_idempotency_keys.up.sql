@@ -0,0 +1,4 @@
+-- A request that crashed leaves its key in flight, a retry takes the key over once the lease ends.
+-- Keys in flight while migrating get a lease ending now and can be taken over right away.
+ALTER TABLE `idempotency_keys`
+ADD COLUMN `lease_expires_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'While status_code is 0, a retry after this takes the key over' AFTER `response_body`;
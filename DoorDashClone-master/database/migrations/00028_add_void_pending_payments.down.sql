@@ -0,0 +1,5 @@
+UPDATE `PAYMENTS` SET `status` = 'voided' WHERE `status` = 'void_pending';
+
+ALTER TABLE `PAYMENTS`
+DROP KEY `status_updated_at`,
+MODIFY `status` VARCHAR(20) NOT NULL DEFAULT 'authorized' COMMENT 'authorized, captured, voided, refund_pending, partially_refunded or refunded';
@@ -0,0 +1,5 @@
+-- Authorizations of orders that ended unpaid are void_pending until the gateway confirms the
+-- void, and are swept by age to be retried
+ALTER TABLE `PAYMENTS`
+MODIFY `status` VARCHAR(20) NOT NULL DEFAULT 'authorized' COMMENT 'authorized, captured, void_pending, voided, refund_pending, partially_refunded or refunded',
+ADD KEY `status_updated_at` (`status`, `updated_at`);
@@ -14,7 +14,75 @@ func (re ResponseError) Error() string {
 }
 
 var (
-	InvalidOrder       = ResponseError{"invalidOrderPayload", "invalid payload provided", http.StatusBadRequest}
-	InvalidOrderID     = ResponseError{"invalidOrderId", "invalid order id provided", http.StatusBadRequest}
-	InvalidPhoneNumber = ResponseError{"invalidPhoneNumber", "invalid phone number ", http.StatusBadRequest}
+	InvalidOrder            = ResponseError{"invalidOrderPayload", "invalid payload provided", http.StatusBadRequest}
+	InvalidOrderID          = ResponseError{"invalidOrderId", "invalid order id provided", http.StatusBadRequest}
+	InvalidPhoneNumber      = ResponseError{"invalidPhoneNumber", "phone number must be 10 to 13 digits and may start with +", http.StatusBadRequest}
+	InvalidEmail            = ResponseError{"invalidEmail", "email must be a valid address of at most 40 characters", http.StatusBadRequest}
+	InvalidName             = ResponseError{"invalidName", "name is required and must be at most 20 characters", http.StatusBadRequest}
+	EmailAlreadyExists      = ResponseError{"emailAlreadyExists", "an account with this email already exists", http.StatusConflict}
+	PhoneAlreadyExists      = ResponseError{"phoneNumberAlreadyExists", "an account with this phone number already exists", http.StatusConflict}
+	MissingToken            = ResponseError{"missingToken", "authorization bearer token is required", http.StatusUnauthorized}
+	InvalidToken            = ResponseError{"invalidToken", "token is invalid or has expired", http.StatusUnauthorized}
+	InvalidRefreshToken     = ResponseError{"invalidRefreshToken", "refresh token is invalid or has expired", http.StatusUnauthorized}
+	RefreshTokenReused      = ResponseError{"refreshTokenReused", "refresh token was already used, all sessions have been revoked", http.StatusUnauthorized}
+	Forbidden               = ResponseError{"forbidden", "insufficient permissions", http.StatusForbidden}
+	InvalidCredentials      = ResponseError{"invalidCredentials", "email or password is incorrect", http.StatusUnauthorized}
+	UserNotVerified         = ResponseError{"userNotVerified", "verify your email or phone number before logging in", http.StatusForbidden}
+	UserAlreadyVerified     = ResponseError{"userAlreadyVerified", "user is already verified", http.StatusConflict}
+	InvalidOTPChannel       = ResponseError{"invalidOtpChannel", "channel must be email or sms", http.StatusBadRequest}
+	InvalidOTP              = ResponseError{"invalidOtp", "otp is incorrect", http.StatusBadRequest}
+	OTPExpired              = ResponseError{"otpExpired", "otp has expired, request a new one", http.StatusBadRequest}
+	OTPAttemptsExceeded     = ResponseError{"otpAttemptsExceeded", "too many incorrect attempts, request a new otp", http.StatusTooManyRequests}
+	OTPResendTooSoon        = ResponseError{"otpResendTooSoon", "wait before requesting another otp", http.StatusTooManyRequests}
+	InvalidResetToken       = ResponseError{"invalidResetToken", "password reset token is invalid, used or expired", http.StatusBadRequest}
+	WeakPassword            = ResponseError{"weakPassword", "password must be 8 to 72 characters and contain a letter and a digit", http.StatusBadRequest}
+	InvalidRole             = ResponseError{"invalidRole", "role must be customer or restaurant_owner", http.StatusBadRequest}
+	HotelNotFound           = ResponseError{"hotelNotFound", "hotel does not exist", http.StatusNotFound}
+	InvalidHotel            = ResponseError{"invalidHotel", "body must be a json hotel object", http.StatusBadRequest}
+	InvalidLocation         = ResponseError{"invalidLocation", "latitude must be within -90 and 90, longitude within -180 and 180, both or neither given, and radii at least 0", http.StatusBadRequest}
+	InvalidSearchRadius     = ResponseError{"invalidSearchRadius", "radius must be positive and at most 50 km, it defaults to 10 km", http.StatusBadRequest}
+	InvalidOpeningHours     = ResponseError{"invalidOpeningHours", "opening hours need a weekday from 0 (Sunday) to 6 and opens and closes times as HH:MM", http.StatusBadRequest}
+	InvalidTimeZone         = ResponseError{"invalidTimeZone", "time zone must be an IANA name such as America/New_York", http.StatusBadRequest}
+	InvalidClosure          = ResponseError{"invalidClosure", "closure must end after it starts and after now", http.StatusBadRequest}
+	InvalidHotelPause       = ResponseError{"invalidHotelPause", "body must be a json object with a paused boolean", http.StatusBadRequest}
+	HotelClosed             = ResponseError{"hotelClosed", "restaurant is closed or not accepting orders right now, see next_opens_at of the hotel", http.StatusConflict}
+	InvalidCursor           = ResponseError{"invalidCursor", "cursor is malformed or was issued for another sort", http.StatusBadRequest}
+	InvalidSort             = ResponseError{"invalidSort", "sort is not supported by this list", http.StatusBadRequest}
+	InvalidListQuery        = ResponseError{"invalidListQuery", "limit must be a number, prices whole minor units and dates RFC 3339 or YYYY-MM-DD", http.StatusBadRequest}
+	InvalidMenuSection      = ResponseError{"invalidMenuSection", "menu section needs a name of at most 100 characters and a description of at most 1000", http.StatusBadRequest}
+	ProductNotFound         = ResponseError{"productNotFound", "product does not exist", http.StatusNotFound}
+	MenuSectionNotFound     = ResponseError{"menuSectionNotFound", "menu section does not exist at this hotel", http.StatusNotFound}
+	InvalidMenuItem         = ResponseError{"invalidMenuItem", "product needs a name of at most 100 characters, a description of at most 1000, an http(s) image URL, known dietary tags and allergens, a spice level from 0 to 3 and a stock of at least 0", http.StatusBadRequest}
+	ProductUnavailable      = ResponseError{"productUnavailable", "product is not available right now", http.StatusConflict}
+	InvalidAvailability     = ResponseError{"invalidAvailability", "body must be a json object with an available boolean", http.StatusBadRequest}
+	InvalidSearchQuery      = ResponseError{"invalidSearchQuery", "q must contain a word to search for and limit must be a number", http.StatusBadRequest}
+	InvalidHotelOwner       = ResponseError{"invalidHotelOwner", "hotel owner must be an existing restaurant_owner user", http.StatusBadRequest}
+	NotHotelOwner           = ResponseError{"notHotelOwner", "only the owner of the hotel can manage its products", http.StatusForbidden}
+	InvalidOrderProduct     = ResponseError{"invalidOrderProduct", "order products must exist, be sold by the hotel, be listed once and have a positive quantity", http.StatusBadRequest}
+	InvalidCurrency         = ResponseError{"invalidCurrency", "currency must be a three letter ISO 4217 code", http.StatusBadRequest}
+	InvalidPrice            = ResponseError{"invalidPrice", "price amount must be at least 0", http.StatusBadRequest}
+	CurrencyMismatch        = ResponseError{"currencyMismatch", "all amounts of an order must be in the same currency", http.StatusBadRequest}
+	OrderPriceMismatch      = ResponseError{"orderPriceMismatch", "prices have changed since the order was built, refresh and try again", http.StatusConflict}
+	InvalidCartProduct      = ResponseError{"invalidCartProduct", "body must be a json object with a product_id and a quantity", http.StatusBadRequest}
+	CartEmpty               = ResponseError{"cartEmpty", "cart has no products to check out", http.StatusBadRequest}
+	CartMultipleHotels      = ResponseError{"cartMultipleHotels", "all products in the cart must come from the same hotel", http.StatusBadRequest}
+	InsufficientStock       = ResponseError{"insufficientStock", "not enough stock left for one of the products", http.StatusConflict}
+	OrderStatusConflict     = ResponseError{"orderStatusConflict", "order status does not allow this change", http.StatusConflict}
+	CancellationRefused     = ResponseError{"cancellationRefused", "orders can only be cancelled until the restaurant accepts them", http.StatusConflict}
+	RejectionReasonRequired = ResponseError{"rejectionReasonRequired", "a reason is required to reject an order", http.StatusBadRequest}
+	InvalidIdempotencyKey   = ResponseError{"invalidIdempotencyKey", "Idempotency-Key must be at most 255 characters", http.StatusBadRequest}
+	IdempotencyKeyReused    = ResponseError{"idempotencyKeyReused", "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity}
+	IdempotencyKeyInFlight  = ResponseError{"idempotencyKeyInFlight", "a request with this Idempotency-Key is still being processed, retry later", http.StatusConflict}
+	OrderNotFound           = ResponseError{"orderNotFound", "order does not exist", http.StatusNotFound}
+	PaymentDeclined         = ResponseError{"paymentDeclined", "payment was declined", http.StatusPaymentRequired}
+	PaymentNotFound         = ResponseError{"paymentNotFound", "order has no payment", http.StatusNotFound}
+	PaymentNotRefundable    = ResponseError{"paymentNotRefundable", "only captured payments can be refunded", http.StatusConflict}
+	InvalidRefundItem       = ResponseError{"invalidRefundItem", "refund items must be products of the order, listed once with a positive quantity", http.StatusBadRequest}
+	RefundExceedsCapture    = ResponseError{"refundExceedsCapture", "refunds cannot exceed what was paid for the order or its items", http.StatusConflict}
+	InvalidLedgerAdjustment = ResponseError{"invalidLedgerAdjustment", "an adjustment needs a non-zero amount and a reason of at most 255 characters", http.StatusBadRequest}
+	InvalidWebhookSignature = ResponseError{"invalidWebhookSignature", "webhook signature is missing or invalid", http.StatusUnauthorized}
+	WebhookExpired          = ResponseError{"webhookExpired", "webhook timestamp is outside the accepted tolerance", http.StatusBadRequest}
+	WebhookAmountMismatch   = ResponseError{"webhookAmountMismatch", "captured amount does not match the payment of the order", http.StatusUnprocessableEntity}
+	InvalidWebhookPayload   = ResponseError{"invalidWebhookPayload", "webhook payload must be a json event with an id and a type", http.StatusBadRequest}
+	InvalidOrderStatus      = ResponseError{"invalidOrderStatus", "status must be one of payment_pending, placed, accepted, preparing, ready, picked_up, delivered, cancelled, rejected or refunded", http.StatusBadRequest}
 )
@@ -0,0 +1,58 @@
+package domain
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// MaxNearbyHotels caps how many of the nearest hotels a nearby search returns
+const MaxNearbyHotels = 50
+
+// NearbyHotel is a hotel found around a location, with how far away it is. Hotels that do not
+// deliver as far as the location are still listed, as pickup only.
+type NearbyHotel struct {
+	Hotel
+	DistanceKm float64 `json:"distance_km"`
+	PickupOnly bool    `json:"pickup_only"` // The location is outside the hotel's delivery radius
+}
+
+// GeoBounds is a latitude and longitude rectangle, a cheap first cut of a radius search
+type GeoBounds struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// ValidCoordinates reports whether latitude and longitude are on the globe
+func ValidCoordinates(latitude, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
+
+// DistanceKm returns the great-circle distance between two points with the haversine formula
+func DistanceKm(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLatitude := toRadians(latitude2 - latitude1)
+	dLongitude := toRadians(longitude2 - longitude1)
+	a := math.Sin(dLatitude/2)*math.Sin(dLatitude/2) +
+		math.Cos(toRadians(latitude1))*math.Cos(toRadians(latitude2))*math.Sin(dLongitude/2)*math.Sin(dLongitude/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoundsAround returns a rectangle holding every point within radiusKm of the location. Near the
+// poles, or when the circle crosses the antimeridian, it spans every longitude.
+func BoundsAround(latitude, longitude, radiusKm float64) GeoBounds {
+	dLatitude := radiusKm / earthRadiusKm * 180 / math.Pi
+	bounds := GeoBounds{
+		MinLatitude:  math.Max(-90, latitude-dLatitude),
+		MaxLatitude:  math.Min(90, latitude+dLatitude),
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+	if bounds.MinLatitude > -90 && bounds.MaxLatitude < 90 {
+		dLongitude := dLatitude / math.Cos(latitude*math.Pi/180)
+		if longitude-dLongitude >= -180 && longitude+dLongitude <= 180 {
+			bounds.MinLongitude, bounds.MaxLongitude = longitude-dLongitude, longitude+dLongitude
+		}
+	}
+	return bounds
+}
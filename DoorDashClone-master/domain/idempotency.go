@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// IdempotencyKeyHeader is the header clients set to make retries of a request safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyKey remembers the first response to a request made with an Idempotency-Key so
+// retries with the same key get the same response instead of repeating the request.
+// StatusCode is 0 while the first request is still being handled, a retry after LeaseExpiresAt
+// takes the key over from a request that crashed before storing its response.
+type IdempotencyKey struct {
+	ID             int       `gorm:"primaryKey"`
+	UserID         int       `gorm:"column:user_id"`
+	Key            string    `gorm:"column:idempotency_key"`
+	RequestHash    string    `gorm:"column:request_hash"` // SHA-256 of the method, path and body
+	StatusCode     int       `gorm:"column:status_code"`
+	ResponseBody   []byte    `gorm:"column:response_body"`
+	LeaseExpiresAt time.Time `gorm:"column:lease_expires_at"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	ExpiresAt      time.Time
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Reservation statuses
+const (
+	ReservationReserved = "reserved" // Stock is held for an order that is still open
+	ReservationReleased = "released" // Stock was returned because the order was cancelled or never paid
+)
+
+// StockReservation holds part of a product's stock for an order. Stock is taken from
+// products.stockLeft when the reservation is made and put back when it is released.
+type StockReservation struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	OrderID   int       `json:"order_id"`
+	ProductID int       `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
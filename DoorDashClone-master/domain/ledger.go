@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+// LedgerAccount is one side of the payment ledger
+type LedgerAccount string
+
+const (
+	AccountGateway        LedgerAccount = "gateway"         // Money held for us by the payment gateway
+	AccountCustomerOrders LedgerAccount = "customer_orders" // What customers have paid towards their orders
+)
+
+// LedgerEntryKind is the money movement an entry records
+type LedgerEntryKind string
+
+const (
+	LedgerCharge     LedgerEntryKind = "charge"     // A payment was captured
+	LedgerRefund     LedgerEntryKind = "refund"     // Money was returned to the customer
+	LedgerAdjustment LedgerEntryKind = "adjustment" // A manual correction by finance
+)
+
+// LedgerEntry is one line of the double-entry payment ledger. Entries are written in journals
+// of at least two lines whose debits and credits are equal, and are never updated or deleted.
+type LedgerEntry struct {
+	ID        int             `gorm:"primaryKey" json:"id"`
+	JournalID string          `json:"journal_id"`
+	OrderID   int             `json:"order_id"`
+	PaymentID int             `json:"payment_id"`
+	RefundID  *int            `json:"refund_id,omitempty"`
+	Kind      LedgerEntryKind `json:"kind"`
+	Account   LedgerAccount   `json:"account"`
+	Debit     Money           `json:"debit" gorm:"embedded;embeddedPrefix:debit_"`
+	Credit    Money           `json:"credit" gorm:"embedded;embeddedPrefix:credit_"`
+	CreatedBy *int            `json:"created_by,omitempty"` // Who made a manual adjustment, nil for charges and refunds
+	Reason    string          `json:"reason,omitempty"`
+	CreatedAt time.Time       `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// MaxLedgerReasonLength bounds the reason given for a manual adjustment
+const MaxLedgerReasonLength = 255
+
+// LedgerAdjustmentRequest corrects the ledger of an order by hand. A positive amount records
+// money the gateway holds for the order that the ledger missed, a negative amount money the
+// ledger counts for the order that the gateway no longer holds. The currency defaults to the
+// currency of the order's payment.
+type LedgerAdjustmentRequest struct {
+	Amount Money  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// PaymentReconciliation compares what was captured and refunded for an order with its ledger.
+// Balanced is false when a journal does not balance or the ledger disagrees with the payment.
+type PaymentReconciliation struct {
+	OrderID       int           `json:"order_id"`
+	PaymentID     int           `json:"payment_id"`
+	Captured      Money         `json:"captured"`
+	Refunded      Money         `json:"refunded"`
+	Refundable    Money         `json:"refundable"`
+	LedgerBalance Money         `json:"ledger_balance"` // Credit balance of the customer_orders account for the order
+	Balanced      bool          `json:"balanced"`
+	Refunds       []Refund      `json:"refunds"`
+	Entries       []LedgerEntry `json:"entries"`
+}
@@ -6,10 +6,30 @@ import "time"
 type User struct {
 	ID           int32  `json:"id,omitempty"`
 	Email        string `json:"email"`
-	PhoneNumber  uint   `json:"phone_number"` // Changed to uint to match repository
-	PasswordHash string `json:"password_hash"`
+	PhoneNumber  string `json:"phone_number"` // Stored as varchar(13), may start with +
+	PasswordHash string `json:"-"`
 	Name         string `json:"name"`
-	Role         string `json:"role"`
+	Role         Role   `json:"role"`
+	IsVerified   bool   `json:"is_verified" gorm:"column:isVerified"`
+}
+
+// CreateUserRequest is the registration payload, the password is only ever accepted in plaintext here.
+type CreateUserRequest struct {
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+	Password    string `json:"password"`
+	Name        string `json:"name"`
+	Role        Role   `json:"role"`
+}
+
+// UserResponse is the public view of a user, it never carries the password hash.
+type UserResponse struct {
+	ID          int32  `json:"id"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+	Name        string `json:"name"`
+	Role        Role   `json:"role"`
+	IsVerified  bool   `json:"is_verified"`
 }
 
 type UserLogin struct {
@@ -21,26 +41,84 @@ type LoginResponse struct {
 	Token        string `json:"token"`
 	RefreshToken string `json:"refreshToken"`
 	Name         string `json:"name"`
-	Role         string `json:"role"`
+	Role         Role   `json:"role"`
+}
+
+// RefreshToken is a persisted, rotatable refresh token. Tokens rotated from
+// the same login share a FamilyID so that reuse can revoke the whole chain.
+type RefreshToken struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	UserID    int        `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// PasswordReset is a single use token that lets a user choose a new password.
+type PasswordReset struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirm struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// AuthClaims is the identity carried by a verified access token.
+type AuthClaims struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Role   Role   `json:"role"`
 }
 
 // Product represents a product in the system.
 type Product struct {
-	ID        int32  `json:"id,omitempty"`
-	Name      string `json:"name"`
-	StockLeft int    `json:"stockLeft" gorm:"column:stockLeft"`
-	HotelID   int    `json:"hotel_id"` // Hotel_id renamed to HotelID for consistency
-	Category  string `json:"category"`
-	Price     int    `json:"price"`
+	ID          int32        `json:"id,omitempty"`
+	Name        string       `json:"name"`
+	StockLeft   int          `json:"stockLeft" gorm:"column:stockLeft"`
+	HotelID     int          `json:"hotel_id"` // Hotel_id renamed to HotelID for consistency
+	Category    string       `json:"category"`
+	Price       Money        `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	SectionID   *int         `json:"section_id,omitempty"` // Menu section of the same hotel listing the product
+	Description string       `json:"description,omitempty"`
+	ImageURL    string       `json:"image_url,omitempty"`
+	DietaryTags []DietaryTag `json:"dietary_tags,omitempty" gorm:"serializer:json"`
+	Allergens   []Allergen   `json:"allergens,omitempty" gorm:"serializer:json"`
+	SpiceLevel  int          `json:"spice_level"`                   // 0 is not spicy up to MaxSpiceLevel
+	Available   bool         `json:"available" gorm:"default:true"` // Products off sale stay on the menu but cannot be ordered
 }
 
 // Hotel represents a hotel in the system.
 type Hotel struct {
-	ID      int16  `json:"hotel,omitempty"`
-	Name    string `json:"name"`
-	City    string `json:"city"`
-	Address string `json:"address"`
-	State   string `json:"state"`
+	ID               int16    `json:"hotel,omitempty"`
+	Name             string   `json:"name"`
+	City             string   `json:"city"`
+	Address          string   `json:"address"`
+	State            string   `json:"state"`
+	OwnerID          *int     `json:"owner_id,omitempty"`                            // User with the restaurant_owner role who manages the hotel
+	Latitude         *float64 `json:"latitude,omitempty"`                            // Hotels without a location are left out of nearby searches
+	Longitude        *float64 `json:"longitude,omitempty"`                           // Set together with Latitude
+	DeliveryRadiusKm *float64 `json:"delivery_radius_km,omitempty" gorm:"default:0"` // 0 means pickup only, nil leaves it unchanged on update
+	TimeZone         string   `json:"time_zone"`                                     // IANA time zone its opening hours are in
+	Paused           bool     `json:"paused"`                                        // Set by the restaurant to stop taking orders
+
+	IsOpen      bool       `gorm:"-" json:"is_open"`                 // Whether orders are accepted right now
+	NextOpensAt *time.Time `gorm:"-" json:"next_opens_at,omitempty"` // When a closed hotel opens next, unknown while paused
 }
 
 // UserCart represents a cart for a user with a product and quantity.
@@ -57,7 +135,7 @@ type UserCartProduct struct {
 	StockLeft int    `json:"stockLeft" gorm:"column:stockLeft"`
 	HotelID   int    `json:"hotel_id"` // Hotel_id renamed to HotelID for consistency
 	Category  string `json:"category"`
-	Price     int    `json:"price"`
+	Price     Money  `json:"price"`
 	HotelName string `json:"hotel_name"`
 }
 type CartResponse struct {
@@ -75,55 +153,72 @@ type CartProducts struct {
 type CreateOrderRequest struct {
 	ID            int                   `gorm:"primaryKey" json:"id"`             // Order ID (optional for request, auto-generated in DB)
 	UserID        int                   `json:"user_id"`                          // ID of the user placing the order
+	HotelID       int                   `json:"hotel_id"`                         // Hotel the order is placed at, taken from the route
 	PhoneNumber   string                `json:"phone_number"`                     // Phone number for the order
 	DriveThruCode string                `json:"drive_thru_code,omitempty"`        // Optional drive-thru code
 	OrderStatus   string                `json:"order_status"`                     // Status of the order (e.g., pending)
 	Products      []OrderProductRequest `json:"products"`                         // List of products in the order
 	IsDelivered   bool                  `json:"is_delivered"`                     // Delivery status
-	OrderTotal    float64               `json:"order_total"`                      // Total price of the order
+	OrderTotal    Money                 `json:"order_total"`                      // Expected total, checked against the server computed total when set
 	CreatedAt     time.Time             `gorm:"autoCreateTime" json:"created_at"` // Timestamp when the order was created
 	UpdatedAt     time.Time             `gorm:"autoUpdateTime" json:"updated_at"` // Timestamp when the order was last updated
 }
 
 // OrderProductRequest represents each product in an order.
 type OrderProductRequest struct {
-	ProductID       int     `json:"product_id"`        // ID of the product
-	Quantity        int     `json:"quantity"`          // Quantity of the product
-	PriceAtPurchase float64 `json:"price_at_purchase"` // Price of the product at the time of the order
+	ProductID       int   `json:"product_id"`        // ID of the product
+	Quantity        int   `json:"quantity"`          // Quantity of the product
+	PriceAtPurchase Money `json:"price_at_purchase"` // Expected unit price, checked against the current price when set
 }
 
 type Order struct {
 	ID            int            `gorm:"primaryKey" json:"id"`
 	UserID        int            `json:"user_id"`
+	HotelID       int            `json:"hotel_id"`
+	CourierID     *int           `json:"courier_id,omitempty"` // Courier who picked the order up
 	PhoneNumber   string         `json:"phone_number"`
 	DriveThruCode string         `json:"drive_thru_code,omitempty"`
-	OrderStatus   string         `json:"order_status"`
+	OrderStatus   OrderStatus    `json:"order_status"`
 	IsDelivered   bool           `json:"is_delivered"`
-	OrderTotal    float64        `json:"order_total"`
+	OrderTotal    Money          `json:"order_total" gorm:"embedded;embeddedPrefix:order_total_"`
+	PaymentDueAt  *time.Time     `json:"payment_due_at,omitempty"` // Reserved stock is released if the order is still pending after this
+	Payment       *Payment       `gorm:"-" json:"payment,omitempty"`
 	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	Products      []OrderProduct `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"products"` // Ensures cascading delete
 }
 
 type OrderProduct struct {
-	ID              int     `gorm:"primaryKey" json:"id"` // Auto-incremented by MySQL
-	OrderID         int     `json:"order_id"`
-	ProductID       int     `json:"product_id"`
-	Quantity        int     `json:"quantity"`
-	PriceAtPurchase float64 `json:"price_at_purchase"`
+	ID              int   `gorm:"primaryKey" json:"id"` // Auto-incremented by MySQL
+	OrderID         int   `json:"order_id"`
+	ProductID       int   `json:"product_id"`
+	Quantity        int   `json:"quantity"`
+	PriceAtPurchase Money `json:"price_at_purchase" gorm:"embedded;embeddedPrefix:price_at_purchase_"`
 }
 
 type OrderResponse struct {
-	ID            int               `json:"id"`
-	UserID        int               `json:"user_id"`
-	PhoneNumber   string            `json:"phone_number"`
-	DriveThruCode string            `json:"drive_thru_code,omitempty"`
-	OrderStatus   string            `json:"order_status"`
-	IsDelivered   bool              `json:"is_delivered"`
-	OrderTotal    float64           `json:"order_total"`
-	CreatedAt     time.Time         `json:"created_at"`
-	UpdatedAt     time.Time         `json:"updated_at"`
-	Products      []UserCartProduct `json:"products"` // Ensures cascading delete
+	ID            int                 `json:"id"`
+	UserID        int                 `json:"user_id"`
+	HotelID       int                 `json:"hotel_id"`
+	PhoneNumber   string              `json:"phone_number"`
+	DriveThruCode string              `json:"drive_thru_code,omitempty"`
+	OrderStatus   OrderStatus         `json:"order_status"`
+	IsDelivered   bool                `json:"is_delivered"`
+	OrderTotal    Money               `json:"order_total"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	Products      []OrderLineResponse `json:"products"`
+}
+
+// OrderLineResponse is a product of an order as it was bought. Only the name and image come
+// from the current menu, the quantity and price are the ones stored with the order.
+type OrderLineResponse struct {
+	ProductID       int    `json:"product_id"`
+	Name            string `json:"name"`
+	ImageURL        string `json:"image_url,omitempty"`
+	HotelName       string `json:"hotel_name"`
+	Quantity        int    `json:"quantity"`
+	PriceAtPurchase Money  `json:"price_at_purchase"`
 }
 
 // CustomResponse represents a generic API response.
@@ -135,36 +230,75 @@ type CustomResponse struct {
 // MCDUsecase defines the use case interface for managing users, orders, etc.
 type MCDUsecase interface {
 	// User CRUD operations
-	CreateUser(user User) error
+	CreateUser(request CreateUserRequest) error
+	CreateCourier(request CreateUserRequest) error
 	UserLogin(userData UserLogin) (LoginResponse, error)
+	VerifyToken(token string) (AuthClaims, error)
+	RefreshToken(refreshToken string) (LoginResponse, error)
+	Logout(userID int) error
+	SendOTP(request SendOTPRequest) error
+	VerifyOTP(request VerifyOTPRequest) error
+	RequestPasswordReset(request PasswordResetRequest) error
+	ResetPassword(request PasswordResetConfirm) error
 	UpdateUser(user User) error
 	DeleteUser(userID string) error
-	GetUserById(userID string) (User, error)
+	GetUserById(userID string) (UserResponse, error)
 
 	// Product CRUD operations
-	CreateProduct(product Product) error
-	UpdateProduct(product Product) error
+	CreateProduct(actor AuthClaims, product Product) error
+	UpdateProduct(actor AuthClaims, product Product) error
 	DeleteProduct(productID string) error
 	GetProductById(productID string) (Product, error)
-	GetProductsByHotel(hotelID string) ([]Product, error)
+	GetProductsByHotel(query ProductQuery, page PageRequest) (Page[Product], error)
+	GetMenu(query ProductQuery, page PageRequest) (Menu, error)
+	CreateMenuSection(actor AuthClaims, section MenuSection) (MenuSection, error)
+	UpdateMenuSection(actor AuthClaims, section MenuSection) (MenuSection, error)
+	DeleteMenuSection(actor AuthClaims, hotelID, sectionID int) error
+	SetProductAvailability(actor AuthClaims, productID int, available bool) error
 
 	// Hotel CRUD operations
 	CreateHotel(hotel Hotel) error
 	UpdateHotel(hotel Hotel) error
 	DeleteHotel(hotelID string) error
-	GetHotels() ([]Hotel, error)
+	GetHotels(query HotelQuery, page PageRequest) (Page[Hotel], error)
+	GetNearbyHotels(latitude, longitude, radiusKm float64) ([]NearbyHotel, error)
+	Search(query SearchQuery) ([]SearchResult, error)
+	RebuildSearchIndex() (int, error)
+	SetOpeningHours(actor AuthClaims, hotelID int, schedule OpeningSchedule) error
+	AddHotelClosure(actor AuthClaims, hotelID int, request HotelClosureRequest) (HotelClosure, error)
+	SetHotelPaused(actor AuthClaims, hotelID int, paused bool) error
 
 	AddProductToCart(CartProducts) error
 	DeleteProductFromCart(CartProducts) error
 	UpdateQuantityInCart(CartProducts) error
 	GetUserCart(userID int) (CartResponse, error)
+	CheckoutCart(userID int) (Order, error)
 
 	// Order operations
-	CreateOrder(order CreateOrderRequest) error
-	//CancelOrder(orderID int) error
+	CreateOrder(order CreateOrderRequest) (Order, error)
+	CancelOrder(actor AuthClaims, orderID int, request CancelOrderRequest) (Order, error)
+	RejectOrder(actor AuthClaims, orderID int, request CancelOrderRequest) (Order, error)
 	//GetTodayOrders() ([]Order, error)
-	GetUserOrders(phoneNumber int) ([]OrderResponse, error)
-	MarkOrderCompleted(orderID int) error
+	GetUserOrders(query OrderQuery, page PageRequest) (Page[OrderResponse], error)
+	UpdateOrderStatus(actor AuthClaims, orderID int, request UpdateOrderStatusRequest) (Order, error)
+	GetOrderStatusHistory(actor AuthClaims, orderID int) ([]OrderStatusChange, error)
+	ReleaseExpiredReservations() (int, error)
+
+	// Payments
+	CapturePayment(actor AuthClaims, orderID int) (Order, error)
+	GetOrderPayment(actor AuthClaims, orderID int) (Payment, error)
+	RefundOrder(actor AuthClaims, orderID int, request RefundRequest) (Refund, error)
+	ReconcileOrderPayment(actor AuthClaims, orderID int) (PaymentReconciliation, error)
+	AdjustLedger(actor AuthClaims, orderID int, request LedgerAdjustmentRequest) (PaymentReconciliation, error)
+	RetryPendingRefunds() (int, error)
+	RetryPendingVoids() (int, error)
+	HandlePaymentWebhook(payload []byte, signature string) (duplicate bool, err error)
+
+	// Idempotency keys
+	BeginIdempotentRequest(userID int, key string, requestHash string) (record IdempotencyKey, replay bool, err error)
+	CompleteIdempotentRequest(recordID int, statusCode int, responseBody []byte) error
+	AbandonIdempotentRequest(recordID int) error
+	PurgeExpiredIdempotencyKeys() (int64, error)
 }
 
 // MCDRepository defines the repository interface for interacting with the database.
@@ -175,20 +309,56 @@ type MCDRepository interface {
 	DeleteUser(userID string) error
 	GetUserById(userID string) (User, error)
 	GetUserByEmail(email string) (User, error)
+	EmailExists(email string) (bool, error)
+	PhoneNumberExists(phoneNumber string) (bool, error)
+
+	// Refresh token operations
+	CreateRefreshToken(token RefreshToken) error
+	GetRefreshTokenByHash(tokenHash string) (RefreshToken, error)
+	RotateRefreshToken(oldTokenID int, newToken RefreshToken) error
+	RevokeRefreshTokenFamily(familyID string) error
+	RevokeUserRefreshTokens(userID int) error
+
+	// OTP operations
+	CreateOTP(otp OTP) error
+	GetLatestOTP(userID int) (OTP, error)
+	IncrementOTPAttempts(otpID int) error // Returns OTPAttemptsExceeded once MaxAttempts were made
+	ConsumeOTP(otpID int) error
+	MarkUserVerified(userID int) error
+
+	// Password reset operations
+	CreatePasswordReset(reset PasswordReset) error
+	GetPasswordResetByHash(tokenHash string) (PasswordReset, error)
+	ResetPassword(resetID int, userID int, passwordHash string) error
 
 	// Product CRUD operations
-	CreateProduct(product Product) error
-	UpdateProduct(product Product) error
+	CreateProduct(product Product) (Product, error)
+	UpdateProduct(product Product) (Product, error) // Returns the product as stored after the update
 	DeleteProduct(productID string) error
 	GetProductById(productID string) (Product, error)
-	GetProductsByHotel(hotelID string) ([]Product, error)
+	GetProductsByHotel(query ProductQuery) ([]Product, error)
+	SetProductAvailable(productID int, available bool) error
+
+	// Menu sections
+	GetMenuSections(hotelID int) ([]MenuSection, error) // Ordered by position, then id
+	CreateMenuSection(section MenuSection) (MenuSection, error)
+	UpdateMenuSection(section MenuSection) (MenuSection, error)
+	DeleteMenuSection(sectionID int) error // Its products move to no section
 
 	// Hotel CRUD operations
-	CreateHotel(hotel Hotel) error
-	UpdateHotel(hotel Hotel) error
+	CreateHotel(hotel Hotel) (Hotel, error)
+	UpdateHotel(hotel Hotel) (Hotel, error) // Returns the hotel as stored after the update
 	DeleteHotel(hotelID string) error
-	GetHotels() ([]Hotel, error)
+	GetHotels(query HotelQuery) ([]Hotel, error)
 	GetHotelByID(int) (*Hotel, error)
+	GetHotelsWithin(bounds GeoBounds) ([]Hotel, error)
+
+	// Opening hours and closures, read for several hotels at once and keyed by hotel id
+	GetOpeningHours(hotelIDs []int) (map[int][]OpeningHours, error)
+	ReplaceOpeningHours(hotelID int, timeZone string, hours []OpeningHours) error
+	GetHotelClosures(hotelIDs []int, endingAfter time.Time) (map[int][]HotelClosure, error)
+	CreateHotelClosure(closure HotelClosure) (HotelClosure, error)
+	SetHotelPaused(hotelID int, paused bool) error
 
 	AddProductToCart(CartProducts) error
 	DeleteProductFromCart(CartProducts) error
@@ -197,9 +367,42 @@ type MCDRepository interface {
 	GetUserCart(userID int) ([]CartProducts, error)
 
 	// Order operations
-	CreateOrder(order Order) error
-	//CancelOrder(orderID int) error
+	CreateOrder(order Order) (Order, error)
+	CheckoutCart(order Order) (Order, error)
+	GetOrdersPastPaymentDue(now time.Time) ([]int, error)
+	CancelOrder(change OrderStatusChange) error
 	//GetTodayOrders() ([]Order, error)
-	GetUserOrders(phoneNumber int) ([]Order, error)
-	MarkOrderCompleted(orderID int) error
+	GetUserOrders(query OrderQuery) ([]Order, error)
+	GetOrderByID(orderID int) (Order, error)
+	UpdateOrderStatus(change OrderStatusChange) error
+	GetOrderStatusHistory(orderID int) ([]OrderStatusChange, error)
+
+	// Payments
+	CreatePayment(payment Payment) (Payment, error)
+	GetPaymentByOrderID(orderID int) (Payment, error)
+	GetPaymentByTransactionID(transactionID string) (Payment, error)
+	CapturePayment(paymentID int, change OrderStatusChange, journal []LedgerEntry) error
+	GetPaymentsPendingVoid(updatedBefore time.Time) ([]Payment, error)
+	VoidPayment(paymentID int) error // Moves a void_pending payment to voided
+
+	// Refunds and the payment ledger
+	GetRefunds(paymentID int) ([]Refund, error)
+	GetPendingRefunds(createdBefore time.Time) ([]Refund, error)
+	CreateRefund(refund Refund) (Refund, error)
+	CompleteRefund(refundID int, journal []LedgerEntry) error
+	FailRefund(refundID int) error
+	GetLedgerEntries(orderID int) ([]LedgerEntry, error)
+	WriteLedgerAdjustment(journal []LedgerEntry) error
+
+	// Payment webhooks
+	WebhookEventExists(eventID string) (bool, error)
+	CreateWebhookEvent(event WebhookEvent) error
+
+	// Idempotency keys
+	CreateIdempotencyKey(record IdempotencyKey) (IdempotencyKey, error)
+	GetIdempotencyKey(userID int, key string) (IdempotencyKey, error)
+	CompleteIdempotencyKey(recordID int, statusCode int, responseBody []byte) error
+	TakeOverIdempotencyKey(recordID int, now time.Time, leaseExpiresAt time.Time) (bool, error) // False if the key was completed or its lease renewed
+	DeleteIdempotencyKey(recordID int) error
+	DeleteExpiredIdempotencyKeys(now time.Time) (int64, error)
 }
@@ -0,0 +1,80 @@
+package domain
+
+// Limits matching the columns of the menu_sections and products tables
+const (
+	MaxMenuNameLength        = 100
+	MaxMenuDescriptionLength = 1000
+	MaxImageURLLength        = 2048
+	MaxSpiceLevel            = 3 // 0 is not spicy, 3 is hot
+)
+
+// OtherMenuSection names the section listing the products that are not in any section
+const OtherMenuSection = "Other"
+
+// DietaryTag is a diet a dish is suitable for
+type DietaryTag string
+
+const (
+	DietVegetarian DietaryTag = "vegetarian"
+	DietVegan      DietaryTag = "vegan"
+	DietGlutenFree DietaryTag = "gluten_free"
+)
+
+// IsValid reports whether the tag is one of the defined dietary tags
+func (tag DietaryTag) IsValid() bool {
+	switch tag {
+	case DietVegetarian, DietVegan, DietGlutenFree:
+		return true
+	}
+	return false
+}
+
+// Allergen is one of the major food allergens a dish may contain
+type Allergen string
+
+const (
+	AllergenGluten    Allergen = "gluten"
+	AllergenDairy     Allergen = "dairy"
+	AllergenEggs      Allergen = "eggs"
+	AllergenPeanuts   Allergen = "peanuts"
+	AllergenTreeNuts  Allergen = "tree_nuts"
+	AllergenSoy       Allergen = "soy"
+	AllergenFish      Allergen = "fish"
+	AllergenShellfish Allergen = "shellfish"
+	AllergenSesame    Allergen = "sesame"
+)
+
+// IsValid reports whether the allergen is one of the defined allergens
+func (allergen Allergen) IsValid() bool {
+	switch allergen {
+	case AllergenGluten, AllergenDairy, AllergenEggs, AllergenPeanuts, AllergenTreeNuts,
+		AllergenSoy, AllergenFish, AllergenShellfish, AllergenSesame:
+		return true
+	}
+	return false
+}
+
+// MenuSection is a heading of a hotel's menu such as Starters or Mains. Sections are listed
+// by position, then in the order they were created.
+type MenuSection struct {
+	ID          int       `gorm:"primaryKey" json:"id"` // 0 for the Other section
+	HotelID     int       `json:"hotel_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Position    int       `json:"position"`
+	Products    []Product `gorm:"-" json:"products,omitempty"`
+}
+
+// Menu is a page of a hotel's products grouped by menu section, in section order. Products
+// without a section come last under OtherMenuSection. Pages follow the menu order, products are
+// sorted within their section and a section spanning pages continues on the next one.
+type Menu struct {
+	HotelID    int           `json:"hotel_id"`
+	Sections   []MenuSection `json:"sections"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// ProductAvailabilityRequest is the body of a request taking a product off or back on sale
+type ProductAvailabilityRequest struct {
+	Available bool `json:"available"`
+}
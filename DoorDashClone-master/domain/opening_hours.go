@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// ClockLayout is the layout of opening and closing times, 24-hour local time of the hotel
+const ClockLayout = "15:04"
+
+// OpeningHours is one weekly opening period of a hotel in its own time zone. A period whose
+// Closes is not after Opens runs past midnight, so 00:00 to 00:00 is open the whole day.
+type OpeningHours struct {
+	ID      int    `gorm:"primaryKey" json:"-"`
+	HotelID int    `json:"-"`
+	Weekday int    `json:"weekday"` // 0 is Sunday, as in time.Weekday
+	Opens   string `json:"opens"`   // HH:MM
+	Closes  string `json:"closes"`  // HH:MM
+}
+
+// OpeningSchedule replaces a hotel's weekly opening hours. A hotel without any opening hours
+// is open around the clock unless it is paused or closed.
+type OpeningSchedule struct {
+	TimeZone string         `json:"time_zone"` // IANA name such as America/New_York, defaults to UTC
+	Hours    []OpeningHours `json:"hours"`
+}
+
+// HotelClosure closes a hotel for a one-off period such as a holiday, whatever its opening hours
+type HotelClosure struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	HotelID   int       `json:"hotel_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// HotelClosureRequest is the body of a request adding a closure
+type HotelClosureRequest struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason"`
+}
+
+// HotelPauseRequest is the body of a request pausing or resuming orders at a hotel
+type HotelPauseRequest struct {
+	Paused bool `json:"paused"`
+}
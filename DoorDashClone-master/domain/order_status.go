@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+const (
+	OrderPaymentPending OrderStatus = "payment_pending" // Stock is reserved, the order waits for its payment to be captured
+	OrderPlaced         OrderStatus = "placed"
+	OrderAccepted       OrderStatus = "accepted"
+	OrderPreparing      OrderStatus = "preparing"
+	OrderReady          OrderStatus = "ready"
+	OrderPickedUp       OrderStatus = "picked_up"
+	OrderDelivered      OrderStatus = "delivered"
+	OrderCancelled      OrderStatus = "cancelled"
+	OrderRejected       OrderStatus = "rejected"
+	OrderRefunded       OrderStatus = "refunded"
+)
+
+// orderStatuses lists every defined order status
+var orderStatuses = []OrderStatus{
+	OrderPaymentPending, OrderPlaced, OrderAccepted, OrderPreparing, OrderReady,
+	OrderPickedUp, OrderDelivered, OrderCancelled, OrderRejected, OrderRefunded,
+}
+
+// IsValid reports whether the status is one of the defined order statuses
+func (status OrderStatus) IsValid() bool {
+	for _, defined := range orderStatuses {
+		if status == defined {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusChange is one row of an order's status history. ChangedBy is nil when the
+// change was made by the system, for example when an unpaid order expires.
+type OrderStatusChange struct {
+	ID            int         `gorm:"primaryKey" json:"id"`
+	OrderID       int         `json:"order_id"`
+	FromStatus    OrderStatus `json:"from_status,omitempty"`
+	ToStatus      OrderStatus `json:"to_status"`
+	ChangedBy     *int        `json:"changed_by,omitempty"`
+	ChangedByRole Role        `json:"changed_by_role,omitempty"`
+	Reason        string      `json:"reason,omitempty"`
+	CreatedAt     time.Time   `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// UpdateOrderStatusRequest asks to move an order to the given status
+type UpdateOrderStatusRequest struct {
+	Status OrderStatus `json:"status"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// CancelOrderRequest carries why a customer cancelled or a restaurant rejected an order
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+// NotificationChannel is the medium a notification is delivered through.
+type NotificationChannel string
+
+const (
+	ChannelEmail NotificationChannel = "email"
+	ChannelSMS   NotificationChannel = "sms"
+)
+
+// Notification is a message addressed to a single recipient.
+type Notification struct {
+	Channel NotificationChannel `json:"channel"`
+	To      string              `json:"to"`
+	Subject string              `json:"subject"`
+	Body    string              `json:"body"`
+}
+
+// Notifier delivers notifications such as OTP codes to users.
+type Notifier interface {
+	Send(notification Notification) error
+}
+
+// OTP is a one time password issued to verify a user's email or phone number.
+type OTP struct {
+	ID          int                 `gorm:"primaryKey" json:"id"`
+	UserID      int                 `json:"user_id"`
+	Channel     NotificationChannel `json:"channel"`
+	Destination string              `json:"destination"`
+	CodeHash    string              `json:"-"`
+	Attempts    int                 `json:"attempts"`
+	MaxAttempts int                 `json:"max_attempts"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+	ConsumedAt  *time.Time          `json:"consumed_at,omitempty"`
+	CreatedAt   time.Time           `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type SendOTPRequest struct {
+	Email   string              `json:"email"`
+	Channel NotificationChannel `json:"channel"`
+}
+
+type VerifyOTPRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes of list endpoints, larger page sizes are capped to MaxPageSize
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Fields lists can be sorted by, each list allows some of them
+const (
+	SortByID        = "id"
+	SortByName      = "name"
+	SortByPrice     = "price"
+	SortByCreatedAt = "created_at"
+)
+
+// Page is one page of a list. NextCursor fetches the following page and is empty on the last one.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PageRequest is the paging and sorting asked for by a client. Sort is a field name, prefixed
+// with - to sort descending.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+	Sort   string
+}
+
+// Sort orders a list by one field, ties are broken by id in the same direction
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+func (sort Sort) String() string {
+	if sort.Descending {
+		return "-" + sort.Field
+	}
+	return sort.Field
+}
+
+// ParseSort reads a sort such as "-price", an empty value is the fallback
+func ParseSort(value string, fallback Sort) Sort {
+	if value == "" {
+		return fallback
+	}
+	if field, ok := strings.CutPrefix(value, "-"); ok {
+		return Sort{Field: field, Descending: true}
+	}
+	return Sort{Field: value}
+}
+
+// Cursor marks where a page ended: the sort it was listed by and the sort key and id of its last
+// item. Clients get it encoded and pass it back unchanged.
+type Cursor struct {
+	Sort    string `json:"s"`
+	Key     string `json:"k,omitempty"`
+	ID      int    `json:"i"`
+	Section int    `json:"m,omitempty"` // Menu section of the last item of a menu page, 0 for products without one
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (cursor Cursor) Encode() string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor reads a cursor produced by Encode
+func DecodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, InvalidCursor
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, InvalidCursor
+	}
+	if _, err := cursor.KeyValue(); err != nil {
+		return cursor, InvalidCursor
+	}
+	return cursor, nil
+}
+
+// KeyValue returns the sort key of the cursor as the type of its field: int64 for prices,
+// time.Time for creation times and string for names. Lists sorted by id have no key.
+func (cursor Cursor) KeyValue() (any, error) {
+	switch ParseSort(cursor.Sort, Sort{}).Field {
+	case SortByID:
+		return nil, nil
+	case SortByPrice:
+		return strconv.ParseInt(cursor.Key, 10, 64)
+	case SortByCreatedAt:
+		return time.Parse(time.RFC3339Nano, cursor.Key)
+	case SortByName:
+		return cursor.Key, nil
+	}
+	return nil, InvalidCursor
+}
+
+// ListOptions are the resolved paging and sorting handed to repositories
+type ListOptions struct {
+	Sort  Sort
+	Limit int     // Repositories return at most this many rows
+	After *Cursor // Continue after this item, nil for the first page
+}
+
+// HotelQuery filters the hotel list, empty filters match every hotel
+type HotelQuery struct {
+	City  string
+	State string
+	ListOptions
+}
+
+// ProductQuery filters the products of a hotel
+type ProductQuery struct {
+	HotelID  int
+	Category string
+	MinPrice *int64 // Minor units, inclusive
+	MaxPrice *int64 // Minor units, inclusive
+	Section  *int   // Only the products of this menu section, 0 for the products without a section
+	ListOptions
+}
+
+// OrderQuery filters the orders of a user
+type OrderQuery struct {
+	UserID        int
+	Status        OrderStatus
+	CreatedFrom   *time.Time // Inclusive
+	CreatedBefore *time.Time // Exclusive
+	ListOptions
+}
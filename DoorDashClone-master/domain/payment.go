@@ -0,0 +1,80 @@
+package domain
+
+import "time"
+
+// PaymentStatus is the state of a row in the PAYMENTS table
+type PaymentStatus string
+
+const (
+	PaymentAuthorized     PaymentStatus = "authorized"         // The gateway holds the amount, nothing has been taken yet
+	PaymentCaptured       PaymentStatus = "captured"           // Money has been taken from the customer
+	PaymentVoidPending    PaymentStatus = "void_pending"       // The order ended before capture, the gateway must still drop the authorization
+	PaymentVoided         PaymentStatus = "voided"             // The order ended before capture, the authorization is dropped
+	PaymentRefundPending  PaymentStatus = "refund_pending"     // The order was cancelled or rejected after capture and the money must be returned
+	PaymentPartlyRefunded PaymentStatus = "partially_refunded" // Some of the captured amount has been returned
+	PaymentRefunded       PaymentStatus = "refunded"           // The whole captured amount has been returned
+)
+
+// Payment is the payment intent of an order. It is authorized when the order is placed and
+// captured when the customer pays, which is what moves the order from payment_pending to placed.
+type Payment struct {
+	ID            int           `gorm:"primaryKey" json:"id"`
+	UserID        int           `json:"user_id"`
+	OrderID       int           `json:"order_id"`
+	Amount        Money         `json:"amount" gorm:"embedded"`
+	TransactionID string        `json:"transaction_id"` // Reference of the payment at the gateway
+	Status        PaymentStatus `json:"status"`
+	CreatedAt     time.Time     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time     `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// PaymentIntent is what the gateway is asked to authorize for an order
+type PaymentIntent struct {
+	OrderID int
+	UserID  int
+	Amount  Money
+}
+
+// PaymentSignatureHeader carries the gateway's signature of a webhook payload
+const PaymentSignatureHeader = "Payment-Signature"
+
+// DefaultWebhookTolerance is how far a webhook timestamp may be from the current time, unless
+// PAYMENT_WEBHOOK_TOLERANCE says otherwise
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// Payment event types the service acts on, other events are acknowledged and ignored
+const (
+	PaymentEventCaptured = "payment.captured" // The gateway captured an authorized payment
+	PaymentEventFailed   = "payment.failed"   // The authorization failed or expired at the gateway
+)
+
+// PaymentEvent is a verified notification sent by the gateway about one of its transactions
+type PaymentEvent struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	TransactionID string `json:"transaction_id"`
+	Amount        Money  `json:"amount"`
+}
+
+// WebhookEvent records a gateway event that has been processed, so a redelivery or a
+// replay of the same event ID is acknowledged without being applied twice
+type WebhookEvent struct {
+	ID            int       `gorm:"primaryKey" json:"id"`
+	EventID       string    `json:"event_id"`
+	Type          string    `json:"type"`
+	TransactionID string    `json:"transaction_id"`
+	Payload       []byte    `json:"-"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// PaymentGateway moves money through a payment provider. Implementations return
+// PaymentDeclined when the provider refuses an authorization, capture or refund.
+// Refunds are made once per reference, asking again for a reference the provider already
+// refunded confirms it without moving money again, so a refund can be retried safely.
+type PaymentGateway interface {
+	Authorize(intent PaymentIntent) (transactionID string, err error)
+	Void(transactionID string) error // Releases an authorization that was never captured
+	Capture(transactionID string, amount Money) error
+	Refund(transactionID, reference string, amount Money) error
+	VerifyWebhook(payload []byte, signature string) (PaymentEvent, error)
+}
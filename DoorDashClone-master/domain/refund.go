@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// RefundStatus is the state of a refund at the gateway
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"   // Reserved against the payment, the gateway has not answered yet
+	RefundSucceeded RefundStatus = "succeeded" // The money was returned to the customer
+	RefundFailed    RefundStatus = "failed"    // The gateway refused, the amount can be refunded again
+)
+
+// Refund returns part or all of a captured payment. Refunds that have not failed count
+// against the payment, so together they can never exceed the captured amount.
+type Refund struct {
+	ID        int          `gorm:"primaryKey" json:"id"`
+	PaymentID int          `json:"payment_id"`
+	OrderID   int          `json:"order_id"`
+	Amount    Money        `json:"amount" gorm:"embedded"`
+	Reason    string       `json:"reason,omitempty"`
+	Status    RefundStatus `json:"status"`
+	CreatedBy int          `json:"created_by"`
+	Items     []RefundItem `gorm:"foreignKey:RefundID" json:"items,omitempty"` // Empty for a refund of the whole remaining amount
+	CreatedAt time.Time    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// RefundItem is the part of a refund paying back some units of one order line
+type RefundItem struct {
+	ID             int   `gorm:"primaryKey" json:"id"`
+	RefundID       int   `json:"refund_id"`
+	OrderProductID int   `json:"order_product_id"`
+	ProductID      int   `json:"product_id"`
+	Quantity       int   `json:"quantity"`
+	Amount         Money `json:"amount" gorm:"embedded"`
+}
+
+// RefundRequest asks to refund an order, in full when no items are listed
+type RefundRequest struct {
+	Reason string              `json:"reason"`
+	Items  []RefundItemRequest `json:"items,omitempty"`
+}
+
+// RefundItemRequest refunds quantity units of the order line of a product
+type RefundItemRequest struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
@@ -0,0 +1,69 @@
+package domain
+
+// Role is the role stored against a user and carried in their access token.
+type Role string
+
+const (
+	RoleCustomer        Role = "customer"
+	RoleRestaurantOwner Role = "restaurant_owner"
+	RoleCourier         Role = "courier"
+	RoleAdmin           Role = "admin"
+)
+
+// Permission is a single action a role may be allowed to perform.
+type Permission string
+
+const (
+	PermissionUserManage   Permission = "user:manage"
+	PermissionHotelManage  Permission = "hotel:manage"
+	PermissionHotelOperate Permission = "hotel:operate"
+	PermissionProductWrite Permission = "product:write"
+	PermissionCartManage   Permission = "cart:manage"
+	PermissionOrderPlace   Permission = "order:place"
+	PermissionOrderFulfil  Permission = "order:fulfil"
+	PermissionOrderDeliver Permission = "order:deliver"
+	PermissionOrderRefund  Permission = "order:refund"
+)
+
+// rolePermissions maps every defined role to the permissions it grants
+var rolePermissions = map[Role][]Permission{
+	RoleCustomer: {
+		PermissionCartManage,
+		PermissionOrderPlace,
+	},
+	RoleRestaurantOwner: {
+		PermissionHotelOperate,
+		PermissionProductWrite,
+		PermissionOrderFulfil,
+	},
+	RoleCourier: {
+		PermissionOrderDeliver,
+	},
+	RoleAdmin: {
+		PermissionUserManage,
+		PermissionHotelManage,
+		PermissionHotelOperate,
+		PermissionProductWrite,
+		PermissionCartManage,
+		PermissionOrderPlace,
+		PermissionOrderFulfil,
+		PermissionOrderDeliver,
+		PermissionOrderRefund,
+	},
+}
+
+// IsValid reports whether the role is one of the defined roles
+func (role Role) IsValid() bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether the role grants the given permission
+func (role Role) HasPermission(permission Permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package domain
+
+// SearchKind is what a search hit refers to
+type SearchKind string
+
+const (
+	SearchHotel   SearchKind = "hotel"
+	SearchProduct SearchKind = "product"
+)
+
+// MaxSearchResults caps how many hits a single search returns
+const MaxSearchResults = 50
+
+// SearchQuery is a full-text search over dish names, categories and restaurant names
+type SearchQuery struct {
+	Text  string
+	City  string // Only hotels in this city, and their products, when set
+	Limit int
+}
+
+// SearchHit is an indexed hotel or product matching a search, higher scores rank first
+type SearchHit struct {
+	Kind  SearchKind
+	ID    int
+	Score float64
+}
+
+// SearchResult is a search hit with the hotel or product it refers to
+type SearchResult struct {
+	Kind    SearchKind `json:"kind"`
+	Score   float64    `json:"score"`
+	Hotel   *Hotel     `json:"hotel"`             // The matching hotel, or the hotel serving the matching product
+	Product *Product   `json:"product,omitempty"` // Set for product hits
+}
+
+// SearchIndex keeps hotels and products searchable. The usecase writes every change to a hotel
+// or product through to it once the repository has stored the change. Indexing a hotel or
+// product that is already indexed replaces it; removing a hotel also removes its products.
+type SearchIndex interface {
+	IndexHotel(hotel Hotel) error
+	IndexProduct(product Product) error
+	RemoveHotel(hotelID int) error
+	RemoveProduct(productID int) error
+	Search(query SearchQuery) ([]SearchHit, error)
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	domain "mcd/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IdempotentReplayedHeader is set on responses replayed from an earlier request with the same key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// responseRecorder copies everything written to the response so it can be stored
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (recorder *responseRecorder) Write(b []byte) (int, error) {
+	recorder.body.Write(b)
+	return recorder.ResponseWriter.Write(b)
+}
+
+// idempotent replays the stored response when a request is retried with the same
+// Idempotency-Key header and body, so a retried order is only placed once. Requests without
+// the header are handled as usual. It must be registered after the authenticate middleware.
+func (delivery *delivery) idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(context echo.Context) error {
+		key := context.Request().Header.Get(domain.IdempotencyKeyHeader)
+		if key == "" {
+			return next(context)
+		}
+
+		body, err := io.ReadAll(context.Request().Body)
+		if err != nil {
+			return respondError(context, domain.InvalidOrder)
+		}
+		context.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		record, replay, err := delivery.MCDUsecase.BeginIdempotentRequest(authenticatedUserID(context), key, requestHash(context, body))
+		if err != nil {
+			return respondError(context, err)
+		}
+		if replay {
+			context.Response().Header().Set(IdempotentReplayedHeader, "true")
+			return context.Blob(record.StatusCode, echo.MIMEApplicationJSONCharsetUTF8, record.ResponseBody)
+		}
+
+		recorder := &responseRecorder{ResponseWriter: context.Response().Writer}
+		context.Response().Writer = recorder
+		err = next(context)
+		context.Response().Writer = recorder.ResponseWriter
+
+		// Server errors are not stored so that the retry gets another chance
+		status := context.Response().Status
+		if err != nil || status >= http.StatusInternalServerError {
+			if abandonErr := delivery.MCDUsecase.AbandonIdempotentRequest(record.ID); abandonErr != nil {
+				log.Printf("Error abandoning idempotency key: %v", abandonErr)
+			}
+			return err
+		}
+		if err := delivery.MCDUsecase.CompleteIdempotentRequest(record.ID, status, recorder.body.Bytes()); err != nil {
+			log.Printf("Error completing idempotency key: %v", err)
+		}
+		return nil
+	}
+}
+
+// requestHash identifies the request a key was first used for
+func requestHash(context echo.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(context.Request().Method + " " + context.Request().URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -2,12 +2,13 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	domain "mcd/domain"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
@@ -20,61 +21,73 @@ func NewMCDHandler(e *echo.Echo, useCase domain.MCDUsecase) {
 
 	// User routes
 	e.POST("/v1/create/user", handler.createUser)
+	e.POST("/v1/create/courier", handler.createCourier, handler.authenticate, RequirePermission(domain.PermissionUserManage))
 	e.POST("/v1/user/login", handler.login)
+	e.POST("/v1/user/token/refresh", handler.refreshToken)
+	e.POST("/v1/user/logout", handler.logout, handler.authenticate)
+	e.POST("/v1/user/otp/send", handler.sendOTP)
+	e.POST("/v1/user/otp/verify", handler.verifyOTP)
+	e.POST("/v1/user/password/reset/request", handler.requestPasswordReset)
+	e.POST("/v1/user/password/reset/confirm", handler.resetPassword)
 
 	// e.POST("/v1/delete/user", handler.deleteUser)
 	// e.POST("/v1/update/user", handler.updateUser)
-	e.GET("/v1/user/:userID", handler.getUserById)
+	e.GET("/v1/user/:userID", handler.getUserById, handler.authenticate)
 
 	// Product routes
-	e.POST("/v1/create/product", handler.createProduct)
+	e.POST("/v1/create/product", handler.createProduct, handler.authenticate, RequirePermission(domain.PermissionProductWrite))
 	// e.POST("/v1/delete/product", handler.deleteProduct)
 	// e.POST("/v1/update/product", handler.updateProduct)
 	e.GET("/v1/product/:productID", handler.getProductById)
 	e.GET("/v1/hotel/:hotelID/products", handler.getProductsByHotel)
+	e.POST("/v1/hotel/:hotelID/sections", handler.createMenuSection, handler.authenticate, RequirePermission(domain.PermissionProductWrite))
+	e.POST("/v1/hotel/:hotelID/sections/:sectionID", handler.updateMenuSection, handler.authenticate, RequirePermission(domain.PermissionProductWrite))
+	e.POST("/v1/hotel/:hotelID/sections/:sectionID/delete", handler.deleteMenuSection, handler.authenticate, RequirePermission(domain.PermissionProductWrite))
+	e.POST("/v1/product/:productID/availability", handler.setProductAvailability, handler.authenticate, RequirePermission(domain.PermissionProductWrite))
 
 	// Hotel routes
-	e.POST("/v1/create/hotel", handler.createHotel)
+	e.POST("/v1/create/hotel", handler.createHotel, handler.authenticate, RequirePermission(domain.PermissionHotelManage))
 	// e.POST("/v1/delete/hotel", handler.deleteHotel)
-	// e.POST("/v1/update/hotel", handler.updateHotel)
+	e.POST("/v1/update/hotel", handler.updateHotel, handler.authenticate, RequirePermission(domain.PermissionHotelManage))
 	e.GET("/v1/hotel", handler.getHotels)
+	e.GET("/v1/hotel/nearby", handler.getNearbyHotels)
+	e.POST("/v1/hotel/:hotelID/hours", handler.setOpeningHours, handler.authenticate, RequirePermission(domain.PermissionHotelOperate))
+	e.POST("/v1/hotel/:hotelID/closures", handler.addHotelClosure, handler.authenticate, RequirePermission(domain.PermissionHotelOperate))
+	e.POST("/v1/hotel/:hotelID/pause", handler.setHotelPaused, handler.authenticate, RequirePermission(domain.PermissionHotelOperate))
+
+	// Search routes
+	e.GET("/v1/search", handler.search)
 
 	// User Cart routes
-	e.POST("/v1/add/user/cart", handler.addProductToCart)
-	e.POST("/v1/delete/user/cart", handler.deleteProductFromCart)
-	e.POST("/v1/update/user/cart", handler.updateQuantityInCart)
-	e.GET("/v1/user/cart", handler.getUserCart)
+	e.POST("/v1/add/user/cart", handler.addProductToCart, handler.authenticate, RequirePermission(domain.PermissionCartManage), handler.idempotent)
+	e.POST("/v1/delete/user/cart", handler.deleteProductFromCart, handler.authenticate, RequirePermission(domain.PermissionCartManage), handler.idempotent)
+	e.POST("/v1/update/user/cart", handler.updateQuantityInCart, handler.authenticate, RequirePermission(domain.PermissionCartManage), handler.idempotent)
+	e.GET("/v1/user/cart", handler.getUserCart, handler.authenticate, RequirePermission(domain.PermissionCartManage))
+	e.POST("/v1/user/cart/checkout", handler.checkoutCart, handler.authenticate, RequirePermission(domain.PermissionOrderPlace), handler.idempotent)
 
 	// Order routes
-	e.POST("/v1/hotel/:hotelID/create/order", handler.CreateOrder)
+	e.POST("/v1/hotel/:hotelID/create/order", handler.CreateOrder, handler.authenticate, RequirePermission(domain.PermissionOrderPlace), handler.idempotent)
 	// e.POST("/v1/hotel/:hotelID/update/order", handler.updateOrder)
-	// e.GET("/v1/order/:orderID/completed", handler)
-	e.GET("/v1/user/:userID/orders", handler.getUserOrders)
+	e.POST("/v1/order/:orderID/status", handler.updateOrderStatus, handler.authenticate)
+	e.POST("/v1/order/:orderID/cancel", handler.cancelOrder, handler.authenticate, RequirePermission(domain.PermissionOrderPlace), handler.idempotent)
+	e.POST("/v1/order/:orderID/reject", handler.rejectOrder, handler.authenticate, RequirePermission(domain.PermissionOrderFulfil))
+	e.GET("/v1/order/:orderID/history", handler.getOrderStatusHistory, handler.authenticate)
+	e.GET("/v1/user/orders", handler.getUserOrders, handler.authenticate)
+	e.GET("/v1/user/:userID/orders", handler.getUserOrders, handler.authenticate)
 
 	// Payment routes
-	//e.GET("/v1/zeel/qrcode", handler.createUser) // Placeholder for now
-	//e.POST("/v1/upload/user/qrcode", handler.createUser)
+	e.POST("/v1/order/:orderID/pay", handler.capturePayment, handler.authenticate, RequirePermission(domain.PermissionOrderPlace), handler.idempotent)
+	e.GET("/v1/order/:orderID/payment", handler.getOrderPayment, handler.authenticate)
+	e.POST("/v1/order/:orderID/refund", handler.refundOrder, handler.authenticate, RequirePermission(domain.PermissionOrderRefund), handler.idempotent)
+	e.GET("/v1/order/:orderID/ledger", handler.reconcileOrderPayment, handler.authenticate, RequirePermission(domain.PermissionOrderRefund))
+	e.POST("/v1/order/:orderID/ledger/adjustments", handler.adjustLedger, handler.authenticate, RequirePermission(domain.PermissionOrderRefund), handler.idempotent)
+	// Called by the payment gateway, authenticated by the signature of the payload
+	e.POST("/v1/payments/webhook", handler.paymentWebhook)
 
 	// Health check route
 	e.GET("/", handler.healthCheck)
 }
 
-func RoleCheckMiddleware(role string) echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			user := c.Get("user").(*jwt.Token)
-			claims := user.Claims.(jwt.MapClaims)
-
-			if claims["role"] != role {
-				return c.JSON(http.StatusForbidden, map[string]string{
-					"error": "Insufficient permissions",
-				})
-			}
-			return next(c)
-		}
-	}
-}
-
 // Health check handler
 func (delivery *delivery) healthCheck(context echo.Context) error {
 	return context.JSON(http.StatusOK, "server is up and running")
@@ -82,20 +95,35 @@ func (delivery *delivery) healthCheck(context echo.Context) error {
 
 // User-related handlers
 func (delivery *delivery) createUser(context echo.Context) error {
-	var user domain.User
-	err := json.NewDecoder(context.Request().Body).Decode(&user)
+	var request domain.CreateUserRequest
+	err := json.NewDecoder(context.Request().Body).Decode(&request)
 	if err != nil {
 		return context.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	err = delivery.MCDUsecase.CreateUser(user)
+	err = delivery.MCDUsecase.CreateUser(request)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err.Error())
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusCreated, "User created successfully")
 }
 
+func (delivery *delivery) createCourier(context echo.Context) error {
+	var request domain.CreateUserRequest
+	err := json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	err = delivery.MCDUsecase.CreateCourier(request)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusCreated, "Courier created successfully")
+}
+
 func (delivery *delivery) login(context echo.Context) error {
 	var userData domain.UserLogin
 	err := json.NewDecoder(context.Request().Body).Decode(&userData)
@@ -104,11 +132,84 @@ func (delivery *delivery) login(context echo.Context) error {
 	}
 	res, err := delivery.MCDUsecase.UserLogin(userData)
 	if err != nil {
-		return err
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, res)
+}
+
+func (delivery *delivery) refreshToken(context echo.Context) error {
+	var request domain.RefreshTokenRequest
+	err := json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+	res, err := delivery.MCDUsecase.RefreshToken(request.RefreshToken)
+	if err != nil {
+		return respondError(context, err)
 	}
 	return context.JSON(http.StatusOK, res)
 }
 
+func (delivery *delivery) logout(context echo.Context) error {
+	err := delivery.MCDUsecase.Logout(authenticatedUserID(context))
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, "logged out of all sessions")
+}
+
+func (delivery *delivery) sendOTP(context echo.Context) error {
+	var request domain.SendOTPRequest
+	err := json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+	err = delivery.MCDUsecase.SendOTP(request)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, "otp sent")
+}
+
+func (delivery *delivery) verifyOTP(context echo.Context) error {
+	var request domain.VerifyOTPRequest
+	err := json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+	err = delivery.MCDUsecase.VerifyOTP(request)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, "otp verified")
+}
+
+func (delivery *delivery) requestPasswordReset(context echo.Context) error {
+	var request domain.PasswordResetRequest
+	err := json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+	err = delivery.MCDUsecase.RequestPasswordReset(request)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, "if the email is registered, a reset code has been sent")
+}
+
+func (delivery *delivery) resetPassword(context echo.Context) error {
+	var request domain.PasswordResetConfirm
+	err := json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+	err = delivery.MCDUsecase.ResetPassword(request)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, "password has been reset")
+}
+
 // func (delivery *delivery) deleteUser(context echo.Context) error {
 // 	userID := context.QueryParam("userID")
 // 	if userID == "" {
@@ -143,6 +244,10 @@ func (delivery *delivery) getUserById(context echo.Context) error {
 	if userID == "" {
 		return context.JSON(http.StatusBadRequest, "userID is required")
 	}
+	// Users may read their own profile, admins may read any profile
+	if userID != strconv.Itoa(authenticatedUserID(context)) && !authenticatedRole(context).HasPermission(domain.PermissionUserManage) {
+		return respondError(context, domain.Forbidden)
+	}
 
 	user, err := delivery.MCDUsecase.GetUserById(userID)
 	if err != nil {
@@ -160,9 +265,9 @@ func (delivery *delivery) createProduct(context echo.Context) error {
 		return context.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	err = delivery.MCDUsecase.CreateProduct(product)
+	err = delivery.MCDUsecase.CreateProduct(authenticatedActor(context), product)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err.Error())
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusCreated, "Product created successfully")
@@ -189,7 +294,7 @@ func (delivery *delivery) createProduct(context echo.Context) error {
 // 		return context.JSON(http.StatusBadRequest, err.Error())
 // 	}
 
-// 	err = delivery.MCDUsecase.UpdateProduct(product)
+// 	err = delivery.MCDUsecase.UpdateProduct(authenticatedActor(context), product)
 // 	if err != nil {
 // 		return context.JSON(http.StatusInternalServerError, err.Error())
 // 	}
@@ -211,18 +316,111 @@ func (delivery *delivery) getProductById(context echo.Context) error {
 	return context.JSON(http.StatusOK, product)
 }
 
+// getProductsByHotel lists a hotel's products a page at a time, filtered by ?category=,
+// ?min_price= and ?max_price= in minor units, sorted by ?sort=id, name or price and grouped
+// by menu section
 func (delivery *delivery) getProductsByHotel(context echo.Context) error {
-	hotelID := context.Param("hotelID")
-	if hotelID == "" {
-		return context.JSON(http.StatusBadRequest, "hotelID is required")
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	page, err := pageRequest(context)
+	if err != nil {
+		return respondError(context, err)
+	}
+	query := domain.ProductQuery{HotelID: hotelID, Category: context.QueryParam("category")}
+	if query.MinPrice, err = amountParam(context, "min_price"); err != nil {
+		return respondError(context, err)
+	}
+	if query.MaxPrice, err = amountParam(context, "max_price"); err != nil {
+		return respondError(context, err)
 	}
 
-	products, err := delivery.MCDUsecase.GetProductsByHotel(hotelID)
+	menu, err := delivery.MCDUsecase.GetMenu(query, page)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err.Error())
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusOK, menu)
+}
+
+// createMenuSection adds a section to the menu of a hotel
+func (delivery *delivery) createMenuSection(context echo.Context) error {
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	var section domain.MenuSection
+	if err := json.NewDecoder(context.Request().Body).Decode(&section); err != nil {
+		return respondError(context, domain.InvalidMenuSection)
+	}
+	section.HotelID = hotelID
+
+	created, err := delivery.MCDUsecase.CreateMenuSection(authenticatedActor(context), section)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusCreated, created)
+}
+
+// updateMenuSection renames, describes or moves a section of a hotel's menu
+func (delivery *delivery) updateMenuSection(context echo.Context) error {
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	sectionID, err := strconv.Atoi(context.Param("sectionID"))
+	if err != nil {
+		return respondError(context, domain.MenuSectionNotFound)
+	}
+	var section domain.MenuSection
+	if err := json.NewDecoder(context.Request().Body).Decode(&section); err != nil {
+		return respondError(context, domain.InvalidMenuSection)
+	}
+	section.ID, section.HotelID = sectionID, hotelID
+
+	updated, err := delivery.MCDUsecase.UpdateMenuSection(authenticatedActor(context), section)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, updated)
+}
+
+// deleteMenuSection removes a section from a hotel's menu, its products move to Other
+func (delivery *delivery) deleteMenuSection(context echo.Context) error {
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	sectionID, err := strconv.Atoi(context.Param("sectionID"))
+	if err != nil {
+		return respondError(context, domain.MenuSectionNotFound)
 	}
 
-	return context.JSON(http.StatusOK, products)
+	if err := delivery.MCDUsecase.DeleteMenuSection(authenticatedActor(context), hotelID, sectionID); err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, "Menu section deleted successfully")
+}
+
+// setProductAvailability takes a product off or back on sale
+func (delivery *delivery) setProductAvailability(context echo.Context) error {
+	productID, err := strconv.Atoi(context.Param("productID"))
+	if err != nil {
+		return respondError(context, domain.ProductNotFound)
+	}
+	var request domain.ProductAvailabilityRequest
+	if err := json.NewDecoder(context.Request().Body).Decode(&request); err != nil {
+		return respondError(context, domain.InvalidAvailability)
+	}
+
+	if err := delivery.MCDUsecase.SetProductAvailability(authenticatedActor(context), productID, request.Available); err != nil {
+		return respondError(context, err)
+	}
+	if request.Available {
+		return context.JSON(http.StatusOK, "Product is available")
+	}
+	return context.JSON(http.StatusOK, "Product is off sale")
 }
 
 // Hotel-related handlers
@@ -235,7 +433,7 @@ func (delivery *delivery) createHotel(context echo.Context) error {
 
 	err = delivery.MCDUsecase.CreateHotel(hotel)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err.Error())
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusCreated, "Hotel created successfully")
@@ -255,41 +453,146 @@ func (delivery *delivery) deleteHotel(context echo.Context) error {
 	return context.JSON(http.StatusOK, "Hotel deleted successfully")
 }
 
+// updateHotel updates the fields given in the body of the hotel with the body's id, a
+// delivery_radius_km of 0 makes it pickup only
 func (delivery *delivery) updateHotel(context echo.Context) error {
 	var hotel domain.Hotel
 	err := json.NewDecoder(context.Request().Body).Decode(&hotel)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err.Error())
+		return respondError(context, domain.InvalidHotel)
 	}
 
 	err = delivery.MCDUsecase.UpdateHotel(hotel)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err.Error())
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusOK, "Hotel updated successfully")
 }
 
+// getHotels lists hotels a page at a time, filtered by ?city= and ?state=, sorted by ?sort=id or name
 func (delivery *delivery) getHotels(context echo.Context) error {
-	hotels, err := delivery.MCDUsecase.GetHotels()
+	page, err := pageRequest(context)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err.Error())
+		return respondError(context, err)
+	}
+	query := domain.HotelQuery{City: context.QueryParam("city"), State: context.QueryParam("state")}
+
+	hotels, err := delivery.MCDUsecase.GetHotels(query, page)
+	if err != nil {
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusOK, hotels)
 }
 
+// getNearbyHotels lists hotels around ?lat=&lng=, within ?radius= km when given
+func (delivery *delivery) getNearbyHotels(context echo.Context) error {
+	latitude, latErr := strconv.ParseFloat(context.QueryParam("lat"), 64)
+	longitude, lngErr := strconv.ParseFloat(context.QueryParam("lng"), 64)
+	if latErr != nil || lngErr != nil {
+		return respondError(context, domain.InvalidLocation)
+	}
+	var radiusKm float64
+	if radius := context.QueryParam("radius"); radius != "" {
+		var err error
+		if radiusKm, err = strconv.ParseFloat(radius, 64); err != nil {
+			return respondError(context, domain.InvalidSearchRadius)
+		}
+	}
+
+	hotels, err := delivery.MCDUsecase.GetNearbyHotels(latitude, longitude, radiusKm)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, hotels)
+}
+
+// search finds hotels and dishes matching ?q=, tolerating typos, optionally only in ?city=
+// and returning at most ?limit= results
+func (delivery *delivery) search(context echo.Context) error {
+	query := domain.SearchQuery{Text: context.QueryParam("q"), City: context.QueryParam("city")}
+	if limit := context.QueryParam("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return respondError(context, domain.InvalidSearchQuery)
+		}
+	}
+
+	results, err := delivery.MCDUsecase.Search(query)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, results)
+}
+
+// setOpeningHours replaces the weekly opening hours of a hotel
+func (delivery *delivery) setOpeningHours(context echo.Context) error {
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	var schedule domain.OpeningSchedule
+	if err := json.NewDecoder(context.Request().Body).Decode(&schedule); err != nil {
+		return respondError(context, domain.InvalidOpeningHours)
+	}
+
+	if err := delivery.MCDUsecase.SetOpeningHours(authenticatedActor(context), hotelID, schedule); err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusOK, "Opening hours updated successfully")
+}
+
+// addHotelClosure closes a hotel for a one-off period
+func (delivery *delivery) addHotelClosure(context echo.Context) error {
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	var request domain.HotelClosureRequest
+	if err := json.NewDecoder(context.Request().Body).Decode(&request); err != nil {
+		return respondError(context, domain.InvalidClosure)
+	}
+
+	closure, err := delivery.MCDUsecase.AddHotelClosure(authenticatedActor(context), hotelID, request)
+	if err != nil {
+		return respondError(context, err)
+	}
+	return context.JSON(http.StatusCreated, closure)
+}
+
+// setHotelPaused pauses or resumes taking orders at a hotel
+func (delivery *delivery) setHotelPaused(context echo.Context) error {
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	var request domain.HotelPauseRequest
+	if err := json.NewDecoder(context.Request().Body).Decode(&request); err != nil {
+		return respondError(context, domain.InvalidHotelPause)
+	}
+
+	if err := delivery.MCDUsecase.SetHotelPaused(authenticatedActor(context), hotelID, request.Paused); err != nil {
+		return respondError(context, err)
+	}
+	if request.Paused {
+		return context.JSON(http.StatusOK, "Hotel paused, it is not accepting orders")
+	}
+	return context.JSON(http.StatusOK, "Hotel resumed accepting orders")
+}
+
 // User cart-related handlers
 func (delivery *delivery) addProductToCart(context echo.Context) error {
 	var cartProduct domain.CartProducts
 	err := json.NewDecoder(context.Request().Body).Decode(&cartProduct)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, domain.InvalidCartProduct)
 	}
+	cartProduct.UserID = authenticatedUserID(context)
 
 	err = delivery.MCDUsecase.AddProductToCart(cartProduct)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusOK, "product is added to cart")
@@ -299,44 +602,50 @@ func (delivery *delivery) deleteProductFromCart(context echo.Context) error {
 	var cartProduct domain.CartProducts
 	err := json.NewDecoder(context.Request().Body).Decode(&cartProduct)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, domain.InvalidCartProduct)
 	}
+	cartProduct.UserID = authenticatedUserID(context)
 
 	err = delivery.MCDUsecase.DeleteProductFromCart(cartProduct)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, err)
 	}
 
-	return context.JSON(http.StatusOK, "product is added to cart")
+	return context.JSON(http.StatusOK, "product is removed from cart")
 }
 
 func (delivery *delivery) updateQuantityInCart(context echo.Context) error {
 	var cartProduct domain.CartProducts
 	err := json.NewDecoder(context.Request().Body).Decode(&cartProduct)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, domain.InvalidCartProduct)
 	}
+	cartProduct.UserID = authenticatedUserID(context)
 
 	err = delivery.MCDUsecase.UpdateQuantityInCart(cartProduct)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusOK, "update successfull !")
 }
 
 func (delivery *delivery) getUserCart(context echo.Context) error {
-	userId := context.QueryParam("userID")
-	userIdInt, err := strconv.Atoi(userId)
+	cart, err := delivery.MCDUsecase.GetUserCart(authenticatedUserID(context))
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, err)
 	}
-	cart, err := delivery.MCDUsecase.GetUserCart(userIdInt)
+
+	return context.JSON(http.StatusOK, cart)
+}
+
+func (delivery *delivery) checkoutCart(context echo.Context) error {
+	order, err := delivery.MCDUsecase.CheckoutCart(authenticatedUserID(context))
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, err)
 	}
 
-	return context.JSON(http.StatusOK, cart)
+	return context.JSON(http.StatusCreated, order)
 }
 
 // Order-related handlers
@@ -346,28 +655,208 @@ func (delivery *delivery) CreateOrder(context echo.Context) error {
 	if err != nil {
 		return context.JSON(http.StatusBadRequest, err)
 	}
+	hotelID, err := strconv.Atoi(context.Param("hotelID"))
+	if err != nil {
+		return respondError(context, domain.HotelNotFound)
+	}
+	order.UserID = authenticatedUserID(context)
+	order.HotelID = hotelID
+
+	created, err := delivery.MCDUsecase.CreateOrder(order)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusCreated, created)
+}
+
+func (delivery *delivery) updateOrderStatus(context echo.Context) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+	var request domain.UpdateOrderStatusRequest
+	err = json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return respondError(context, domain.InvalidOrder)
+	}
+
+	order, err := delivery.MCDUsecase.UpdateOrderStatus(authenticatedActor(context), orderID, request)
+	if err != nil {
+		return respondError(context, err)
+	}
 
-	err = delivery.MCDUsecase.CreateOrder(order)
+	return context.JSON(http.StatusOK, order)
+}
+
+func (delivery *delivery) cancelOrder(context echo.Context) error {
+	return delivery.endOrder(context, delivery.MCDUsecase.CancelOrder)
+}
+
+func (delivery *delivery) rejectOrder(context echo.Context) error {
+	return delivery.endOrder(context, delivery.MCDUsecase.RejectOrder)
+}
+
+// endOrder decodes the reason for cancelling or rejecting an order and applies it with end
+func (delivery *delivery) endOrder(context echo.Context, end func(domain.AuthClaims, int, domain.CancelOrderRequest) (domain.Order, error)) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+	// The reason is optional for customers, so an empty body is accepted
+	var request domain.CancelOrderRequest
+	err = json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return respondError(context, domain.InvalidOrder)
+	}
+
+	order, err := end(authenticatedActor(context), orderID, request)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err)
+		return respondError(context, err)
 	}
 
-	return context.JSON(http.StatusCreated, "Order created successfully")
+	return context.JSON(http.StatusOK, order)
+}
+
+func (delivery *delivery) getOrderStatusHistory(context echo.Context) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+
+	history, err := delivery.MCDUsecase.GetOrderStatusHistory(authenticatedActor(context), orderID)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusOK, history)
+}
+
+func (delivery *delivery) capturePayment(context echo.Context) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+
+	order, err := delivery.MCDUsecase.CapturePayment(authenticatedActor(context), orderID)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusOK, order)
+}
+
+func (delivery *delivery) getOrderPayment(context echo.Context) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+
+	payment, err := delivery.MCDUsecase.GetOrderPayment(authenticatedActor(context), orderID)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusOK, payment)
+}
+
+func (delivery *delivery) refundOrder(context echo.Context) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+	// An empty body refunds everything that has not been refunded yet
+	var request domain.RefundRequest
+	err = json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return respondError(context, domain.InvalidOrder)
+	}
+
+	refund, err := delivery.MCDUsecase.RefundOrder(authenticatedActor(context), orderID, request)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusCreated, refund)
+}
+
+func (delivery *delivery) reconcileOrderPayment(context echo.Context) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+
+	reconciliation, err := delivery.MCDUsecase.ReconcileOrderPayment(authenticatedActor(context), orderID)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusOK, reconciliation)
+}
+
+func (delivery *delivery) adjustLedger(context echo.Context) error {
+	orderID, err := strconv.Atoi(context.Param("orderID"))
+	if err != nil {
+		return respondError(context, domain.InvalidOrderID)
+	}
+	var request domain.LedgerAdjustmentRequest
+	err = json.NewDecoder(context.Request().Body).Decode(&request)
+	if err != nil {
+		return respondError(context, domain.InvalidLedgerAdjustment)
+	}
+
+	reconciliation, err := delivery.MCDUsecase.AdjustLedger(authenticatedActor(context), orderID, request)
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	return context.JSON(http.StatusCreated, reconciliation)
+}
+
+// maxWebhookSize bounds the payload read from the gateway
+const maxWebhookSize = 64 << 10
+
+func (delivery *delivery) paymentWebhook(context echo.Context) error {
+	payload, err := io.ReadAll(io.LimitReader(context.Request().Body, maxWebhookSize))
+	if err != nil {
+		return respondError(context, domain.InvalidWebhookPayload)
+	}
+
+	duplicate, err := delivery.MCDUsecase.HandlePaymentWebhook(payload, context.Request().Header.Get(domain.PaymentSignatureHeader))
+	if err != nil {
+		return respondError(context, err)
+	}
+
+	// Duplicates are acknowledged too, so the gateway stops redelivering them
+	if duplicate {
+		return context.JSON(http.StatusOK, domain.CustomResponse{Status: "duplicate", Description: "event was already processed"})
+	}
+	return context.JSON(http.StatusOK, domain.CustomResponse{Status: "processed", Description: "event was processed"})
 }
 
 func (delivery *delivery) getUserOrders(context echo.Context) error {
-	userID := context.Param("userID")
-	if userID == "" {
-		return context.JSON(http.StatusBadRequest, "userID is required")
+	userID := authenticatedUserID(context)
+	// The legacy /v1/user/:userID/orders route may only be used for the caller's own orders
+	if pathUserID := context.Param("userID"); pathUserID != "" && pathUserID != strconv.Itoa(userID) {
+		return respondError(context, domain.Forbidden)
 	}
-	userIdInt, err := strconv.Atoi(userID)
+
+	page, err := pageRequest(context)
 	if err != nil {
-		return context.JSON(http.StatusBadRequest, err)
+		return respondError(context, err)
+	}
+	// ?status= filters by order status, ?from= and ?to= by creation time, to being exclusive
+	query := domain.OrderQuery{UserID: userID, Status: domain.OrderStatus(context.QueryParam("status"))}
+	if query.CreatedFrom, err = timeParam(context, "from"); err != nil {
+		return respondError(context, err)
+	}
+	if query.CreatedBefore, err = timeParam(context, "to"); err != nil {
+		return respondError(context, err)
 	}
 
-	orders, err := delivery.MCDUsecase.GetUserOrders(userIdInt)
+	orders, err := delivery.MCDUsecase.GetUserOrders(query, page)
 	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err.Error())
+		return respondError(context, err)
 	}
 
 	return context.JSON(http.StatusOK, orders)
@@ -0,0 +1,2002 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcd/domain"
+	mcddelivery "mcd/mcd/delivery/http"
+	"mcd/mcd/payment"
+	memoryrepository "mcd/mcd/repository/memory"
+	"mcd/mcd/search"
+	mcdusecase "mcd/mcd/usecase"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "passw0rd1"
+
+var (
+	testWebhookSecret         = []byte("fedcba9876543210fedcba9876543210")
+	testPreviousWebhookSecret = []byte("0f1e2d3c4b5a69780f1e2d3c4b5a6978") // Still accepted while the secret is rotated
+)
+
+// recordingNotifier keeps every notification so tests can read OTP and reset codes
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []domain.Notification
+}
+
+func (n *recordingNotifier) Send(notification domain.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func (n *recordingNotifier) last(t *testing.T) domain.Notification {
+	t.Helper()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.sent) == 0 {
+		t.Fatal("no notification was sent")
+	}
+	return n.sent[len(n.sent)-1]
+}
+
+type testServer struct {
+	t          *testing.T
+	server     *httptest.Server
+	repository domain.MCDRepository
+	usecase    domain.MCDUsecase
+	notifier   *recordingNotifier
+	gateway    *payment.FakeGateway
+}
+
+// newTestServer starts NewMCDHandler on an httptest server backed by the in-memory repository.
+// configure can change the usecase options before the usecase is built.
+func newTestServer(t *testing.T, configure ...func(*mcdusecase.Options)) *testServer {
+	t.Helper()
+	return newTestServerWith(t, memoryrepository.NewRepository(), configure...)
+}
+
+// newTestServerWith is newTestServer over the given repository, for tests that make it fail
+func newTestServerWith(t *testing.T, repository domain.MCDRepository, configure ...func(*mcdusecase.Options)) *testServer {
+	t.Helper()
+	notifier := &recordingNotifier{}
+	gateway := payment.NewFakeGateway([][]byte{testWebhookSecret, testPreviousWebhookSecret}, domain.DefaultWebhookTolerance)
+	options := mcdusecase.Options{
+		Notifier:          notifier,
+		SigningKeyID:      "test",
+		SigningKeys:       map[string][]byte{"test": []byte("0123456789abcdef0123456789abcdef")},
+		AccessTokenTTL:    time.Hour,
+		RefreshTokenTTL:   time.Hour,
+		IdempotencyKeyTTL: time.Hour,
+		IdempotencyLease:  time.Minute,
+		PaymentGateway:    gateway,
+		Currency:          "USD",
+		SearchIndex:       search.NewLocalIndex(),
+	}
+	for _, option := range configure {
+		option(&options)
+	}
+	usecase := mcdusecase.NewUseCase(repository, options)
+
+	e := echo.New()
+	mcddelivery.NewMCDHandler(e, usecase)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	return &testServer{t: t, server: server, repository: repository, usecase: usecase, notifier: notifier, gateway: gateway}
+}
+
+// do sends a JSON request and returns the status code and raw body
+func (s *testServer) do(method, path, token string, body any) (int, []byte) {
+	s.t.Helper()
+	res, raw := s.send(method, path, token, nil, body)
+	return res.StatusCode, raw
+}
+
+// send sends a JSON request with the extra headers and returns the response with its body read
+func (s *testServer) send(method, path, token string, header http.Header, body any) (*http.Response, []byte) {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, s.server.URL+path, reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return res, raw
+}
+
+// expect sends a request, asserts the status code and decodes the body into out when given
+func (s *testServer) expect(status int, method, path, token string, body any, out any) {
+	s.t.Helper()
+	got, raw := s.do(method, path, token, body)
+	if got != status {
+		s.t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, status, got, raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			s.t.Fatalf("%s %s: cannot decode %s: %v", method, path, raw, err)
+		}
+	}
+}
+
+// expectError asserts the status code and the errorCode of a domain.ResponseError body
+func (s *testServer) expectError(expected domain.ResponseError, method, path, token string, body any) {
+	s.t.Helper()
+	var res domain.ResponseError
+	s.expect(expected.Status, method, path, token, body, &res)
+	if res.ErrorCode != expected.ErrorCode {
+		s.t.Fatalf("%s %s: expected error %s, got %s", method, path, expected.ErrorCode, res.ErrorCode)
+	}
+}
+
+// signup registers a user through the API and returns their access token
+func (s *testServer) signup(email, phoneNumber string, role domain.Role) string {
+	s.t.Helper()
+	var message string
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/user", "", domain.CreateUserRequest{
+		Email:       email,
+		PhoneNumber: phoneNumber,
+		Password:    testPassword,
+		Name:        "Test User",
+		Role:        role,
+	}, &message)
+	return s.login(email, testPassword).Token
+}
+
+// courier provisions a courier through the admin route and returns their access token
+func (s *testServer) courier(admin, email, phoneNumber string) string {
+	s.t.Helper()
+	var message string
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/courier", admin, domain.CreateUserRequest{
+		Email:       email,
+		PhoneNumber: phoneNumber,
+		Password:    testPassword,
+		Name:        "Test Courier",
+	}, &message)
+	return s.login(email, testPassword).Token
+}
+
+func (s *testServer) login(email, password string) domain.LoginResponse {
+	s.t.Helper()
+	var res domain.LoginResponse
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/login", "", domain.UserLogin{Email: email, Password: password}, &res)
+	return res
+}
+
+// adminToken creates an admin directly in the repository, admins cannot self-register
+func (s *testServer) adminToken() string {
+	s.t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	err = s.repository.CreateUser(domain.User{
+		Email:        "admin@quickbyte.com",
+		PhoneNumber:  "+10000000000",
+		PasswordHash: string(hash),
+		Name:         "Admin",
+		Role:         domain.RoleAdmin,
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return s.login("admin@quickbyte.com", testPassword).Token
+}
+
+func userIDOf(s *testServer, email string) int {
+	s.t.Helper()
+	user, err := s.repository.GetUserByEmail(email)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return int(user.ID)
+}
+
+// usd returns an amount of cents
+func usd(cents int64) domain.Money {
+	return domain.Money{Amount: cents, Currency: "USD"}
+}
+
+// menuItems lists the products of a menu page section by section
+func menuItems(menu domain.Menu) []domain.Product {
+	var products []domain.Product
+	for _, section := range menu.Sections {
+		products = append(products, section.Products...)
+	}
+	return products
+}
+
+// storeFixture sets up an admin, a restaurant owner with one hotel and two products, and a customer
+type storeFixture struct {
+	admin, owner, customer string
+	customerID             int
+	hotelID                int
+	burgerID, friesID      int
+}
+
+func newStoreFixture(s *testServer) storeFixture {
+	s.t.Helper()
+	var f storeFixture
+	f.admin = s.adminToken()
+	f.owner = s.signup("owner@quickbyte.com", "+11111111111", domain.RoleRestaurantOwner)
+	f.customer = s.signup("customer@quickbyte.com", "+12222222222", domain.RoleCustomer)
+	f.customerID = userIDOf(s, "customer@quickbyte.com")
+	ownerID := userIDOf(s, "owner@quickbyte.com")
+
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{
+		Name: "Burger Barn", City: "Austin", Address: "1 Main St", State: "TX", OwnerID: &ownerID,
+	}, nil)
+	var hotels domain.Page[domain.Hotel]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel", "", nil, &hotels)
+	if len(hotels.Items) != 1 {
+		s.t.Fatalf("expected 1 hotel, got %d", len(hotels.Items))
+	}
+	f.hotelID = int(hotels.Items[0].ID)
+
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Burger", StockLeft: 10, HotelID: f.hotelID, Category: "mains", Price: domain.Money{Amount: 800},
+	}, nil)
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Fries", StockLeft: 20, HotelID: f.hotelID, Category: "sides", Price: usd(300),
+	}, nil)
+	var menu domain.Menu
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/products", "", nil, &menu)
+	products := menuItems(menu)
+	if len(products) != 2 {
+		s.t.Fatalf("expected 2 products, got %d", len(products))
+	}
+	f.burgerID, f.friesID = int(products[0].ID), int(products[1].ID)
+	return f
+}
+
+func TestHealthCheck(t *testing.T) {
+	s := newTestServer(t)
+	var message string
+	s.expect(http.StatusOK, http.MethodGet, "/", "", nil, &message)
+	if message != "server is up and running" {
+		t.Fatalf("unexpected health message %q", message)
+	}
+}
+
+func TestSignupAndLogin(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signup("jane@quickbyte.com", "+15555555555", "")
+	userID := userIDOf(s, "jane@quickbyte.com")
+
+	var user map[string]any
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/"+strconv.Itoa(userID), token, nil, &user)
+	if user["email"] != "jane@quickbyte.com" || user["role"] != string(domain.RoleCustomer) {
+		t.Fatalf("unexpected user %v", user)
+	}
+	if _, ok := user["password_hash"]; ok {
+		t.Fatal("user response must not expose the password hash")
+	}
+
+	s.expectError(domain.InvalidCredentials, http.MethodPost, "/v1/user/login", "", domain.UserLogin{Email: "jane@quickbyte.com", Password: "wrong"})
+	s.expectError(domain.EmailAlreadyExists, http.MethodPost, "/v1/create/user", "", domain.CreateUserRequest{
+		Email: "jane@quickbyte.com", PhoneNumber: "+15555555556", Password: testPassword, Name: "Jane",
+	})
+	s.expectError(domain.PhoneAlreadyExists, http.MethodPost, "/v1/create/user", "", domain.CreateUserRequest{
+		Email: "jane2@quickbyte.com", PhoneNumber: "+15555555555", Password: testPassword, Name: "Jane",
+	})
+	s.expectError(domain.InvalidRole, http.MethodPost, "/v1/create/user", "", domain.CreateUserRequest{
+		Email: "root@quickbyte.com", PhoneNumber: "+15555555557", Password: testPassword, Name: "Root", Role: domain.RoleAdmin,
+	})
+}
+
+func TestSignupValidation(t *testing.T) {
+	s := newTestServer(t)
+	valid := domain.CreateUserRequest{Email: "ok@quickbyte.com", PhoneNumber: "+15555555555", Password: testPassword, Name: "Ok"}
+
+	cases := []struct {
+		name     string
+		mutate   func(*domain.CreateUserRequest)
+		expected domain.ResponseError
+	}{
+		{"bad email", func(r *domain.CreateUserRequest) { r.Email = "not-an-email" }, domain.InvalidEmail},
+		{"short phone", func(r *domain.CreateUserRequest) { r.PhoneNumber = "12345" }, domain.InvalidPhoneNumber},
+		{"long phone", func(r *domain.CreateUserRequest) { r.PhoneNumber = "+12345678901234" }, domain.InvalidPhoneNumber},
+		{"letters in phone", func(r *domain.CreateUserRequest) { r.PhoneNumber = "+1555555555a" }, domain.InvalidPhoneNumber},
+		{"missing name", func(r *domain.CreateUserRequest) { r.Name = " " }, domain.InvalidName},
+		{"short password", func(r *domain.CreateUserRequest) { r.Password = "a1" }, domain.WeakPassword},
+		{"password without digit", func(r *domain.CreateUserRequest) { r.Password = "password" }, domain.WeakPassword},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			request := valid
+			c.mutate(&request)
+			s.expectError(c.expected, http.MethodPost, "/v1/create/user", "", request)
+		})
+	}
+}
+
+func TestAuthenticationIsRequired(t *testing.T) {
+	s := newTestServer(t)
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/v1/user/1"},
+		{http.MethodPost, "/v1/user/logout"},
+		{http.MethodPost, "/v1/create/product"},
+		{http.MethodPost, "/v1/create/hotel"},
+		{http.MethodPost, "/v1/add/user/cart"},
+		{http.MethodPost, "/v1/delete/user/cart"},
+		{http.MethodPost, "/v1/update/user/cart"},
+		{http.MethodGet, "/v1/user/cart"},
+		{http.MethodPost, "/v1/user/cart/checkout"},
+		{http.MethodPost, "/v1/hotel/1/create/order"},
+		{http.MethodGet, "/v1/user/orders"},
+		{http.MethodGet, "/v1/user/1/orders"},
+		{http.MethodPost, "/v1/order/1/status"},
+		{http.MethodGet, "/v1/order/1/history"},
+		{http.MethodPost, "/v1/order/1/cancel"},
+		{http.MethodPost, "/v1/order/1/reject"},
+	}
+	for _, route := range routes {
+		s.expectError(domain.MissingToken, route.method, route.path, "", nil)
+		s.expectError(domain.InvalidToken, route.method, route.path, "not-a-token", nil)
+	}
+}
+
+func TestHotelsAndProducts(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+
+	// Only admins may create hotels, only the hotel's owner may add products to it
+	s.expectError(domain.Forbidden, http.MethodPost, "/v1/create/hotel", f.owner, domain.Hotel{Name: "Mine"})
+	s.expectError(domain.Forbidden, http.MethodPost, "/v1/create/product", f.customer, domain.Product{Name: "Pie", HotelID: f.hotelID})
+	other := s.signup("other@quickbyte.com", "+13333333333", domain.RoleRestaurantOwner)
+	s.expectError(domain.NotHotelOwner, http.MethodPost, "/v1/create/product", other, domain.Product{Name: "Pie", HotelID: f.hotelID})
+	s.expectError(domain.HotelNotFound, http.MethodPost, "/v1/create/product", f.admin, domain.Product{Name: "Pie", HotelID: 999})
+	s.expectError(domain.InvalidHotelOwner, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{Name: "Cafe", OwnerID: &f.customerID})
+
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.Name != "Burger" || product.Price != usd(800) || product.HotelID != f.hotelID {
+		t.Fatalf("unexpected product %+v", product)
+	}
+}
+
+func TestNearbyHotels(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	createHotel := func(name string, latitude, longitude, deliveryRadiusKm float64) {
+		s.expect(http.StatusCreated, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{
+			Name: name, Latitude: &latitude, Longitude: &longitude, DeliveryRadiusKm: &deliveryRadiusKm,
+		}, nil)
+	}
+	createHotel("Brooklyn Bagels", 40.6782, -73.9442, 2)
+	createHotel("City Hall Deli", 40.7128, -74.0060, 5)
+	createHotel("Pittsburgh Pierogi", 40.4406, -79.9959, 20)
+
+	// Nearest first, hotels without a location or out of the radius are left out
+	var hotels []domain.NearbyHotel
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel/nearby?lat=40.7130&lng=-74.0070", "", nil, &hotels)
+	if len(hotels) != 2 || hotels[0].Name != "City Hall Deli" || hotels[1].Name != "Brooklyn Bagels" {
+		t.Fatalf("unexpected nearby hotels %+v", hotels)
+	}
+	if hotels[0].PickupOnly || hotels[0].DistanceKm > 0.1 {
+		t.Fatalf("expected the deli to deliver next door, got %+v", hotels[0])
+	}
+	if !hotels[1].PickupOnly || hotels[1].DistanceKm < 5 || hotels[1].DistanceKm > 7 {
+		t.Fatalf("expected the bagels about 6 km away as pickup only, got %+v", hotels[1])
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel/nearby?lat=40.7130&lng=-74.0070&radius=1", "", nil, &hotels)
+	if len(hotels) != 1 {
+		t.Fatalf("expected 1 hotel within 1 km, got %+v", hotels)
+	}
+
+	// A delivery radius updated to 0 makes the hotel pickup only, updates leaving it out keep it
+	deli := domain.Hotel{ID: hotels[0].ID, Name: "City Hall Deli & Grill"}
+	s.expect(http.StatusOK, http.MethodPost, "/v1/update/hotel", f.admin, deli, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel/nearby?lat=40.7130&lng=-74.0070&radius=1", "", nil, &hotels)
+	if hotels[0].Name != "City Hall Deli & Grill" || hotels[0].PickupOnly {
+		t.Fatalf("expected the renamed deli to still deliver, got %+v", hotels[0])
+	}
+	pickupOnly := 0.0
+	deli.DeliveryRadiusKm = &pickupOnly
+	s.expect(http.StatusOK, http.MethodPost, "/v1/update/hotel", f.admin, deli, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel/nearby?lat=40.7130&lng=-74.0070&radius=1", "", nil, &hotels)
+	if !hotels[0].PickupOnly || hotels[0].DeliveryRadiusKm == nil || *hotels[0].DeliveryRadiusKm != 0 {
+		t.Fatalf("expected the deli to be pickup only, got %+v", hotels[0])
+	}
+	s.expectError(domain.Forbidden, http.MethodPost, "/v1/update/hotel", f.owner, deli)
+	s.expectError(domain.HotelNotFound, http.MethodPost, "/v1/update/hotel", f.admin, domain.Hotel{ID: 999, Name: "Nowhere"})
+	s.expectError(domain.InvalidHotel, http.MethodPost, "/v1/update/hotel", f.admin, json.RawMessage(`{"name":7}`))
+
+	// Only the nearest hotels are returned
+	for i := 0; i < domain.MaxNearbyHotels; i++ {
+		createHotel("Food Court "+strconv.Itoa(i), 40.7130, -74.0100, 1)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel/nearby?lat=40.7130&lng=-74.0070", "", nil, &hotels)
+	if len(hotels) != domain.MaxNearbyHotels || hotels[0].Name != "City Hall Deli & Grill" || hotels[len(hotels)-1].Name == "Brooklyn Bagels" {
+		t.Fatalf("expected the %d nearest hotels, got %d starting with %+v", domain.MaxNearbyHotels, len(hotels), hotels[0])
+	}
+
+	s.expectError(domain.InvalidLocation, http.MethodGet, "/v1/hotel/nearby?lat=91&lng=0", "", nil)
+	s.expectError(domain.InvalidLocation, http.MethodGet, "/v1/hotel/nearby?lng=0", "", nil)
+	s.expectError(domain.InvalidSearchRadius, http.MethodGet, "/v1/hotel/nearby?lat=0&lng=0&radius=500", "", nil)
+	latitude := 40.0
+	s.expectError(domain.InvalidLocation, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{Name: "Half", Latitude: &latitude})
+}
+
+func TestOpeningHours(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	hotelPath := "/v1/hotel/" + strconv.Itoa(f.hotelID)
+	order := domain.CreateOrderRequest{Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 1}}}
+	hotel := func() domain.Hotel {
+		var hotels domain.Page[domain.Hotel]
+		s.expect(http.StatusOK, http.MethodGet, "/v1/hotel", "", nil, &hotels)
+		return hotels.Items[0]
+	}
+
+	// Without opening hours a hotel is always open
+	if h := hotel(); !h.IsOpen || h.TimeZone != "UTC" || h.NextOpensAt != nil {
+		t.Fatalf("expected an open hotel, got %+v", h)
+	}
+	s.expectError(domain.Forbidden, http.MethodPost, hotelPath+"/hours", f.customer, domain.OpeningSchedule{})
+	s.expectError(domain.InvalidTimeZone, http.MethodPost, hotelPath+"/hours", f.owner, domain.OpeningSchedule{TimeZone: "Mars/Olympus"})
+	s.expectError(domain.InvalidOpeningHours, http.MethodPost, hotelPath+"/hours", f.owner, domain.OpeningSchedule{
+		Hours: []domain.OpeningHours{{Weekday: 7, Opens: "09:00", Closes: "17:00"}},
+	})
+	s.expectError(domain.InvalidOpeningHours, http.MethodPost, hotelPath+"/hours", f.owner, domain.OpeningSchedule{
+		Hours: []domain.OpeningHours{{Weekday: 1, Opens: "9am", Closes: "17:00"}},
+	})
+
+	// Open one day a week, three days from today in Tokyo
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	openDay := time.Now().In(tokyo).AddDate(0, 0, 3)
+	s.expect(http.StatusOK, http.MethodPost, hotelPath+"/hours", f.owner, domain.OpeningSchedule{
+		TimeZone: "Asia/Tokyo",
+		Hours:    []domain.OpeningHours{{Weekday: int(openDay.Weekday()), Opens: "09:00", Closes: "17:00"}},
+	}, nil)
+	opensAt := time.Date(openDay.Year(), openDay.Month(), openDay.Day(), 9, 0, 0, 0, tokyo)
+	if h := hotel(); h.IsOpen || h.NextOpensAt == nil || !h.NextOpensAt.Equal(opensAt) {
+		t.Fatalf("expected the hotel to open at %s, got %+v", opensAt, h)
+	}
+	s.expectError(domain.HotelClosed, http.MethodPost, hotelPath+"/create/order", f.customer, order)
+	s.expect(http.StatusOK, http.MethodPost, "/v1/add/user/cart", f.customer, domain.CartProducts{ProductID: f.burgerID, Quantity: 1}, nil)
+	s.expectError(domain.HotelClosed, http.MethodPost, "/v1/user/cart/checkout", f.customer, nil)
+
+	// Opening around the clock, a period closing at its opening time runs past midnight
+	everyDay := make([]domain.OpeningHours, 7)
+	for weekday := range everyDay {
+		everyDay[weekday] = domain.OpeningHours{Weekday: weekday, Opens: "00:00", Closes: "00:00"}
+	}
+	s.expect(http.StatusOK, http.MethodPost, hotelPath+"/hours", f.owner, domain.OpeningSchedule{TimeZone: "Asia/Tokyo", Hours: everyDay}, nil)
+	s.expect(http.StatusCreated, http.MethodPost, hotelPath+"/create/order", f.customer, order, nil)
+
+	// A paused hotel has no known next opening
+	s.expect(http.StatusOK, http.MethodPost, hotelPath+"/pause", f.owner, domain.HotelPauseRequest{Paused: true}, nil)
+	if h := hotel(); h.IsOpen || !h.Paused || h.NextOpensAt != nil {
+		t.Fatalf("expected a paused hotel, got %+v", h)
+	}
+	s.expectError(domain.HotelClosed, http.MethodPost, hotelPath+"/create/order", f.customer, order)
+	s.expect(http.StatusOK, http.MethodPost, hotelPath+"/pause", f.owner, domain.HotelPauseRequest{Paused: false}, nil)
+	s.expectError(domain.InvalidHotelPause, http.MethodPost, hotelPath+"/pause", f.owner, json.RawMessage(`{"paused":"no"}`))
+
+	// A holiday closure overrides the opening hours until it ends
+	now := time.Now().Truncate(time.Second)
+	s.expectError(domain.InvalidClosure, http.MethodPost, hotelPath+"/closures", f.owner, domain.HotelClosureRequest{StartsAt: now, EndsAt: now.Add(-time.Hour)})
+	var closure domain.HotelClosure
+	s.expect(http.StatusCreated, http.MethodPost, hotelPath+"/closures", f.owner, domain.HotelClosureRequest{
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(2 * time.Hour), Reason: "holiday",
+	}, &closure)
+	if h := hotel(); h.IsOpen || h.NextOpensAt == nil || !h.NextOpensAt.Equal(closure.EndsAt) {
+		t.Fatalf("expected the hotel to open when the closure ends at %s, got %+v", closure.EndsAt, h)
+	}
+	s.expectError(domain.HotelClosed, http.MethodPost, hotelPath+"/create/order", f.customer, order)
+
+	// Listed together, each hotel keeps its own hours and closures
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{Name: "Night Diner", City: "Austin"}, nil)
+	var hotels domain.Page[domain.Hotel]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel", "", nil, &hotels)
+	if len(hotels.Items) != 2 || hotels.Items[0].IsOpen || !hotels.Items[1].IsOpen {
+		t.Fatalf("expected only the new hotel to be open, got %+v", hotels.Items)
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	for _, product := range []domain.Product{
+		{Name: "Shake", StockLeft: 5, HotelID: f.hotelID, Category: "drinks", Price: usd(500)},
+		{Name: "Salad", StockLeft: 5, HotelID: f.hotelID, Category: "Mains", Price: usd(650)},
+	} {
+		s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, product, nil)
+	}
+	productsPath := "/v1/hotel/" + strconv.Itoa(f.hotelID) + "/products"
+	names := func(products []domain.Product) []string {
+		var names []string
+		for _, product := range products {
+			names = append(names, product.Name)
+		}
+		return names
+	}
+	// Pages are decoded fresh, an omitted next_cursor must not keep the previous one
+	products := func(path string) domain.Page[domain.Product] {
+		var menu domain.Menu
+		s.expect(http.StatusOK, http.MethodGet, path, "", nil, &menu)
+		return domain.Page[domain.Product]{Items: menuItems(menu), NextCursor: menu.NextCursor}
+	}
+
+	// Walking the pages by price visits every product once, cheapest first
+	var seen []string
+	path := productsPath + "?limit=3&sort=price"
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("pagination did not end")
+		}
+		page := products(path)
+		seen = append(seen, names(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		path = productsPath + "?limit=3&sort=price&cursor=" + page.NextCursor
+	}
+	if got := strings.Join(seen, ","); got != "Fries,Shake,Salad,Burger" {
+		t.Fatalf("unexpected price order %s", got)
+	}
+
+	page := products(productsPath + "?sort=-price&limit=1")
+	if len(page.Items) != 1 || page.Items[0].Name != "Burger" || page.NextCursor == "" {
+		t.Fatalf("expected the burger first by descending price, got %+v", page)
+	}
+	s.expectError(domain.InvalidCursor, http.MethodGet, productsPath+"?sort=name&cursor="+page.NextCursor, "", nil)
+	s.expectError(domain.InvalidCursor, http.MethodGet, productsPath+"?cursor=not-a-cursor", "", nil)
+	s.expectError(domain.InvalidSort, http.MethodGet, productsPath+"?sort=stock_left", "", nil)
+	s.expectError(domain.InvalidListQuery, http.MethodGet, productsPath+"?limit=many", "", nil)
+	s.expectError(domain.InvalidListQuery, http.MethodGet, productsPath+"?min_price=900&max_price=100", "", nil)
+
+	// Category matches regardless of case, the price range is inclusive and in minor units
+	page = products(productsPath + "?category=mains&sort=name")
+	if got := strings.Join(names(page.Items), ","); got != "Burger,Salad" || page.NextCursor != "" {
+		t.Fatalf("unexpected mains %s", got)
+	}
+	page = products(productsPath + "?min_price=500&max_price=650")
+	if got := strings.Join(names(page.Items), ","); got != "Shake,Salad" {
+		t.Fatalf("unexpected price range %s", got)
+	}
+
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{
+		Name: "Taco Town", City: "Dallas", Address: "2 Elm St", State: "TX",
+	}, nil)
+	var hotels domain.Page[domain.Hotel]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel?city=dallas", "", nil, &hotels)
+	if len(hotels.Items) != 1 || hotels.Items[0].Name != "Taco Town" {
+		t.Fatalf("expected only the Dallas hotel, got %+v", hotels.Items)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/hotel?state=TX&sort=-name", "", nil, &hotels)
+	if len(hotels.Items) != 2 || hotels.Items[0].Name != "Taco Town" {
+		t.Fatalf("expected both Texas hotels by descending name, got %+v", hotels.Items)
+	}
+
+	for i := 0; i < 3; i++ {
+		s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+			Products: []domain.OrderProductRequest{{ProductID: f.friesID, Quantity: 1}},
+		}, nil)
+	}
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders?limit=2&sort=-created_at", f.customer, nil, &orders)
+	if len(orders.Items) != 2 || orders.NextCursor == "" {
+		t.Fatalf("expected a first page of 2 orders, got %+v", orders)
+	}
+	var lastPage domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders?limit=2&sort=-created_at&cursor="+orders.NextCursor, f.customer, nil, &lastPage)
+	if len(lastPage.Items) != 1 || lastPage.NextCursor != "" || lastPage.Items[0].ID == orders.Items[1].ID {
+		t.Fatalf("expected the last order on the second page, got %+v", lastPage)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders?status=cancelled", f.customer, nil, &orders)
+	if len(orders.Items) != 0 {
+		t.Fatalf("expected no cancelled orders, got %+v", orders.Items)
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders?to="+today, f.customer, nil, &orders)
+	if len(orders.Items) != 0 {
+		t.Fatalf("expected no orders before today, got %+v", orders.Items)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders?from="+today, f.customer, nil, &orders)
+	if len(orders.Items) != 3 {
+		t.Fatalf("expected today's 3 orders, got %d", len(orders.Items))
+	}
+	s.expectError(domain.InvalidOrderStatus, http.MethodGet, "/v1/user/orders?status=lost", f.customer, nil)
+	s.expectError(domain.InvalidListQuery, http.MethodGet, "/v1/user/orders?from=yesterday", f.customer, nil)
+}
+
+// countingProductDetails is an in-memory repository counting the product detail reads
+type countingProductDetails struct {
+	domain.MCDRepository
+	reads int
+}
+
+func (r *countingProductDetails) GetProductDetails(productIDs []int) ([]domain.Product, error) {
+	r.reads++
+	return r.MCDRepository.GetProductDetails(productIDs)
+}
+
+func TestUserOrdersReadProductsOnce(t *testing.T) {
+	repository := &countingProductDetails{MCDRepository: memoryrepository.NewRepository()}
+	s := newTestServerWith(t, repository)
+	f := newStoreFixture(s)
+	for _, productID := range []int{f.burgerID, f.friesID} {
+		s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+			Products: []domain.OrderProductRequest{{ProductID: productID, Quantity: 1}},
+		}, nil)
+	}
+
+	repository.reads = 0
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders", f.customer, nil, &orders)
+	if repository.reads != 1 {
+		t.Fatalf("expected the products of the page read once, got %d reads", repository.reads)
+	}
+	if len(orders.Items) != 2 || len(orders.Items[0].Products) != 1 || orders.Items[0].Products[0].Name != "Burger" ||
+		len(orders.Items[1].Products) != 1 || orders.Items[1].Products[0].Name != "Fries" || orders.Items[1].Products[0].HotelName != "Burger Barn" {
+		t.Fatalf("expected each order with its own product, got %+v", orders.Items)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{
+		Name: "Taco Town", City: "Dallas", Address: "2 Elm St", State: "TX",
+	}, nil)
+	tacoTownID := f.hotelID + 1
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.admin, domain.Product{
+		Name: "Chicken Burrito", StockLeft: 5, HotelID: tacoTownID, Category: "mains", Price: usd(900),
+	}, nil)
+	search := func(query string) []domain.SearchResult {
+		var results []domain.SearchResult
+		s.expect(http.StatusOK, http.MethodGet, "/v1/search?"+query, "", nil, &results)
+		return results
+	}
+	names := func(results []domain.SearchResult) string {
+		var names []string
+		for _, result := range results {
+			if result.Product != nil {
+				names = append(names, result.Product.Name+"@"+result.Hotel.Name)
+			} else {
+				names = append(names, result.Hotel.Name)
+			}
+		}
+		return strings.Join(names, ",")
+	}
+
+	// Restaurant and dish names both match, a typo or a word prefix is tolerated at a lower score
+	for query, expected := range map[string]string{
+		"q=burger":               "Burger Barn,Burger@Burger Barn",
+		"q=buger":                "Burger Barn,Burger@Burger Barn",
+		"q=BURR":                 "Chicken Burrito@Taco Town",
+		"q=sides":                "Fries@Burger Barn",
+		"q=chikcen+burito":       "Chicken Burrito@Taco Town",
+		"q=mains&city=dallas":    "Chicken Burrito@Taco Town",
+		"q=mains&limit=1":        "Burger@Burger Barn",
+		"q=burger+taco":          "",
+		"q=pizza":                "",
+		"q=chicken&city=Houston": "",
+	} {
+		if got := names(search(query)); got != expected {
+			t.Errorf("%s: expected %q, got %q", query, expected, got)
+		}
+	}
+	if results := search("q=burger"); results[0].Score <= search("q=buger")[0].Score || !results[1].Hotel.IsOpen {
+		t.Fatalf("expected an exact match to outrank a typo and hotels with availability, got %+v", results)
+	}
+
+	// Changes made after indexing are searchable right away
+	if err := s.usecase.UpdateHotel(domain.Hotel{ID: int16(f.hotelID), Name: "Patty Palace"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(search("q=barn")); got != "" {
+		t.Fatalf("expected the old name to be gone, got %q", got)
+	}
+	if got := names(search("q=patty")); got != "Patty Palace" {
+		t.Fatalf("expected the new name to be found, got %q", got)
+	}
+	if err := s.usecase.DeleteProduct(strconv.Itoa(f.burgerID)); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(search("q=burger")); got != "" {
+		t.Fatalf("expected the deleted burger to be gone, got %q", got)
+	}
+	if err := s.usecase.DeleteHotel(strconv.Itoa(tacoTownID)); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(search("q=burrito")); got != "" {
+		t.Fatalf("expected the products of a deleted hotel to be gone, got %q", got)
+	}
+
+	// Rebuilding indexes what the repository holds without duplicating hits
+	if indexed, err := s.usecase.RebuildSearchIndex(); err != nil || indexed != 2 {
+		t.Fatalf("expected the remaining hotel and fries to be indexed, got %d, %v", indexed, err)
+	}
+	if got := names(search("q=fries")); got != "Fries@Patty Palace" {
+		t.Fatalf("unexpected hits after a rebuild %q", got)
+	}
+
+	s.expectError(domain.InvalidSearchQuery, http.MethodGet, "/v1/search?q=+", "", nil)
+	s.expectError(domain.InvalidSearchQuery, http.MethodGet, "/v1/search?q=fries&limit=some", "", nil)
+}
+
+func TestMenu(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	hotelPath := "/v1/hotel/" + strconv.Itoa(f.hotelID)
+
+	section := func(name string, position int) int {
+		var created domain.MenuSection
+		s.expect(http.StatusCreated, http.MethodPost, hotelPath+"/sections", f.owner, domain.MenuSection{Name: name, Position: position}, &created)
+		return created.ID
+	}
+	mainsID := section("Mains", 2)
+	startersID := section("Starters", 1)
+	section("Drinks", 3)
+	s.expectError(domain.InvalidMenuSection, http.MethodPost, hotelPath+"/sections", f.owner, domain.MenuSection{Name: " "})
+	s.expectError(domain.Forbidden, http.MethodPost, hotelPath+"/sections", f.customer, domain.MenuSection{Name: "Secret"})
+
+	sandwich := domain.Product{
+		Name: "Crispy Chicken Sandwich with Pickles", StockLeft: 5, HotelID: f.hotelID, Category: "mains", Price: usd(1100),
+		SectionID: &mainsID, Description: "Buttermilk fried chicken on a toasted brioche bun",
+		ImageURL:  "https://images.quickbyte.com/sandwich.jpg",
+		Allergens: []domain.Allergen{domain.AllergenGluten, domain.AllergenEggs}, SpiceLevel: 2,
+	}
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, sandwich, nil)
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Garlic Bread", StockLeft: 5, HotelID: f.hotelID, Category: "starters", Price: usd(450),
+		SectionID: &startersID, DietaryTags: []domain.DietaryTag{domain.DietVegetarian},
+	}, nil)
+
+	for _, invalid := range []domain.Product{
+		{Name: strings.Repeat("a", 101)},
+		{Name: "Inferno Wings", SpiceLevel: domain.MaxSpiceLevel + 1},
+		{Name: "Mystery Stew", DietaryTags: []domain.DietaryTag{"keto"}},
+		{Name: "Nutty Bar", Allergens: []domain.Allergen{"nuts"}},
+		{Name: "Picture Pie", ImageURL: "ftp://images.quickbyte.com/pie.jpg"},
+	} {
+		invalid.HotelID, invalid.Price = f.hotelID, usd(100)
+		s.expectError(domain.InvalidMenuItem, http.MethodPost, "/v1/create/product", f.owner, invalid)
+	}
+	unknownSectionID := mainsID + 100
+	s.expectError(domain.MenuSectionNotFound, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Lost Soup", HotelID: f.hotelID, Price: usd(100), SectionID: &unknownSectionID,
+	})
+
+	// layoutOf fetches a menu page and describes its sections and products
+	var menu domain.Menu
+	layoutOf := func(path string) string {
+		menu = domain.Menu{}
+		s.expect(http.StatusOK, http.MethodGet, path, "", nil, &menu)
+		var layout []string
+		for _, section := range menu.Sections {
+			var names []string
+			for _, product := range section.Products {
+				names = append(names, product.Name)
+			}
+			layout = append(layout, section.Name+": "+strings.Join(names, ", "))
+		}
+		return strings.Join(layout, "; ")
+	}
+
+	// Sections come by position, the products without one last under Other, empty sections not at all
+	if got := layoutOf(hotelPath + "/products"); got != "Starters: Garlic Bread; Mains: Crispy Chicken Sandwich with Pickles; Other: Burger, Fries" {
+		t.Fatalf("unexpected menu %s", got)
+	}
+
+	// Pages follow the menu order whatever the sort, a section spanning pages continues on the next
+	if got := layoutOf(hotelPath + "/products?limit=3&sort=-price"); got != "Starters: Garlic Bread; Mains: Crispy Chicken Sandwich with Pickles; Other: Burger" || menu.NextCursor == "" {
+		t.Fatalf("unexpected first menu page %s", got)
+	}
+	if got := layoutOf(hotelPath + "/products?limit=3&sort=-price&cursor=" + menu.NextCursor); got != "Other: Fries" || menu.NextCursor != "" {
+		t.Fatalf("unexpected last menu page %s", got)
+	}
+	layoutOf(hotelPath + "/products")
+	stored := menu.Sections[1].Products[0]
+	if stored.Description != sandwich.Description || stored.ImageURL != sandwich.ImageURL || stored.SpiceLevel != 2 ||
+		len(stored.Allergens) != 2 || stored.Allergens[1] != domain.AllergenEggs || !stored.Available {
+		t.Fatalf("menu fields were not kept %+v", stored)
+	}
+
+	// Products off sale stay on the menu but cannot be ordered
+	availabilityPath := "/v1/product/" + strconv.Itoa(int(stored.ID)) + "/availability"
+	s.expectError(domain.Forbidden, http.MethodPost, availabilityPath, f.customer, domain.ProductAvailabilityRequest{})
+	s.expect(http.StatusOK, http.MethodPost, availabilityPath, f.owner, domain.ProductAvailabilityRequest{Available: false}, nil)
+	s.expect(http.StatusOK, http.MethodGet, hotelPath+"/products?category=mains", "", nil, &menu)
+	if len(menu.Sections) != 2 || menu.Sections[0].Products[0].Available {
+		t.Fatalf("expected the sandwich listed as unavailable, got %+v", menu.Sections)
+	}
+	order := domain.CreateOrderRequest{Products: []domain.OrderProductRequest{{ProductID: int(stored.ID), Quantity: 1}}}
+	s.expectError(domain.ProductUnavailable, http.MethodPost, hotelPath+"/create/order", f.customer, order)
+	s.expect(http.StatusOK, http.MethodPost, availabilityPath, f.owner, domain.ProductAvailabilityRequest{Available: true}, nil)
+	s.expect(http.StatusCreated, http.MethodPost, hotelPath+"/create/order", f.customer, order, nil)
+	s.expectError(domain.ProductNotFound, http.MethodPost, "/v1/product/999/availability", f.owner, domain.ProductAvailabilityRequest{})
+	s.expectError(domain.InvalidAvailability, http.MethodPost, availabilityPath, f.owner, json.RawMessage(`{"available":"yes"}`))
+
+	// Descriptions and dietary tags are searchable
+	var results []domain.SearchResult
+	s.expect(http.StatusOK, http.MethodGet, "/v1/search?q=vegetarian", "", nil, &results)
+	if len(results) != 1 || results[0].Product.Name != "Garlic Bread" {
+		t.Fatalf("expected the garlic bread for vegetarians, got %+v", results)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/search?q=brioche", "", nil, &results)
+	if len(results) != 1 || results[0].Product.ID != stored.ID {
+		t.Fatalf("expected the sandwich by its description, got %+v", results)
+	}
+
+	// Sections can be renamed and moved, deleting one moves its products to Other
+	mainsPath := hotelPath + "/sections/" + strconv.Itoa(mainsID)
+	var updated domain.MenuSection
+	s.expect(http.StatusOK, http.MethodPost, mainsPath, f.owner, domain.MenuSection{Name: "Mains & Burgers", Position: 0}, &updated)
+	if updated.ID != mainsID || updated.Name != "Mains & Burgers" || updated.Position != 0 {
+		t.Fatalf("unexpected updated section %+v", updated)
+	}
+	s.expectError(domain.InvalidMenuSection, http.MethodPost, mainsPath, f.owner, domain.MenuSection{Name: ""})
+	s.expectError(domain.Forbidden, http.MethodPost, mainsPath, f.customer, domain.MenuSection{Name: "Secret"})
+	s.expectError(domain.MenuSectionNotFound, http.MethodPost, hotelPath+"/sections/"+strconv.Itoa(unknownSectionID), f.owner, domain.MenuSection{Name: "Lost"})
+	s.expect(http.StatusOK, http.MethodPost, hotelPath+"/sections/"+strconv.Itoa(startersID)+"/delete", f.owner, nil, nil)
+	s.expectError(domain.MenuSectionNotFound, http.MethodPost, hotelPath+"/sections/"+strconv.Itoa(startersID)+"/delete", f.owner, nil)
+	if got := layoutOf(hotelPath + "/products"); got != "Mains & Burgers: Crispy Chicken Sandwich with Pickles; Other: Burger, Fries, Garlic Bread" {
+		t.Fatalf("unexpected menu after the section changes %s", got)
+	}
+}
+
+func TestCart(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+
+	var message string
+	s.expect(http.StatusOK, http.MethodPost, "/v1/add/user/cart", f.customer, domain.CartProducts{ProductID: f.burgerID, Quantity: 1}, &message)
+	if message != "product is added to cart" {
+		t.Fatalf("unexpected add message %q", message)
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/v1/add/user/cart", f.customer, domain.CartProducts{ProductID: f.friesID, Quantity: 2}, nil)
+	s.expect(http.StatusOK, http.MethodPost, "/v1/update/user/cart", f.customer, domain.CartProducts{ProductID: f.burgerID, Quantity: 3}, &message)
+	if message != "update successfull !" {
+		t.Fatalf("unexpected update message %q", message)
+	}
+
+	var cart domain.CartResponse
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/cart", f.customer, nil, &cart)
+	if len(cart.Products) != 2 || cart.Products[0].HotelName != "Burger Barn" {
+		t.Fatalf("unexpected cart %+v", cart)
+	}
+
+	s.expect(http.StatusOK, http.MethodPost, "/v1/delete/user/cart", f.customer, domain.CartProducts{ProductID: f.friesID}, &message)
+	if message != "product is removed from cart" {
+		t.Fatalf("unexpected delete message %q", message)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/cart", f.customer, nil, &cart)
+	if len(cart.Products) != 1 || int(cart.Products[0].ID) != f.burgerID {
+		t.Fatalf("unexpected cart after delete %+v", cart)
+	}
+
+	// Another user's cart is never visible, the identity comes from the token
+	other := s.signup("other@quickbyte.com", "+13333333333", domain.RoleCustomer)
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/cart?userID="+strconv.Itoa(f.customerID), other, nil, &cart)
+	if len(cart.Products) != 0 {
+		t.Fatalf("expected an empty cart for another user, got %+v", cart)
+	}
+
+	// Restaurant owners do not have carts
+	s.expectError(domain.Forbidden, http.MethodGet, "/v1/user/cart", f.owner, nil)
+}
+
+func TestOrders(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+
+	orderPath := "/v1/hotel/" + strconv.Itoa(f.hotelID) + "/create/order"
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		PhoneNumber: "+12222222222",
+		OrderTotal:  usd(1400),
+		Products: []domain.OrderProductRequest{
+			{ProductID: f.burgerID, Quantity: 1, PriceAtPurchase: domain.Money{Amount: 800}},
+			{ProductID: f.friesID, Quantity: 2},
+		},
+	}, &order)
+	if order.ID == 0 || order.HotelID != f.hotelID || order.UserID != f.customerID || order.OrderStatus != domain.OrderPaymentPending {
+		t.Fatalf("unexpected order %+v", order)
+	}
+	if order.Payment == nil || order.Payment.Status != domain.PaymentAuthorized || order.Payment.Amount != usd(1400) {
+		t.Fatalf("expected the order total to be authorized, got %+v", order.Payment)
+	}
+	if order.OrderTotal != usd(1400) || len(order.Products) != 2 || order.Products[1].PriceAtPurchase != usd(300) || order.Products[1].OrderID != order.ID {
+		t.Fatalf("unexpected order pricing %+v", order)
+	}
+
+	// The order keeps the prices it was bought at after the menu is repriced
+	owner, err := s.usecase.VerifyToken(f.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.usecase.UpdateProduct(owner, domain.Product{ID: int32(f.friesID), Price: usd(450)}); err != nil {
+		t.Fatal(err)
+	}
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders", f.customer, nil, &orders)
+	if len(orders.Items) != 1 || orders.Items[0].ID != order.ID || orders.Items[0].OrderTotal != usd(1400) || len(orders.Items[0].Products) != 2 {
+		t.Fatalf("unexpected orders %+v", orders.Items)
+	}
+	fries := orders.Items[0].Products[1]
+	if fries.ProductID != f.friesID || fries.Name != "Fries" || fries.Quantity != 2 || fries.PriceAtPurchase != usd(300) {
+		t.Fatalf("expected the fries as they were bought, got %+v", fries)
+	}
+
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/"+strconv.Itoa(f.customerID)+"/orders", f.customer, nil, &orders)
+	if len(orders.Items) != 1 {
+		t.Fatalf("expected 1 order on the legacy route, got %d", len(orders.Items))
+	}
+
+	// Another user can neither read these orders nor place orders as a restaurant owner
+	other := s.signup("other@quickbyte.com", "+13333333333", domain.RoleCustomer)
+	s.expectError(domain.Forbidden, http.MethodGet, "/v1/user/"+strconv.Itoa(f.customerID)+"/orders", other, nil)
+	s.expectError(domain.Forbidden, http.MethodPost, orderPath, f.owner, domain.CreateOrderRequest{})
+}
+
+func TestOrderPricing(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderPath := "/v1/hotel/" + strconv.Itoa(f.hotelID) + "/create/order"
+	burger := func(quantity int, price domain.Money) domain.OrderProductRequest {
+		return domain.OrderProductRequest{ProductID: f.burgerID, Quantity: quantity, PriceAtPurchase: price}
+	}
+
+	s.expectError(domain.OrderPriceMismatch, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{burger(1, usd(100))},
+	})
+	s.expectError(domain.OrderPriceMismatch, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		OrderTotal: usd(100), Products: []domain.OrderProductRequest{burger(1, usd(800))},
+	})
+	s.expectError(domain.InvalidOrderProduct, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{})
+	s.expectError(domain.InvalidOrderProduct, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{burger(0, usd(800))},
+	})
+	s.expectError(domain.InvalidOrderProduct, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{burger(1, usd(800)), burger(1, usd(800))},
+	})
+	s.expectError(domain.InvalidOrderProduct, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: 999, Quantity: 1}},
+	})
+	s.expectError(domain.HotelNotFound, http.MethodPost, "/v1/hotel/999/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{burger(1, usd(800))},
+	})
+
+	// A product can only be ordered from the hotel that sells it
+	ownerID := userIDOf(s, "owner@quickbyte.com")
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{Name: "Taco Town", OwnerID: &ownerID}, nil)
+	s.expectError(domain.InvalidOrderProduct, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID+1)+"/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{burger(1, usd(800))},
+	})
+
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders", f.customer, nil, &orders)
+	if len(orders.Items) != 0 {
+		t.Fatalf("rejected orders must not be stored, got %+v", orders.Items)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderPath := "/v1/hotel/" + strconv.Itoa(f.hotelID) + "/create/order"
+
+	// Prices without a currency are in the configured one, unknown codes are refused
+	s.expectError(domain.InvalidCurrency, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Shake", StockLeft: 5, HotelID: f.hotelID, Category: "drinks", Price: domain.Money{Amount: 333, Currency: "dollars"},
+	})
+	// Negative prices and stock are refused too
+	s.expectError(domain.InvalidPrice, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Shake", StockLeft: 5, HotelID: f.hotelID, Category: "drinks", Price: domain.Money{Amount: -333},
+	})
+	s.expectError(domain.InvalidMenuItem, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Shake", StockLeft: -5, HotelID: f.hotelID, Category: "drinks", Price: domain.Money{Amount: 333},
+	})
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Shake", StockLeft: 5, HotelID: f.hotelID, Category: "drinks", Price: domain.Money{Amount: 333},
+	}, nil)
+	shakeID := f.friesID + 1
+
+	// A third of a dollar three times adds up to exactly 9.99
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		OrderTotal: usd(999),
+		Products:   []domain.OrderProductRequest{{ProductID: shakeID, Quantity: 3, PriceAtPurchase: usd(333)}},
+	}, &order)
+	if order.OrderTotal != usd(999) || order.Payment.Amount != usd(999) || order.OrderTotal.String() != "9.99 USD" {
+		t.Fatalf("unexpected order total %+v", order)
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(order.ID)+"/pay", f.customer, nil, nil)
+
+	var refund domain.Refund
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/order/"+strconv.Itoa(order.ID)+"/refund", f.admin, domain.RefundRequest{
+		Items: []domain.RefundItemRequest{{ProductID: shakeID, Quantity: 1}},
+	}, &refund)
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/order/"+strconv.Itoa(order.ID)+"/refund", f.admin, nil, &refund)
+	if refund.Amount != usd(666) {
+		t.Fatalf("expected the remaining 6.66 USD to be refunded, got %+v", refund)
+	}
+	var reconciliation domain.PaymentReconciliation
+	s.expect(http.StatusOK, http.MethodGet, "/v1/order/"+strconv.Itoa(order.ID)+"/ledger", f.admin, nil, &reconciliation)
+	if !reconciliation.Balanced || reconciliation.Refunded != usd(999) || reconciliation.LedgerBalance != usd(0) {
+		t.Fatalf("unexpected reconciliation %+v", reconciliation)
+	}
+
+	// Client amounts in another currency do not match, and an order cannot mix currencies
+	s.expectError(domain.OrderPriceMismatch, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: shakeID, Quantity: 1, PriceAtPurchase: domain.Money{Amount: 333, Currency: "EUR"}}},
+	})
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Crepe", StockLeft: 5, HotelID: f.hotelID, Category: "desserts", Price: domain.Money{Amount: 450, Currency: "eur"},
+	}, nil)
+	s.expectError(domain.CurrencyMismatch, http.MethodPost, orderPath, f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: shakeID, Quantity: 1}, {ProductID: shakeID + 1, Quantity: 1}},
+	})
+
+	// Updates keep a product in its currency unless they name another one
+	owner, err := s.usecase.VerifyToken(f.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crepeID := strconv.Itoa(shakeID + 1)
+	for _, update := range []struct {
+		price    domain.Money
+		expected domain.Money
+	}{
+		{domain.Money{}, domain.Money{Amount: 450, Currency: "EUR"}},
+		{domain.Money{Amount: 500}, domain.Money{Amount: 500, Currency: "EUR"}},
+		{domain.Money{Amount: 550, Currency: "gbp"}, domain.Money{Amount: 550, Currency: "GBP"}},
+	} {
+		if err := s.usecase.UpdateProduct(owner, domain.Product{ID: int32(shakeID + 1), Name: "Crepe", Price: update.price}); err != nil {
+			t.Fatal(err)
+		}
+		var crepe domain.Product
+		s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+crepeID, "", nil, &crepe)
+		if crepe.Price != update.expected {
+			t.Fatalf("updating with %+v: expected %+v, got %+v", update.price, update.expected, crepe.Price)
+		}
+	}
+	err = s.usecase.UpdateProduct(owner, domain.Product{ID: int32(shakeID + 1), Price: domain.Money{Amount: 1, Currency: "euros"}})
+	if !errors.Is(err, domain.InvalidCurrency) {
+		t.Fatalf("expected an invalid currency to be refused, got %v", err)
+	}
+	err = s.usecase.UpdateProduct(owner, domain.Product{ID: int32(shakeID + 1), Price: domain.Money{Amount: -1}})
+	if !errors.Is(err, domain.InvalidPrice) {
+		t.Fatalf("expected a negative price to be refused, got %v", err)
+	}
+}
+
+func TestCheckoutCart(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	addToCart := func(productID, quantity int) {
+		s.expect(http.StatusOK, http.MethodPost, "/v1/add/user/cart", f.customer, domain.CartProducts{ProductID: productID, Quantity: quantity}, nil)
+	}
+
+	s.expectError(domain.CartEmpty, http.MethodPost, "/v1/user/cart/checkout", f.customer, nil)
+	s.expectError(domain.InvalidCartProduct, http.MethodPost, "/v1/add/user/cart", f.customer, json.RawMessage(`{"product_id":"burger"}`))
+
+	// Repeated cart rows of a product are merged into one order line
+	addToCart(f.burgerID, 1)
+	addToCart(f.burgerID, 1)
+	addToCart(f.friesID, 1)
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/user/cart/checkout", f.customer, nil, &order)
+	if order.ID == 0 || order.HotelID != f.hotelID || order.PhoneNumber != "+12222222222" || order.OrderTotal != usd(1900) || len(order.Products) != 2 {
+		t.Fatalf("unexpected order %+v", order)
+	}
+
+	var cart domain.CartResponse
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/cart", f.customer, nil, &cart)
+	if len(cart.Products) != 0 {
+		t.Fatalf("expected the cart to be emptied, got %+v", cart)
+	}
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.StockLeft != 8 {
+		t.Fatalf("expected 8 burgers left, got %d", product.StockLeft)
+	}
+
+	// Nothing changes when a product lacks stock
+	addToCart(f.burgerID, 9)
+	s.expectError(domain.InsufficientStock, http.MethodPost, "/v1/user/cart/checkout", f.customer, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/cart", f.customer, nil, &cart)
+	if len(cart.Products) != 1 {
+		t.Fatalf("expected the cart to be kept, got %+v", cart)
+	}
+	s.expect(http.StatusOK, http.MethodPost, "/v1/update/user/cart", f.customer, domain.CartProducts{ProductID: f.burgerID, Quantity: 1}, nil)
+
+	// Orders are placed at one hotel, so the cart cannot mix hotels
+	ownerID := userIDOf(s, "owner@quickbyte.com")
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/hotel", f.admin, domain.Hotel{Name: "Taco Town", OwnerID: &ownerID}, nil)
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Taco", StockLeft: 5, HotelID: f.hotelID + 1, Category: "mains", Price: usd(400),
+	}, nil)
+	addToCart(f.friesID+1, 1)
+	s.expectError(domain.CartMultipleHotels, http.MethodPost, "/v1/user/cart/checkout", f.customer, nil)
+}
+
+// TestConcurrentOrdersDoNotOversell runs against the in-memory repository, whose single lock
+// serializes orders. The conditional decrement and the lock order of the MySQL repository need
+// a MySQL server and are not covered here.
+func TestConcurrentOrdersDoNotOversell(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/create/product", f.owner, domain.Product{
+		Name: "Last Pie", StockLeft: 3, HotelID: f.hotelID, Category: "desserts", Price: usd(500),
+	}, nil)
+	pieID := f.friesID + 1
+
+	const buyers = 10
+	tokens := make([]string, buyers)
+	for i := range tokens {
+		tokens[i] = s.signup("buyer"+strconv.Itoa(i)+"@quickbyte.com", "+1444444444"+strconv.Itoa(i), domain.RoleCustomer)
+	}
+
+	var wg sync.WaitGroup
+	statuses := make([]int, buyers)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], _ = s.do(http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", tokens[i], domain.CreateOrderRequest{
+				Products: []domain.OrderProductRequest{{ProductID: pieID, Quantity: 1}},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	created, conflicts := 0, 0
+	for _, status := range statuses {
+		switch status {
+		case http.StatusCreated:
+			created++
+		case domain.InsufficientStock.Status:
+			conflicts++
+		default:
+			t.Fatalf("unexpected status %d", status)
+		}
+	}
+	if created != 3 || conflicts != buyers-3 {
+		t.Fatalf("expected 3 orders and %d rejections, got %d and %d", buyers-3, created, conflicts)
+	}
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(pieID), "", nil, &product)
+	if product.StockLeft != 0 {
+		t.Fatalf("expected no stock left, got %d", product.StockLeft)
+	}
+}
+
+func TestUnpaidOrdersReleaseStock(t *testing.T) {
+	s := newTestServer(t, func(options *mcdusecase.Options) { options.PaymentTimeout = time.Nanosecond })
+	f := newStoreFixture(s)
+
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 4}},
+	}, &order)
+	if order.PaymentDueAt == nil {
+		t.Fatal("expected a payment deadline on the order")
+	}
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.StockLeft != 6 {
+		t.Fatalf("expected 6 burgers left while reserved, got %d", product.StockLeft)
+	}
+
+	released, err := s.usecase.ReleaseExpiredReservations()
+	if err != nil || released != 1 {
+		t.Fatalf("expected 1 released order, got %d, %v", released, err)
+	}
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.StockLeft != 10 {
+		t.Fatalf("expected the reserved burgers back in stock, got %d", product.StockLeft)
+	}
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders", f.customer, nil, &orders)
+	if len(orders.Items) != 1 || orders.Items[0].OrderStatus != "cancelled" {
+		t.Fatalf("expected the order to be cancelled, got %+v", orders.Items)
+	}
+	if open := s.gateway.OpenAuthorizations(); open != 0 {
+		t.Fatalf("expected the authorization of the expired order to be voided, %d still open", open)
+	}
+
+	// Releasing again finds nothing to do
+	if released, err := s.usecase.ReleaseExpiredReservations(); err != nil || released != 0 {
+		t.Fatalf("expected nothing to release, got %d, %v", released, err)
+	}
+}
+
+// placeOrder places and pays for an order of one burger and returns its ID
+func placeOrder(s *testServer, f storeFixture) int {
+	s.t.Helper()
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 1}},
+	}, &order)
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(order.ID)+"/pay", f.customer, nil, &order)
+	if order.OrderStatus != domain.OrderPlaced {
+		s.t.Fatalf("expected a paid order to be placed, got %s", order.OrderStatus)
+	}
+	return order.ID
+}
+
+// failingPayments is an in-memory repository that cannot store payments
+type failingPayments struct {
+	domain.MCDRepository
+}
+
+func (failingPayments) CreatePayment(domain.Payment) (domain.Payment, error) {
+	return domain.Payment{}, errors.New("payments table is unavailable")
+}
+
+func TestUnrecordedPaymentCancelsOrder(t *testing.T) {
+	s := newTestServerWith(t, failingPayments{memoryrepository.NewRepository()})
+	f := newStoreFixture(s)
+
+	status, _ := s.do(http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 2}},
+	})
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected the order to fail, got %d", status)
+	}
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.StockLeft != 10 {
+		t.Fatalf("expected the reserved burgers back in stock, got %d", product.StockLeft)
+	}
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders", f.customer, nil, &orders)
+	if len(orders.Items) != 1 || orders.Items[0].OrderStatus != domain.OrderCancelled {
+		t.Fatalf("expected the order to be cancelled, got %+v", orders.Items)
+	}
+	if open := s.gateway.OpenAuthorizations(); open != 0 {
+		t.Fatalf("expected the authorization to be voided, %d still open", open)
+	}
+}
+
+func TestPayments(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderPath := "/v1/hotel/" + strconv.Itoa(f.hotelID) + "/create/order"
+	request := domain.CreateOrderRequest{Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 2}}}
+	stockLeft := func() int {
+		var product domain.Product
+		s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+		return product.StockLeft
+	}
+
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, orderPath, f.customer, request, &order)
+	orderPath = "/v1/order/" + strconv.Itoa(order.ID)
+
+	// The restaurant only sees the order once it is paid
+	s.expectError(domain.OrderStatusConflict, http.MethodPost, orderPath+"/status", f.owner, domain.UpdateOrderStatusRequest{Status: domain.OrderAccepted})
+	s.expectError(domain.Forbidden, http.MethodPost, orderPath+"/pay", f.owner, nil)
+
+	// A declined capture leaves the order waiting for payment
+	s.gateway.SetDeclining(true)
+	s.expectError(domain.PaymentDeclined, http.MethodPost, orderPath+"/pay", f.customer, nil)
+	s.gateway.SetDeclining(false)
+
+	s.expect(http.StatusOK, http.MethodPost, orderPath+"/pay", f.customer, nil, &order)
+	if order.OrderStatus != domain.OrderPlaced || order.Payment == nil || order.Payment.Status != domain.PaymentCaptured {
+		t.Fatalf("expected a placed order with a captured payment, got %+v", order)
+	}
+	s.expectError(domain.OrderStatusConflict, http.MethodPost, orderPath+"/pay", f.customer, nil)
+
+	var payment domain.Payment
+	s.expect(http.StatusOK, http.MethodGet, orderPath+"/payment", f.owner, nil, &payment)
+	if payment.Amount != usd(1600) || payment.Status != domain.PaymentCaptured || payment.TransactionID == "" {
+		t.Fatalf("unexpected payment %+v", payment)
+	}
+	s.expect(http.StatusOK, http.MethodPost, orderPath+"/reject", f.owner, domain.CancelOrderRequest{Reason: "out of buns"}, nil)
+	s.expect(http.StatusOK, http.MethodGet, orderPath+"/payment", f.customer, nil, &payment)
+	if payment.Status != domain.PaymentRefundPending {
+		t.Fatalf("expected the captured payment to await a refund, got %s", payment.Status)
+	}
+
+	// Cancelling before paying drops the authorization
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, request, &order)
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(order.ID)+"/cancel", f.customer, nil, nil)
+	s.expect(http.StatusOK, http.MethodGet, "/v1/order/"+strconv.Itoa(order.ID)+"/payment", f.customer, nil, &payment)
+	if payment.Status != domain.PaymentVoided {
+		t.Fatalf("expected the authorization to be voided, got %s", payment.Status)
+	}
+
+	// A declined authorization cancels the order and gives its stock back
+	s.gateway.SetDeclining(true)
+	s.expectError(domain.PaymentDeclined, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, request)
+	if left := stockLeft(); left != 10 {
+		t.Fatalf("expected every burger back in stock, got %d", left)
+	}
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders", f.customer, nil, &orders)
+	if len(orders.Items) != 3 || orders.Items[2].OrderStatus != domain.OrderCancelled {
+		t.Fatalf("expected the declined order to be cancelled, got %+v", orders.Items)
+	}
+}
+
+func TestRefunds(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 2}, {ProductID: f.friesID, Quantity: 1}},
+	}, &order)
+	orderPath := "/v1/order/" + strconv.Itoa(order.ID)
+	oneBurger := domain.RefundRequest{Reason: "burger missing", Items: []domain.RefundItemRequest{{ProductID: f.burgerID, Quantity: 1}}}
+
+	// Nothing can be refunded before the payment is captured
+	s.expectError(domain.PaymentNotRefundable, http.MethodPost, orderPath+"/refund", f.admin, nil)
+	s.expect(http.StatusOK, http.MethodPost, orderPath+"/pay", f.customer, nil, nil)
+	s.expectError(domain.Forbidden, http.MethodPost, orderPath+"/refund", f.owner, oneBurger)
+
+	var refund domain.Refund
+	s.expect(http.StatusCreated, http.MethodPost, orderPath+"/refund", f.admin, oneBurger, &refund)
+	if refund.Amount != usd(800) || refund.Status != domain.RefundSucceeded || len(refund.Items) != 1 {
+		t.Fatalf("unexpected refund %+v", refund)
+	}
+	s.expectError(domain.RefundExceedsCapture, http.MethodPost, orderPath+"/refund", f.admin, domain.RefundRequest{
+		Items: []domain.RefundItemRequest{{ProductID: f.burgerID, Quantity: 2}},
+	})
+	s.expectError(domain.InvalidRefundItem, http.MethodPost, orderPath+"/refund", f.admin, domain.RefundRequest{
+		Items: []domain.RefundItemRequest{{ProductID: 999, Quantity: 1}},
+	})
+
+	// A refund the gateway declines does not count against the payment
+	s.gateway.SetDeclining(true)
+	s.expectError(domain.PaymentDeclined, http.MethodPost, orderPath+"/refund", f.admin, oneBurger)
+	s.gateway.SetDeclining(false)
+
+	s.expect(http.StatusCreated, http.MethodPost, orderPath+"/refund", f.admin, nil, &refund)
+	if refund.Amount != usd(1100) {
+		t.Fatalf("expected the remaining 11.00 USD to be refunded, got %+v", refund)
+	}
+	s.expectError(domain.PaymentNotRefundable, http.MethodPost, orderPath+"/refund", f.admin, nil)
+
+	var reconciliation domain.PaymentReconciliation
+	s.expect(http.StatusOK, http.MethodGet, orderPath+"/ledger", f.admin, nil, &reconciliation)
+	if !reconciliation.Balanced || reconciliation.Captured != usd(1900) || reconciliation.Refunded != usd(1900) || reconciliation.Refundable != usd(0) || reconciliation.LedgerBalance != usd(0) {
+		t.Fatalf("unexpected reconciliation %+v", reconciliation)
+	}
+	if len(reconciliation.Entries) != 6 || len(reconciliation.Refunds) != 3 || reconciliation.Refunds[1].Status != domain.RefundFailed {
+		t.Fatalf("expected a charge and two refunds in the ledger, got %+v", reconciliation)
+	}
+	s.expectError(domain.Forbidden, http.MethodGet, orderPath+"/ledger", f.customer, nil)
+}
+
+// failingRefunds is an in-memory repository that cannot complete refunds while failing is set
+type failingRefunds struct {
+	domain.MCDRepository
+	failing bool
+}
+
+func (r *failingRefunds) CompleteRefund(refundID int, journal []domain.LedgerEntry) error {
+	if r.failing {
+		return errors.New("refunds table is unavailable")
+	}
+	return r.MCDRepository.CompleteRefund(refundID, journal)
+}
+
+func TestPendingRefundsAreRetried(t *testing.T) {
+	repository := &failingRefunds{MCDRepository: memoryrepository.NewRepository(), failing: true}
+	s := newTestServerWith(t, repository, func(options *mcdusecase.Options) {
+		options.RefundRetryAfter = 0
+	})
+	f := newStoreFixture(s)
+	orderID := placeOrder(s, f)
+	orderPath := "/v1/order/" + strconv.Itoa(orderID)
+
+	// The gateway refunds the money but the refund cannot be recorded, so it stays pending
+	if status, _ := s.do(http.MethodPost, orderPath+"/refund", f.admin, nil); status != http.StatusInternalServerError {
+		t.Fatalf("expected the refund to fail, got %d", status)
+	}
+	var reconciliation domain.PaymentReconciliation
+	s.expect(http.StatusOK, http.MethodGet, orderPath+"/ledger", f.admin, nil, &reconciliation)
+	if len(reconciliation.Refunds) != 1 || reconciliation.Refunds[0].Status != domain.RefundPending || reconciliation.Refundable != usd(0) {
+		t.Fatalf("expected a pending refund holding the payment, got %+v", reconciliation)
+	}
+
+	repository.failing = false
+	completed, err := s.usecase.RetryPendingRefunds()
+	if err != nil || completed != 1 {
+		t.Fatalf("expected the pending refund to be completed, got %d, %v", completed, err)
+	}
+	// The gateway would decline refunding the payment twice, so the retry only confirmed it
+	s.expect(http.StatusOK, http.MethodGet, orderPath+"/ledger", f.admin, nil, &reconciliation)
+	if !reconciliation.Balanced || reconciliation.Refunds[0].Status != domain.RefundSucceeded || reconciliation.Refunded != usd(800) {
+		t.Fatalf("expected the refund to be settled, got %+v", reconciliation)
+	}
+	if completed, err = s.usecase.RetryPendingRefunds(); err != nil || completed != 0 {
+		t.Fatalf("expected nothing left to retry, got %d, %v", completed, err)
+	}
+}
+
+// failingVoids is a payment gateway that cannot be reached to void authorizations
+type failingVoids struct {
+	domain.PaymentGateway
+	failing bool
+}
+
+func (g *failingVoids) Void(transactionID string) error {
+	if g.failing {
+		return errors.New("gateway is unavailable")
+	}
+	return g.PaymentGateway.Void(transactionID)
+}
+
+func TestPendingVoidsAreRetried(t *testing.T) {
+	gateway := &failingVoids{failing: true}
+	s := newTestServer(t, func(options *mcdusecase.Options) {
+		gateway.PaymentGateway = options.PaymentGateway
+		options.PaymentGateway = gateway
+		options.RefundRetryAfter = 0
+	})
+	f := newStoreFixture(s)
+	var order domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 1}},
+	}, &order)
+	paymentPath := "/v1/order/" + strconv.Itoa(order.ID) + "/payment"
+
+	// The order is cancelled but the gateway cannot be told, so the void stays pending
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(order.ID)+"/cancel", f.customer, nil, nil)
+	var payment domain.Payment
+	s.expect(http.StatusOK, http.MethodGet, paymentPath, f.customer, nil, &payment)
+	if payment.Status != domain.PaymentVoidPending || s.gateway.OpenAuthorizations() != 1 {
+		t.Fatalf("expected the authorization to await its void, got %s", payment.Status)
+	}
+
+	gateway.failing = false
+	voided, err := s.usecase.RetryPendingVoids()
+	if err != nil || voided != 1 {
+		t.Fatalf("expected the pending void to be completed, got %d, %v", voided, err)
+	}
+	s.expect(http.StatusOK, http.MethodGet, paymentPath, f.customer, nil, &payment)
+	if payment.Status != domain.PaymentVoided || s.gateway.OpenAuthorizations() != 0 {
+		t.Fatalf("expected the authorization to be voided, got %s", payment.Status)
+	}
+	if voided, err = s.usecase.RetryPendingVoids(); err != nil || voided != 0 {
+		t.Fatalf("expected nothing left to retry, got %d, %v", voided, err)
+	}
+}
+
+func TestLedgerAdjustments(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderID := placeOrder(s, f)
+	adjustPath := "/v1/order/" + strconv.Itoa(orderID) + "/ledger/adjustments"
+
+	s.expectError(domain.Forbidden, http.MethodPost, adjustPath, f.owner, domain.LedgerAdjustmentRequest{Amount: usd(250), Reason: "fee reversal"})
+	s.expectError(domain.InvalidLedgerAdjustment, http.MethodPost, adjustPath, f.admin, domain.LedgerAdjustmentRequest{Amount: usd(250)})
+	s.expectError(domain.InvalidLedgerAdjustment, http.MethodPost, adjustPath, f.admin, domain.LedgerAdjustmentRequest{Reason: "nothing"})
+	s.expectError(domain.CurrencyMismatch, http.MethodPost, adjustPath, f.admin, domain.LedgerAdjustmentRequest{
+		Amount: domain.Money{Amount: 250, Currency: "EUR"}, Reason: "fee reversal",
+	})
+
+	var reconciliation domain.PaymentReconciliation
+	s.expect(http.StatusCreated, http.MethodPost, adjustPath, f.admin, domain.LedgerAdjustmentRequest{
+		Amount: domain.Money{Amount: 250}, Reason: "fee reversal",
+	}, &reconciliation)
+	if reconciliation.Balanced || reconciliation.LedgerBalance != usd(1050) || len(reconciliation.Entries) != 4 {
+		t.Fatalf("expected the adjustment to move the ledger away from the gateway, got %+v", reconciliation)
+	}
+	adjustment := reconciliation.Entries[2]
+	if adjustment.Kind != domain.LedgerAdjustment || adjustment.Reason != "fee reversal" || adjustment.CreatedBy == nil || *adjustment.CreatedBy != userIDOf(s, "admin@quickbyte.com") {
+		t.Fatalf("expected the adjustment to record who made it and why, got %+v", adjustment)
+	}
+
+	// A negative adjustment takes it back
+	s.expect(http.StatusCreated, http.MethodPost, adjustPath, f.admin, domain.LedgerAdjustmentRequest{
+		Amount: usd(-250), Reason: "fee reversal entered twice",
+	}, &reconciliation)
+	if !reconciliation.Balanced || reconciliation.LedgerBalance != usd(800) || len(reconciliation.Entries) != 6 {
+		t.Fatalf("expected the ledger to balance again, got %+v", reconciliation)
+	}
+}
+
+// webhook sends a payment event signed at the given time and returns the status code and body
+func (s *testServer) webhook(event domain.PaymentEvent, signedAt time.Time) (int, []byte) {
+	s.t.Helper()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	header := http.Header{domain.PaymentSignatureHeader: {payment.SignWebhook(testWebhookSecret, payload, signedAt)}}
+	res, raw := s.send(http.MethodPost, "/v1/payments/webhook", "", header, json.RawMessage(payload))
+	return res.StatusCode, raw
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderPath := "/v1/hotel/" + strconv.Itoa(f.hotelID) + "/create/order"
+	request := domain.CreateOrderRequest{Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 3}}}
+	var paid, unpaid domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, orderPath, f.customer, request, &paid)
+	s.expect(http.StatusCreated, http.MethodPost, orderPath, f.customer, request, &unpaid)
+	captured := domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventCaptured, TransactionID: paid.Payment.TransactionID, Amount: usd(2400)}
+
+	// Unsigned, wrongly signed and stale webhooks are refused
+	s.expectError(domain.InvalidWebhookSignature, http.MethodPost, "/v1/payments/webhook", "", captured)
+	payload, _ := json.Marshal(captured)
+	res, _ := s.send(http.MethodPost, "/v1/payments/webhook", "", http.Header{
+		domain.PaymentSignatureHeader: {payment.SignWebhook([]byte("not the webhook secret, not at all"), payload, time.Now())},
+	}, json.RawMessage(payload))
+	if res.StatusCode != domain.InvalidWebhookSignature.Status {
+		t.Fatalf("expected a wrongly signed webhook to be refused, got %d", res.StatusCode)
+	}
+	if status, raw := s.webhook(captured, time.Now().Add(-10*time.Minute)); status != domain.WebhookExpired.Status {
+		t.Fatalf("expected a stale webhook to be refused, got %d %s", status, raw)
+	}
+
+	// A capture of another amount than the order's is refused
+	short := captured
+	short.Amount = usd(800)
+	if status, raw := s.webhook(short, time.Now()); status != domain.WebhookAmountMismatch.Status {
+		t.Fatalf("expected a capture of the wrong amount to be refused, got %d %s", status, raw)
+	}
+	var pending domain.Payment
+	s.expect(http.StatusOK, http.MethodGet, "/v1/order/"+strconv.Itoa(paid.ID)+"/payment", f.customer, nil, &pending)
+	if pending.Status != domain.PaymentAuthorized {
+		t.Fatalf("expected the payment to stay authorized, got %+v", pending)
+	}
+
+	// Webhooks signed with the previous secret are accepted while it is rotated out
+	var response domain.CustomResponse
+	payload, _ = json.Marshal(captured)
+	res, raw := s.send(http.MethodPost, "/v1/payments/webhook", "", http.Header{
+		domain.PaymentSignatureHeader: {payment.SignWebhook(testPreviousWebhookSecret, payload, time.Now())},
+	}, json.RawMessage(payload))
+	if err := json.Unmarshal(raw, &response); err != nil || res.StatusCode != http.StatusOK || response.Status != "processed" {
+		t.Fatalf("expected the event to be processed, got %d %s", res.StatusCode, raw)
+	}
+	status, raw := s.webhook(captured, time.Now())
+	if err := json.Unmarshal(raw, &response); err != nil || status != http.StatusOK || response.Status != "duplicate" {
+		t.Fatalf("expected the event to be a duplicate, got %d %s", status, raw)
+	}
+	var order domain.Order
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(paid.ID)+"/status", f.owner, domain.UpdateOrderStatusRequest{Status: domain.OrderAccepted}, &order)
+
+	// Redeliveries are acknowledged without being applied again
+	status, raw = s.webhook(captured, time.Now())
+	if err := json.Unmarshal(raw, &response); err != nil || status != http.StatusOK || response.Status != "duplicate" {
+		t.Fatalf("expected the event to be a duplicate, got %d %s", status, raw)
+	}
+	captured.ID = "evt_2"
+	if status, raw = s.webhook(captured, time.Now()); status != http.StatusOK {
+		t.Fatalf("expected a second capture event to be harmless, got %d %s", status, raw)
+	}
+	var reconciliation domain.PaymentReconciliation
+	s.expect(http.StatusOK, http.MethodGet, "/v1/order/"+strconv.Itoa(paid.ID)+"/ledger", f.admin, nil, &reconciliation)
+	if reconciliation.Captured != usd(2400) || len(reconciliation.Entries) != 2 || !reconciliation.Balanced {
+		t.Fatalf("expected the charge to be recorded once, got %+v", reconciliation)
+	}
+
+	// A failed payment cancels the unpaid order and puts its stock back
+	status, raw = s.webhook(domain.PaymentEvent{ID: "evt_3", Type: domain.PaymentEventFailed, TransactionID: unpaid.Payment.TransactionID}, time.Now())
+	if status != http.StatusOK {
+		t.Fatalf("expected the failure to be processed, got %d %s", status, raw)
+	}
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.StockLeft != 7 {
+		t.Fatalf("expected the unpaid burgers back in stock, got %d", product.StockLeft)
+	}
+	if status, _ = s.webhook(domain.PaymentEvent{ID: "evt_4", Type: "payment.disputed", TransactionID: "unknown"}, time.Now()); status != http.StatusOK {
+		t.Fatalf("expected unknown event types to be acknowledged, got %d", status)
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	// Couriers cannot sign themselves up, an admin provisions them
+	s.expectError(domain.InvalidRole, http.MethodPost, "/v1/create/user", "", domain.CreateUserRequest{
+		Email: "courier@quickbyte.com", PhoneNumber: "+15555555555", Password: testPassword, Name: "Courier", Role: domain.RoleCourier,
+	})
+	s.expectError(domain.Forbidden, http.MethodPost, "/v1/create/courier", f.owner, domain.CreateUserRequest{
+		Email: "courier@quickbyte.com", PhoneNumber: "+15555555555", Password: testPassword, Name: "Courier",
+	})
+	courier := s.courier(f.admin, "courier@quickbyte.com", "+15555555555")
+	otherCourier := s.courier(f.admin, "courier2@quickbyte.com", "+15555555556")
+	otherOwner := s.signup("other@quickbyte.com", "+13333333333", domain.RoleRestaurantOwner)
+	orderID := placeOrder(s, f)
+	statusPath := "/v1/order/" + strconv.Itoa(orderID) + "/status"
+	moveTo := func(token string, status domain.OrderStatus) domain.Order {
+		var order domain.Order
+		s.expect(http.StatusOK, http.MethodPost, statusPath, token, domain.UpdateOrderStatusRequest{Status: status}, &order)
+		if order.OrderStatus != status {
+			t.Fatalf("expected status %s, got %s", status, order.OrderStatus)
+		}
+		return order
+	}
+
+	s.expectError(domain.InvalidOrderStatus, http.MethodPost, statusPath, f.owner, domain.UpdateOrderStatusRequest{Status: "cooking"})
+	s.expectError(domain.OrderStatusConflict, http.MethodPost, statusPath, f.owner, domain.UpdateOrderStatusRequest{Status: domain.OrderReady})
+	s.expectError(domain.Forbidden, http.MethodPost, statusPath, f.customer, domain.UpdateOrderStatusRequest{Status: domain.OrderAccepted})
+	s.expectError(domain.OrderNotFound, http.MethodPost, statusPath, otherOwner, domain.UpdateOrderStatusRequest{Status: domain.OrderAccepted})
+	s.expectError(domain.OrderNotFound, http.MethodPost, "/v1/order/999/status", f.owner, domain.UpdateOrderStatusRequest{Status: domain.OrderAccepted})
+
+	moveTo(f.owner, domain.OrderAccepted)
+	moveTo(f.owner, domain.OrderPreparing)
+	s.expectError(domain.Forbidden, http.MethodPost, statusPath, courier, domain.UpdateOrderStatusRequest{Status: domain.OrderReady})
+	moveTo(f.owner, domain.OrderReady)
+
+	// Until a courier picks the order up, couriers only get its pickup view
+	courierClaims, err := s.usecase.VerifyToken(courier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.usecase.GetOrderStatusHistory(courierClaims, orderID); !errors.Is(err, domain.OrderNotFound) {
+		t.Fatalf("expected the history to be hidden from unassigned couriers, got %v", err)
+	}
+	s.expectError(domain.OrderNotFound, http.MethodGet, "/v1/order/"+strconv.Itoa(orderID)+"/payment", courier, nil)
+	s.expectError(domain.Forbidden, http.MethodPost, statusPath, f.owner, domain.UpdateOrderStatusRequest{Status: domain.OrderPickedUp})
+	order := moveTo(courier, domain.OrderPickedUp)
+	if order.CourierID == nil || *order.CourierID != userIDOf(s, "courier@quickbyte.com") {
+		t.Fatalf("expected the courier to be recorded, got %+v", order.CourierID)
+	}
+
+	// Only the courier who picked the order up can deliver it
+	s.expectError(domain.OrderNotFound, http.MethodPost, statusPath, otherCourier, domain.UpdateOrderStatusRequest{Status: domain.OrderDelivered})
+	order = moveTo(courier, domain.OrderDelivered)
+	if !order.IsDelivered {
+		t.Fatal("expected a delivered order to be marked as delivered")
+	}
+	s.expectError(domain.CancellationRefused, http.MethodPost, statusPath, f.customer, domain.UpdateOrderStatusRequest{Status: domain.OrderCancelled})
+
+	var history []domain.OrderStatusChange
+	s.expect(http.StatusOK, http.MethodGet, "/v1/order/"+strconv.Itoa(orderID)+"/history", f.customer, nil, &history)
+	expected := []domain.OrderStatus{domain.OrderPaymentPending, domain.OrderPlaced, domain.OrderAccepted, domain.OrderPreparing, domain.OrderReady, domain.OrderPickedUp, domain.OrderDelivered}
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d history entries, got %+v", len(expected), history)
+	}
+	for i, change := range history {
+		if change.ToStatus != expected[i] || change.ChangedBy == nil {
+			t.Fatalf("unexpected history entry %d: %+v", i, change)
+		}
+		if i > 0 && change.FromStatus != expected[i-1] {
+			t.Fatalf("unexpected history entry %d: %+v", i, change)
+		}
+	}
+	if history[2].ChangedByRole != domain.RoleRestaurantOwner || history[5].ChangedByRole != domain.RoleCourier {
+		t.Fatalf("expected the roles of who changed the status, got %+v", history)
+	}
+	s.expectError(domain.OrderNotFound, http.MethodGet, "/v1/order/"+strconv.Itoa(orderID)+"/history", otherOwner, nil)
+
+	// Admins can refund a delivered order
+	moveTo(f.admin, domain.OrderRefunded)
+}
+
+func TestRejectedOrderReleasesStock(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderID := placeOrder(s, f)
+
+	var order domain.Order
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(orderID)+"/status", f.owner, domain.UpdateOrderStatusRequest{
+		Status: domain.OrderRejected, Reason: "kitchen closed",
+	}, &order)
+
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.StockLeft != 10 {
+		t.Fatalf("expected the burger back in stock, got %d", product.StockLeft)
+	}
+	var history []domain.OrderStatusChange
+	s.expect(http.StatusOK, http.MethodGet, "/v1/order/"+strconv.Itoa(orderID)+"/history", f.owner, nil, &history)
+	if len(history) != 3 || history[2].Reason != "kitchen closed" {
+		t.Fatalf("expected the rejection reason in the history, got %+v", history)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderID := placeOrder(s, f)
+	cancelPath := "/v1/order/" + strconv.Itoa(orderID) + "/cancel"
+
+	other := s.signup("other@quickbyte.com", "+13333333333", domain.RoleCustomer)
+	s.expectError(domain.OrderNotFound, http.MethodPost, cancelPath, other, nil)
+	s.expectError(domain.Forbidden, http.MethodPost, cancelPath, f.owner, nil)
+
+	var order domain.Order
+	s.expect(http.StatusOK, http.MethodPost, cancelPath, f.customer, domain.CancelOrderRequest{Reason: "ordered by mistake"}, &order)
+	if order.OrderStatus != domain.OrderCancelled {
+		t.Fatalf("expected the order to be cancelled, got %s", order.OrderStatus)
+	}
+	var product domain.Product
+	s.expect(http.StatusOK, http.MethodGet, "/v1/product/"+strconv.Itoa(f.burgerID), "", nil, &product)
+	if product.StockLeft != 10 {
+		t.Fatalf("expected the burger back in stock, got %d", product.StockLeft)
+	}
+
+	var refused domain.ResponseError
+	s.expect(domain.CancellationRefused.Status, http.MethodPost, cancelPath, f.customer, nil, &refused)
+	if refused.ErrorCode != domain.CancellationRefused.ErrorCode || refused.ErrorDescription != "order is already cancelled" {
+		t.Fatalf("unexpected refusal %+v", refused)
+	}
+
+	// Cancelling an unpaid order drops its authorization at the gateway
+	var unpaid domain.Order
+	s.expect(http.StatusCreated, http.MethodPost, "/v1/hotel/"+strconv.Itoa(f.hotelID)+"/create/order", f.customer, domain.CreateOrderRequest{
+		Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 1}},
+	}, &unpaid)
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(unpaid.ID)+"/cancel", f.customer, nil, nil)
+	if open := s.gateway.OpenAuthorizations(); open != 0 {
+		t.Fatalf("expected the authorization of the cancelled order to be voided, %d still open", open)
+	}
+
+	// Once the restaurant accepts the order the customer can no longer cancel it
+	orderID = placeOrder(s, f)
+	s.expect(http.StatusOK, http.MethodPost, "/v1/order/"+strconv.Itoa(orderID)+"/status", f.owner, domain.UpdateOrderStatusRequest{Status: domain.OrderAccepted}, nil)
+	s.expect(domain.CancellationRefused.Status, http.MethodPost, "/v1/order/"+strconv.Itoa(orderID)+"/cancel", f.customer, nil, &refused)
+	if refused.ErrorDescription != "order is already accepted, orders can only be cancelled until the restaurant accepts them" {
+		t.Fatalf("unexpected refusal %+v", refused)
+	}
+}
+
+func TestRejectOrder(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderID := placeOrder(s, f)
+	rejectPath := "/v1/order/" + strconv.Itoa(orderID) + "/reject"
+
+	s.expectError(domain.Forbidden, http.MethodPost, rejectPath, f.customer, domain.CancelOrderRequest{Reason: "no"})
+	s.expectError(domain.RejectionReasonRequired, http.MethodPost, rejectPath, f.owner, nil)
+	s.expectError(domain.RejectionReasonRequired, http.MethodPost, rejectPath, f.owner, domain.CancelOrderRequest{Reason: "  "})
+
+	var order domain.Order
+	s.expect(http.StatusOK, http.MethodPost, rejectPath, f.owner, domain.CancelOrderRequest{Reason: "out of buns"}, &order)
+	if order.OrderStatus != domain.OrderRejected {
+		t.Fatalf("expected the order to be rejected, got %s", order.OrderStatus)
+	}
+	s.expectError(domain.OrderStatusConflict, http.MethodPost, rejectPath, f.owner, domain.CancelOrderRequest{Reason: "again"})
+}
+
+func TestIdempotentOrderCreation(t *testing.T) {
+	s := newTestServer(t)
+	f := newStoreFixture(s)
+	orderPath := "/v1/hotel/" + strconv.Itoa(f.hotelID) + "/create/order"
+	withKey := func(key string) http.Header {
+		return http.Header{domain.IdempotencyKeyHeader: {key}}
+	}
+	request := domain.CreateOrderRequest{Products: []domain.OrderProductRequest{{ProductID: f.burgerID, Quantity: 2}}}
+
+	first, firstBody := s.send(http.MethodPost, orderPath, f.customer, withKey("order-1"), request)
+	retry, retryBody := s.send(http.MethodPost, orderPath, f.customer, withKey("order-1"), request)
+	if first.StatusCode != http.StatusCreated || retry.StatusCode != http.StatusCreated {
+		t.Fatalf("expected both requests to succeed, got %d and %d", first.StatusCode, retry.StatusCode)
+	}
+	if !bytes.Equal(firstBody, retryBody) || retry.Header.Get(mcddelivery.IdempotentReplayedHeader) != "true" {
+		t.Fatalf("expected the first response to be replayed, got %s and %s", firstBody, retryBody)
+	}
+	if first.Header.Get(mcddelivery.IdempotentReplayedHeader) != "" {
+		t.Fatal("the first response must not be marked as replayed")
+	}
+
+	// The same key cannot be reused for another request
+	other := request
+	other.Products = []domain.OrderProductRequest{{ProductID: f.friesID, Quantity: 1}}
+	res, raw := s.send(http.MethodPost, orderPath, f.customer, withKey("order-1"), other)
+	if res.StatusCode != domain.IdempotencyKeyReused.Status || !bytes.Contains(raw, []byte(domain.IdempotencyKeyReused.ErrorCode)) {
+		t.Fatalf("expected the reused key to be refused, got %d %s", res.StatusCode, raw)
+	}
+
+	// Keys belong to the user that sent them, and requests without a key are never deduplicated
+	another := s.signup("another@quickbyte.com", "+13333333333", domain.RoleCustomer)
+	res, _ = s.send(http.MethodPost, orderPath, another, withKey("order-1"), request)
+	if res.StatusCode != http.StatusCreated || res.Header.Get(mcddelivery.IdempotentReplayedHeader) != "" {
+		t.Fatalf("expected another user's key to place a new order, got %d", res.StatusCode)
+	}
+	s.expect(http.StatusCreated, http.MethodPost, orderPath, f.customer, request, nil)
+
+	var orders domain.Page[domain.OrderResponse]
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/orders", f.customer, nil, &orders)
+	if len(orders.Items) != 2 {
+		t.Fatalf("expected the retry not to place an order, got %d orders", len(orders.Items))
+	}
+}
+
+func TestIdempotencyKeysExpire(t *testing.T) {
+	s := newTestServer(t, func(options *mcdusecase.Options) { options.IdempotencyKeyTTL = time.Nanosecond })
+	f := newStoreFixture(s)
+	header := http.Header{domain.IdempotencyKeyHeader: {"cart-1"}}
+	add := domain.CartProducts{ProductID: f.burgerID, Quantity: 1}
+
+	s.send(http.MethodPost, "/v1/add/user/cart", f.customer, header, add)
+	res, _ := s.send(http.MethodPost, "/v1/add/user/cart", f.customer, header, add)
+	if res.Header.Get(mcddelivery.IdempotentReplayedHeader) != "" {
+		t.Fatal("an expired key must not be replayed")
+	}
+	cart, err := s.repository.GetUserCart(f.customerID)
+	if err != nil || len(cart) != 2 {
+		t.Fatalf("expected both additions once the key expired, got %+v, %v", cart, err)
+	}
+
+	if purged, err := s.usecase.PurgeExpiredIdempotencyKeys(); err != nil || purged != 1 {
+		t.Fatalf("expected 1 purged key, got %d, %v", purged, err)
+	}
+}
+
+func TestIdempotencyKeysOfCrashedRequestsAreTakenOver(t *testing.T) {
+	lease := 50 * time.Millisecond
+	s := newTestServer(t, func(options *mcdusecase.Options) { options.IdempotencyLease = lease })
+	s.signup("jane@quickbyte.com", "+15555555555", "")
+	userID := userIDOf(s, "jane@quickbyte.com")
+
+	// The first request never completes, its retries are refused while its lease lasts
+	first, replay, err := s.usecase.BeginIdempotentRequest(userID, "crashed", "hash")
+	if err != nil || replay {
+		t.Fatalf("expected the key to be claimed, got %v, %v", replay, err)
+	}
+	if _, _, err := s.usecase.BeginIdempotentRequest(userID, "crashed", "hash"); !errors.Is(err, domain.IdempotencyKeyInFlight) {
+		t.Fatalf("expected the key to be in flight, got %v", err)
+	}
+
+	// Once the lease ended a retry takes the key over, and then holds it for its own lease
+	time.Sleep(2 * lease)
+	retry, replay, err := s.usecase.BeginIdempotentRequest(userID, "crashed", "hash")
+	if err != nil || replay || retry.ID != first.ID {
+		t.Fatalf("expected the retry to take over record %d, got %+v, %v, %v", first.ID, retry, replay, err)
+	}
+	if _, _, err := s.usecase.BeginIdempotentRequest(userID, "crashed", "hash"); !errors.Is(err, domain.IdempotencyKeyInFlight) {
+		t.Fatalf("expected the taken over key to be in flight, got %v", err)
+	}
+	if _, _, err := s.usecase.BeginIdempotentRequest(userID, "crashed", "other"); !errors.Is(err, domain.IdempotencyKeyReused) {
+		t.Fatalf("expected another request to be refused the key, got %v", err)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("jane@quickbyte.com", "+15555555555", "")
+	first := s.login("jane@quickbyte.com", testPassword)
+
+	var rotated domain.LoginResponse
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/token/refresh", "", domain.RefreshTokenRequest{RefreshToken: first.RefreshToken}, &rotated)
+	if rotated.Token == "" || rotated.RefreshToken == "" || rotated.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected a rotated refresh token, got %+v", rotated)
+	}
+
+	// Reusing the first token revokes the whole family, including the rotated token
+	s.expectError(domain.RefreshTokenReused, http.MethodPost, "/v1/user/token/refresh", "", domain.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	s.expectError(domain.RefreshTokenReused, http.MethodPost, "/v1/user/token/refresh", "", domain.RefreshTokenRequest{RefreshToken: rotated.RefreshToken})
+	s.expectError(domain.InvalidRefreshToken, http.MethodPost, "/v1/user/token/refresh", "", domain.RefreshTokenRequest{RefreshToken: "unknown"})
+
+	// Logout revokes every session of the user
+	session := s.login("jane@quickbyte.com", testPassword)
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/logout", session.Token, nil, nil)
+	s.expectError(domain.RefreshTokenReused, http.MethodPost, "/v1/user/token/refresh", "", domain.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+}
+
+// codePattern matches the OTP or reset token in a notification body, which is followed by a full stop
+var codePattern = regexp.MustCompile(`(?:is|:) ([A-Za-z0-9_-]+)\.`)
+
+func codeFrom(t *testing.T, notification domain.Notification) string {
+	t.Helper()
+	match := codePattern.FindStringSubmatch(notification.Body)
+	if match == nil {
+		t.Fatalf("no code in notification %q", notification.Body)
+	}
+	return match[1]
+}
+
+func TestOTPVerification(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("jane@quickbyte.com", "+15555555555", "")
+
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/otp/send", "", domain.SendOTPRequest{Email: "jane@quickbyte.com"}, nil)
+	notification := s.notifier.last(t)
+	if notification.Channel != domain.ChannelEmail || notification.To != "jane@quickbyte.com" {
+		t.Fatalf("unexpected notification %+v", notification)
+	}
+	s.expectError(domain.OTPResendTooSoon, http.MethodPost, "/v1/user/otp/send", "", domain.SendOTPRequest{Email: "jane@quickbyte.com"})
+
+	s.expectError(domain.InvalidOTP, http.MethodPost, "/v1/user/otp/verify", "", domain.VerifyOTPRequest{Email: "jane@quickbyte.com", Code: "000000x"})
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/otp/verify", "", domain.VerifyOTPRequest{Email: "jane@quickbyte.com", Code: codeFrom(t, notification)}, nil)
+
+	var user domain.UserResponse
+	s.expect(http.StatusOK, http.MethodGet, "/v1/user/"+strconv.Itoa(userIDOf(s, "jane@quickbyte.com")), s.login("jane@quickbyte.com", testPassword).Token, nil, &user)
+	if !user.IsVerified {
+		t.Fatal("expected the user to be verified")
+	}
+	s.expectError(domain.UserAlreadyVerified, http.MethodPost, "/v1/user/otp/send", "", domain.SendOTPRequest{Email: "jane@quickbyte.com"})
+}
+
+func TestOTPAttemptsAreLimitedUnderConcurrency(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("jane@quickbyte.com", "+15555555555", "")
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/otp/send", "", domain.SendOTPRequest{Email: "jane@quickbyte.com"}, nil)
+	code := codeFrom(t, s.notifier.last(t))
+	otp, err := s.repository.GetLatestOTP(userIDOf(s, "jane@quickbyte.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent wrong guesses get no more tries than MaxAttempts between them
+	results := make(chan error, 3*otp.MaxAttempts)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- s.usecase.VerifyOTP(domain.VerifyOTPRequest{Email: "jane@quickbyte.com", Code: "wrong"})
+		}()
+	}
+	wg.Wait()
+	close(results)
+	compared := 0
+	for err := range results {
+		if errors.Is(err, domain.InvalidOTP) {
+			compared++
+		} else if !errors.Is(err, domain.OTPAttemptsExceeded) {
+			t.Fatalf("unexpected verification error %v", err)
+		}
+	}
+	if compared != otp.MaxAttempts {
+		t.Fatalf("expected %d guesses to be compared, got %d", otp.MaxAttempts, compared)
+	}
+	s.expectError(domain.OTPAttemptsExceeded, http.MethodPost, "/v1/user/otp/verify", "", domain.VerifyOTPRequest{Email: "jane@quickbyte.com", Code: code})
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("jane@quickbyte.com", "+15555555555", "")
+	session := s.login("jane@quickbyte.com", testPassword)
+
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/password/reset/request", "", domain.PasswordResetRequest{Email: "jane@quickbyte.com"}, nil)
+	token := codeFrom(t, s.notifier.last(t))
+
+	s.expectError(domain.WeakPassword, http.MethodPost, "/v1/user/password/reset/confirm", "", domain.PasswordResetConfirm{Token: token, NewPassword: "short"})
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/password/reset/confirm", "", domain.PasswordResetConfirm{Token: token, NewPassword: "n3wpassword"}, nil)
+	s.expectError(domain.InvalidResetToken, http.MethodPost, "/v1/user/password/reset/confirm", "", domain.PasswordResetConfirm{Token: token, NewPassword: "an0therpassword"})
+
+	s.expectError(domain.InvalidCredentials, http.MethodPost, "/v1/user/login", "", domain.UserLogin{Email: "jane@quickbyte.com", Password: testPassword})
+	s.login("jane@quickbyte.com", "n3wpassword")
+	s.expectError(domain.RefreshTokenReused, http.MethodPost, "/v1/user/token/refresh", "", domain.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+
+	// Unknown emails get the same answer so accounts cannot be enumerated
+	s.expect(http.StatusOK, http.MethodPost, "/v1/user/password/reset/request", "", domain.PasswordResetRequest{Email: "nobody@quickbyte.com"}, nil)
+}
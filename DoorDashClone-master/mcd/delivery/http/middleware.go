@@ -0,0 +1,91 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	domain "mcd/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Context keys populated by the authenticate middleware
+const (
+	userIDContextKey = "user_id"
+	roleContextKey   = "role"
+)
+
+// authenticate validates the bearer token on the request and injects the
+// caller's user_id and role into the echo context.
+func (delivery *delivery) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(context echo.Context) error {
+		header := context.Request().Header.Get(echo.HeaderAuthorization)
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || strings.TrimSpace(tokenString) == "" {
+			return respondError(context, domain.MissingToken)
+		}
+
+		claims, err := delivery.MCDUsecase.VerifyToken(strings.TrimSpace(tokenString))
+		if err != nil {
+			return respondError(context, err)
+		}
+
+		context.Set(userIDContextKey, claims.UserID)
+		context.Set(roleContextKey, claims.Role)
+		return next(context)
+	}
+}
+
+// RoleCheckMiddleware only lets through authenticated callers with the given role.
+// It must be registered after the authenticate middleware.
+func RoleCheckMiddleware(role domain.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			if authenticatedRole(context) != role {
+				return respondError(context, domain.Forbidden)
+			}
+			return next(context)
+		}
+	}
+}
+
+// RequirePermission only lets through authenticated callers whose role grants the permission.
+// It must be registered after the authenticate middleware.
+func RequirePermission(permission domain.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			if !authenticatedRole(context).HasPermission(permission) {
+				return respondError(context, domain.Forbidden)
+			}
+			return next(context)
+		}
+	}
+}
+
+// authenticatedUserID returns the user id injected by the authenticate middleware
+func authenticatedUserID(context echo.Context) int {
+	userID, _ := context.Get(userIDContextKey).(int)
+	return userID
+}
+
+// authenticatedRole returns the role injected by the authenticate middleware
+func authenticatedRole(context echo.Context) domain.Role {
+	role, _ := context.Get(roleContextKey).(domain.Role)
+	return role
+}
+
+// authenticatedActor returns the caller identity used for per-resource checks in the usecase
+func authenticatedActor(context echo.Context) domain.AuthClaims {
+	return domain.AuthClaims{UserID: authenticatedUserID(context), Role: authenticatedRole(context)}
+}
+
+// respondError writes a domain.ResponseError with its own status code, and any
+// other error as an internal server error.
+func respondError(context echo.Context, err error) error {
+	var responseError domain.ResponseError
+	if errors.As(err, &responseError) {
+		return context.JSON(responseError.Status, responseError)
+	}
+	return context.JSON(http.StatusInternalServerError, err.Error())
+}
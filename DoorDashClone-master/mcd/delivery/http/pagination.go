@@ -0,0 +1,54 @@
+package http
+
+import (
+	"strconv"
+	"time"
+
+	domain "mcd/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// pageRequest reads the ?cursor=, ?limit= and ?sort= parameters shared by list endpoints
+func pageRequest(context echo.Context) (domain.PageRequest, error) {
+	request := domain.PageRequest{
+		Cursor: context.QueryParam("cursor"),
+		Sort:   context.QueryParam("sort"),
+	}
+	if limit := context.QueryParam("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 0 {
+			return request, domain.InvalidListQuery
+		}
+		request.Limit = parsed
+	}
+	return request, nil
+}
+
+// amountParam reads an optional amount in minor units
+func amountParam(context echo.Context, name string) (*int64, error) {
+	value := context.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, domain.InvalidListQuery
+	}
+	return &amount, nil
+}
+
+// timeParam reads an optional RFC 3339 time, or a YYYY-MM-DD date meaning its start in UTC
+func timeParam(context echo.Context, name string) (*time.Time, error) {
+	value := context.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if parsed, err = time.Parse(time.DateOnly, value); err != nil {
+			return nil, domain.InvalidListQuery
+		}
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"mcd/domain"
+	"os"
+	"sync"
+	"time"
+)
+
+type fileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier returns a notifier that appends every notification as a JSON line to a file,
+// so codes can be read back during local development and tests
+func NewFileNotifier(path string) domain.Notifier {
+	return &fileNotifier{path: path}
+}
+
+func (n *fileNotifier) Send(notification domain.Notification) error {
+	line, err := json.Marshal(struct {
+		domain.Notification
+		SentAt time.Time `json:"sent_at"`
+	}{notification, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
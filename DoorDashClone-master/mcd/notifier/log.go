@@ -0,0 +1,18 @@
+package notifier
+
+import (
+	"log"
+	"mcd/domain"
+)
+
+type logNotifier struct{}
+
+// NewLogNotifier returns a notifier that only writes notifications to the log, for local development
+func NewLogNotifier() domain.Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Send(notification domain.Notification) error {
+	log.Printf("[%s] to=%s subject=%q body=%q", notification.Channel, notification.To, notification.Subject, notification.Body)
+	return nil
+}
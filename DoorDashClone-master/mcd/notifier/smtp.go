@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"fmt"
+	"mcd/domain"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPConfig holds the mail server settings of the SMTP notifier
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type smtpNotifier struct {
+	config SMTPConfig
+}
+
+// NewSMTPNotifier returns a notifier that delivers email notifications through an SMTP server
+func NewSMTPNotifier(config SMTPConfig) domain.Notifier {
+	return &smtpNotifier{config: config}
+}
+
+func (n *smtpNotifier) Send(notification domain.Notification) error {
+	if notification.Channel != domain.ChannelEmail {
+		return fmt.Errorf("smtp notifier cannot deliver %s notifications", notification.Channel)
+	}
+
+	message := strings.Join([]string{
+		"From: " + n.config.From,
+		"To: " + notification.To,
+		"Subject: " + notification.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		notification.Body,
+	}, "\r\n")
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+	addr := net.JoinHostPort(n.config.Host, n.config.Port)
+	if err := smtp.SendMail(addr, auth, n.config.From, []string{notification.To}, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package payment
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mcd/domain"
+	"sync"
+	"time"
+)
+
+// fakeTransaction is what the fake gateway remembers about a transaction
+type fakeTransaction struct {
+	currency   string
+	authorized int64
+	captured   int64
+	refunded   int64
+	refunds    map[string]bool // References of the refunds made
+	voided     bool
+}
+
+// FakeGateway is an in-process payment gateway for local development and tests. It approves
+// every request unless told to decline, and checks amounts the way a real provider would:
+// nothing can be captured beyond the authorization or refunded beyond the capture.
+type FakeGateway struct {
+	mu               sync.Mutex
+	webhookSecrets   [][]byte
+	webhookTolerance time.Duration
+	declining        bool
+	transactions     map[string]*fakeTransaction
+}
+
+// NewFakeGateway returns a gateway that accepts webhooks signed with any of webhookSecrets
+// by SignWebhook and sent at most webhookTolerance ago, domain.DefaultWebhookTolerance if it is 0
+func NewFakeGateway(webhookSecrets [][]byte, webhookTolerance time.Duration) *FakeGateway {
+	if webhookTolerance <= 0 {
+		webhookTolerance = domain.DefaultWebhookTolerance
+	}
+	return &FakeGateway{
+		webhookSecrets:   webhookSecrets,
+		webhookTolerance: webhookTolerance,
+		transactions:     make(map[string]*fakeTransaction),
+	}
+}
+
+// SetDeclining makes every following authorization, capture and refund fail with domain.PaymentDeclined
+func (g *FakeGateway) SetDeclining(declining bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.declining = declining
+}
+
+func (g *FakeGateway) Authorize(intent domain.PaymentIntent) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.declining || intent.Amount.Amount <= 0 || !domain.ValidCurrency(intent.Amount.Currency) {
+		return "", domain.PaymentDeclined
+	}
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	transactionID := "fake_" + hex.EncodeToString(buf)
+	g.transactions[transactionID] = &fakeTransaction{
+		currency:   intent.Amount.Currency,
+		authorized: intent.Amount.Amount,
+		refunds:    make(map[string]bool),
+	}
+	return transactionID, nil
+}
+
+func (g *FakeGateway) Void(transactionID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	transaction, ok := g.transactions[transactionID]
+	if !ok {
+		return fmt.Errorf("unknown transaction %q", transactionID)
+	}
+	if transaction.captured > 0 {
+		return domain.PaymentDeclined
+	}
+	transaction.voided = true
+	return nil
+}
+
+// OpenAuthorizations counts the authorizations still holding money, neither captured nor voided
+func (g *FakeGateway) OpenAuthorizations() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	open := 0
+	for _, transaction := range g.transactions {
+		if !transaction.voided && transaction.captured == 0 {
+			open++
+		}
+	}
+	return open
+}
+
+func (g *FakeGateway) Capture(transactionID string, amount domain.Money) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	transaction, ok := g.transactions[transactionID]
+	if !ok {
+		return fmt.Errorf("unknown transaction %q", transactionID)
+	}
+	if g.declining || transaction.voided || amount.Amount <= 0 || amount.Currency != transaction.currency ||
+		transaction.captured+amount.Amount > transaction.authorized {
+		return domain.PaymentDeclined
+	}
+	transaction.captured += amount.Amount
+	return nil
+}
+
+func (g *FakeGateway) Refund(transactionID, reference string, amount domain.Money) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	transaction, ok := g.transactions[transactionID]
+	if !ok {
+		return fmt.Errorf("unknown transaction %q", transactionID)
+	}
+	if transaction.refunds[reference] {
+		return nil
+	}
+	if g.declining || amount.Amount <= 0 || amount.Currency != transaction.currency ||
+		transaction.refunded+amount.Amount > transaction.captured {
+		return domain.PaymentDeclined
+	}
+	transaction.refunded += amount.Amount
+	transaction.refunds[reference] = true
+	return nil
+}
+
+func (g *FakeGateway) VerifyWebhook(payload []byte, signature string) (domain.PaymentEvent, error) {
+	var event domain.PaymentEvent
+	if err := VerifyWebhookSignature(g.webhookSecrets, payload, signature, time.Now(), g.webhookTolerance); err != nil {
+		return event, err
+	}
+	if err := json.Unmarshal(payload, &event); err != nil || event.ID == "" || event.Type == "" {
+		return event, domain.InvalidWebhookPayload
+	}
+	return event, nil
+}
@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"mcd/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhookMAC returns the hex HMAC-SHA256 of "<timestamp>.<payload>"
+func webhookMAC(secret, payload []byte, timestamp string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignWebhook returns the Payment-Signature header for a payload sent at timestamp, in the form
+// "t=<unix seconds>,v1=<signature>". Tests and the webhooksign command use it to build webhooks.
+func SignWebhook(secret, payload []byte, timestamp time.Time) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + webhookMAC(secret, payload, unix)
+}
+
+// VerifyWebhookSignature checks the Payment-Signature header of a payload. The timestamp is
+// signed with the payload, so a captured webhook cannot be replayed once it is older than
+// tolerance. While the secret is being rotated the header may carry several v1 signatures and
+// secrets lists both the new and the old secret, a signature made with any of them is accepted.
+func VerifyWebhookSignature(secrets [][]byte, payload []byte, header string, now time.Time, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return domain.InvalidWebhookSignature
+	}
+
+	valid := false
+	for _, secret := range secrets {
+		expected := []byte(webhookMAC(secret, payload, timestamp))
+		for _, signature := range signatures {
+			valid = valid || hmac.Equal(expected, []byte(signature))
+		}
+	}
+	if !valid {
+		return domain.InvalidWebhookSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return domain.WebhookExpired
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"mcd/domain"
+)
+
+// CheckoutCart - Stores the order, reserves its stock and removes the checked out products
+// from the user's cart, leaving everything untouched when any product lacks stock
+func (r *repository) CheckoutCart(order domain.Order) (domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkStock(order.Products); err != nil {
+		return order, err
+	}
+	created, err := r.insertOrder(order)
+	if err != nil {
+		return order, err
+	}
+	r.reserveStock(created)
+
+	checkedOut := make(map[int]bool, len(order.Products))
+	for _, product := range order.Products {
+		checkedOut[product.ProductID] = true
+	}
+
+	kept := r.carts[:0]
+	for _, item := range r.carts {
+		if item.UserID != order.UserID || !checkedOut[item.ProductID] {
+			kept = append(kept, item)
+		}
+	}
+	r.carts = kept
+	return created, nil
+}
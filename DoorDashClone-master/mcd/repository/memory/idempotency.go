@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreateIdempotencyKey - Stores a new key, returns domain.IdempotencyKeyInFlight if the user already has it
+func (r *repository) CreateIdempotencyKey(record domain.IdempotencyKey) (domain.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.idempotencyKeys {
+		if existing.UserID == record.UserID && existing.Key == record.Key {
+			return record, domain.IdempotencyKeyInFlight
+		}
+	}
+	r.lastIdempotencyKeyID++
+	record.ID = r.lastIdempotencyKeyID
+	record.CreatedAt = time.Now()
+	r.idempotencyKeys[record.ID] = record
+	return record, nil
+}
+
+// GetIdempotencyKey - Fetches the user's record for the key
+func (r *repository) GetIdempotencyKey(userID int, key string) (domain.IdempotencyKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, record := range r.idempotencyKeys {
+		if record.UserID == userID && record.Key == key {
+			record.ResponseBody = append([]byte(nil), record.ResponseBody...)
+			return record, nil
+		}
+	}
+	return domain.IdempotencyKey{}, fmt.Errorf("failed to get idempotency key: %w", errNotFound)
+}
+
+// CompleteIdempotencyKey - Stores the response of the request made with the key
+func (r *repository) CompleteIdempotencyKey(recordID int, statusCode int, responseBody []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.idempotencyKeys[recordID]
+	if !ok {
+		return fmt.Errorf("failed to complete idempotency key: %w", errNotFound)
+	}
+	record.StatusCode = statusCode
+	record.ResponseBody = append([]byte(nil), responseBody...)
+	r.idempotencyKeys[recordID] = record
+	return nil
+}
+
+// TakeOverIdempotencyKey - Renews the lease of a key still in flight whose lease ended before now
+func (r *repository) TakeOverIdempotencyKey(recordID int, now time.Time, leaseExpiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.idempotencyKeys[recordID]
+	if !ok || record.StatusCode != 0 || !record.LeaseExpiresAt.Before(now) {
+		return false, nil
+	}
+	record.LeaseExpiresAt = leaseExpiresAt
+	r.idempotencyKeys[recordID] = record
+	return true, nil
+}
+
+// DeleteIdempotencyKey - Deletes a key so it can be claimed again
+func (r *repository) DeleteIdempotencyKey(recordID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.idempotencyKeys, recordID)
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys - Deletes every key that expired before now
+func (r *repository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, record := range r.idempotencyKeys {
+		if record.ExpiresAt.Before(now) {
+			delete(r.idempotencyKeys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
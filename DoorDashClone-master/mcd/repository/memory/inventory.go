@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"mcd/domain"
+	"time"
+)
+
+// checkStock reports domain.InsufficientStock if any product lacks the ordered quantity,
+// the caller must hold the write lock and call reserveStock before releasing it
+func (r *repository) checkStock(products []domain.OrderProduct) error {
+	for _, product := range products {
+		stored, ok := r.products[int32(product.ProductID)]
+		if !ok || stored.StockLeft < product.Quantity {
+			return domain.InsufficientStock
+		}
+	}
+	return nil
+}
+
+// reserveStock takes the ordered quantities out of the stock and records the reservations
+func (r *repository) reserveStock(order domain.Order) {
+	now := time.Now()
+	for _, product := range order.Products {
+		stored := r.products[int32(product.ProductID)]
+		stored.StockLeft -= product.Quantity
+		r.products[stored.ID] = stored
+
+		r.lastReservationID++
+		r.reservations[r.lastReservationID] = domain.StockReservation{
+			ID:        r.lastReservationID,
+			OrderID:   order.ID,
+			ProductID: product.ProductID,
+			Quantity:  product.Quantity,
+			Status:    domain.ReservationReserved,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+}
+
+// GetOrdersPastPaymentDue - Fetches the IDs of unpaid orders whose payment deadline has passed
+func (r *repository) GetOrdersPastPaymentDue(now time.Time) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orderIDs []int
+	for _, order := range r.orders {
+		if order.OrderStatus == domain.OrderPaymentPending && order.PaymentDueAt != nil && !order.PaymentDueAt.After(now) {
+			orderIDs = append(orderIDs, order.ID)
+		}
+	}
+	return orderIDs, nil
+}
+
+// CancelOrder - Applies the cancelled or rejected status change, puts the order's reserved stock
+// back, voids payments that were only authorized and marks captured payments for refund.
+func (r *repository) CancelOrder(change domain.OrderStatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.changeOrderStatus(change); err != nil {
+		return err
+	}
+
+	orderID := change.OrderID
+	now := time.Now()
+	for id, reservation := range r.reservations {
+		if reservation.OrderID != orderID || reservation.Status != domain.ReservationReserved {
+			continue
+		}
+		product := r.products[int32(reservation.ProductID)]
+		product.StockLeft += reservation.Quantity
+		r.products[product.ID] = product
+
+		reservation.Status = domain.ReservationReleased
+		reservation.UpdatedAt = now
+		r.reservations[id] = reservation
+	}
+	r.setPaymentStatus(orderID, domain.PaymentAuthorized, domain.PaymentVoidPending)
+	r.setPaymentStatus(orderID, domain.PaymentCaptured, domain.PaymentRefundPending)
+	return nil
+}
@@ -0,0 +1,611 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"mcd/domain"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errNotFound mirrors gorm.ErrRecordNotFound for lookups that match no row
+var errNotFound = errors.New("record not found")
+
+// repository keeps every table in process memory behind a single lock, so each
+// method is atomic in the same way as a MySQL transaction.
+type repository struct {
+	mu sync.RWMutex
+
+	users           map[int32]domain.User
+	products        map[int32]domain.Product
+	hotels          map[int16]domain.Hotel
+	carts           []domain.CartProducts
+	orders          map[int]domain.Order
+	refreshTokens   map[int]domain.RefreshToken
+	otps            map[int]domain.OTP
+	passwordResets  map[int]domain.PasswordReset
+	reservations    map[int]domain.StockReservation
+	statusHistory   []domain.OrderStatusChange
+	idempotencyKeys map[int]domain.IdempotencyKey
+	payments        map[int]domain.Payment
+	refunds         map[int]domain.Refund
+	ledger          []domain.LedgerEntry
+	webhookEvents   map[string]domain.WebhookEvent
+	openingHours    map[int][]domain.OpeningHours
+	closures        []domain.HotelClosure
+	menuSections    map[int]domain.MenuSection
+
+	lastUserID           int32
+	lastProductID        int32
+	lastHotelID          int16
+	lastOrderID          int
+	lastOrderProductID   int
+	lastRefreshTokenID   int
+	lastOTPID            int
+	lastPasswordResetID  int
+	lastReservationID    int
+	lastStatusChangeID   int
+	lastIdempotencyKeyID int
+	lastPaymentID        int
+	lastRefundID         int
+	lastRefundItemID     int
+	lastLedgerEntryID    int
+	lastWebhookEventID   int
+	lastOpeningHoursID   int
+	lastClosureID        int
+	lastMenuSectionID    int
+}
+
+// NewRepository returns an empty in-memory implementation of domain.MCDRepository
+// for running the API and its tests without MySQL. Nothing is persisted across restarts.
+func NewRepository() domain.MCDRepository {
+	return &repository{
+		users:           make(map[int32]domain.User),
+		products:        make(map[int32]domain.Product),
+		hotels:          make(map[int16]domain.Hotel),
+		orders:          make(map[int]domain.Order),
+		refreshTokens:   make(map[int]domain.RefreshToken),
+		otps:            make(map[int]domain.OTP),
+		passwordResets:  make(map[int]domain.PasswordReset),
+		reservations:    make(map[int]domain.StockReservation),
+		idempotencyKeys: make(map[int]domain.IdempotencyKey),
+		payments:        make(map[int]domain.Payment),
+		refunds:         make(map[int]domain.Refund),
+		webhookEvents:   make(map[string]domain.WebhookEvent),
+		openingHours:    make(map[int][]domain.OpeningHours),
+		menuSections:    make(map[int]domain.MenuSection),
+	}
+}
+
+// parseID converts the string ids used by the interface
+func parseID(id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q: %w", id, err)
+	}
+	return n, nil
+}
+
+// CreateUser - Adds a new user
+func (r *repository) CreateUser(user domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return domain.EmailAlreadyExists
+		}
+	}
+	r.lastUserID++
+	user.ID = r.lastUserID
+	r.users[user.ID] = user
+	return nil
+}
+
+// DeleteUser - Deletes a user by ID
+func (r *repository) DeleteUser(userID string) error {
+	id, err := parseID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, int32(id))
+	return nil
+}
+
+// UpdateUser - Updates the non-zero fields of an existing user, like gorm's Updates
+func (r *repository) UpdateUser(user domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok {
+		return nil
+	}
+	if user.Email != "" {
+		existing.Email = user.Email
+	}
+	if user.PhoneNumber != "" {
+		existing.PhoneNumber = user.PhoneNumber
+	}
+	if user.PasswordHash != "" {
+		existing.PasswordHash = user.PasswordHash
+	}
+	if user.Name != "" {
+		existing.Name = user.Name
+	}
+	if user.Role != "" {
+		existing.Role = user.Role
+	}
+	if user.IsVerified {
+		existing.IsVerified = true
+	}
+	r.users[user.ID] = existing
+	return nil
+}
+
+// GetUserById - Fetches a user by their ID
+func (r *repository) GetUserById(userID string) (domain.User, error) {
+	id, err := parseID(userID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to get user by id: %w", err)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[int32(id)]
+	if !ok {
+		return domain.User{}, fmt.Errorf("failed to get user by id: %w", errNotFound)
+	}
+	return user, nil
+}
+
+// GetUserByEmail - Fetches a user by their email
+func (r *repository) GetUserByEmail(email string) (domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return domain.User{}, fmt.Errorf("failed to get user by email: %w", errNotFound)
+}
+
+// EmailExists - Reports whether a user is registered with the email
+func (r *repository) EmailExists(email string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PhoneNumberExists - Reports whether a user is registered with the phone number
+func (r *repository) PhoneNumberExists(phoneNumber string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.PhoneNumber == phoneNumber {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateProduct - Adds a new product, the hotel must exist
+func (r *repository) CreateProduct(product domain.Product) (domain.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hotels[int16(product.HotelID)]; !ok {
+		return product, fmt.Errorf("failed to create product: hotel %d does not exist", product.HotelID)
+	}
+	r.lastProductID++
+	product.ID = r.lastProductID
+	r.products[product.ID] = product
+	return product, nil
+}
+
+// DeleteProduct - Deletes a product by ID
+func (r *repository) DeleteProduct(productID string) error {
+	id, err := parseID(productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.products, int32(id))
+	return nil
+}
+
+// UpdateProduct - Updates the non-zero fields of an existing product, like gorm's Updates
+func (r *repository) UpdateProduct(product domain.Product) (domain.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.products[product.ID]
+	if !ok {
+		return product, fmt.Errorf("failed to update product: %w", errNotFound)
+	}
+	if product.Name != "" {
+		existing.Name = product.Name
+	}
+	if product.StockLeft != 0 {
+		existing.StockLeft = product.StockLeft
+	}
+	if product.HotelID != 0 {
+		existing.HotelID = product.HotelID
+	}
+	if product.Category != "" {
+		existing.Category = product.Category
+	}
+	if !product.Price.IsZero() {
+		existing.Price = product.Price
+	}
+	if product.SectionID != nil {
+		existing.SectionID = product.SectionID
+	}
+	if product.Description != "" {
+		existing.Description = product.Description
+	}
+	if product.ImageURL != "" {
+		existing.ImageURL = product.ImageURL
+	}
+	if product.DietaryTags != nil {
+		existing.DietaryTags = product.DietaryTags
+	}
+	if product.Allergens != nil {
+		existing.Allergens = product.Allergens
+	}
+	if product.SpiceLevel != 0 {
+		existing.SpiceLevel = product.SpiceLevel
+	}
+	r.products[product.ID] = existing
+	return existing, nil
+}
+
+// GetProductById - Fetches a product by its ID
+func (r *repository) GetProductById(productID string) (domain.Product, error) {
+	id, err := parseID(productID)
+	if err != nil {
+		return domain.Product{}, fmt.Errorf("failed to get product by id: %w", err)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, ok := r.products[int32(id)]
+	if !ok {
+		return domain.Product{}, fmt.Errorf("failed to get product by id: %w", errNotFound)
+	}
+	return product, nil
+}
+
+// GetProductsByHotel - Fetches one page of a hotel's products matching the filters
+func (r *repository) GetProductsByHotel(query domain.ProductQuery) ([]domain.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var products []domain.Product
+	for _, product := range r.products {
+		if product.HotelID != query.HotelID {
+			continue
+		}
+		if query.Category != "" && !strings.EqualFold(product.Category, query.Category) {
+			continue
+		}
+		if (query.MinPrice != nil && product.Price.Amount < *query.MinPrice) ||
+			(query.MaxPrice != nil && product.Price.Amount > *query.MaxPrice) {
+			continue
+		}
+		if query.Section != nil && sectionOf(product) != *query.Section {
+			continue
+		}
+		products = append(products, product)
+	}
+	return paginate(products, query.ListOptions,
+		func(product domain.Product) int { return int(product.ID) },
+		func(product domain.Product, field string) any {
+			switch field {
+			case domain.SortByName:
+				return product.Name
+			case domain.SortByPrice:
+				return product.Price.Amount
+			}
+			return nil
+		})
+}
+
+// CreateHotel - Adds a new hotel, the owner must exist when set
+func (r *repository) CreateHotel(hotel domain.Hotel) (domain.Hotel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if hotel.OwnerID != nil {
+		if _, ok := r.users[int32(*hotel.OwnerID)]; !ok {
+			return hotel, fmt.Errorf("failed to create hotel: owner %d does not exist", *hotel.OwnerID)
+		}
+	}
+	if hotel.DeliveryRadiusKm == nil {
+		pickupOnly := 0.0
+		hotel.DeliveryRadiusKm = &pickupOnly
+	}
+	r.lastHotelID++
+	hotel.ID = r.lastHotelID
+	r.hotels[hotel.ID] = hotel
+	return hotel, nil
+}
+
+// DeleteHotel - Deletes a hotel by ID
+func (r *repository) DeleteHotel(hotelID string) error {
+	id, err := parseID(hotelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete hotel: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.hotels, int16(id))
+	return nil
+}
+
+// UpdateHotel - Updates the non-zero fields of an existing hotel, like gorm's Updates, where a
+// pointer to zero is not a zero field
+func (r *repository) UpdateHotel(hotel domain.Hotel) (domain.Hotel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.hotels[hotel.ID]
+	if !ok {
+		return hotel, fmt.Errorf("failed to update hotel: %w", errNotFound)
+	}
+	if hotel.Name != "" {
+		existing.Name = hotel.Name
+	}
+	if hotel.City != "" {
+		existing.City = hotel.City
+	}
+	if hotel.Address != "" {
+		existing.Address = hotel.Address
+	}
+	if hotel.State != "" {
+		existing.State = hotel.State
+	}
+	if hotel.OwnerID != nil {
+		existing.OwnerID = hotel.OwnerID
+	}
+	if hotel.Latitude != nil {
+		existing.Latitude = hotel.Latitude
+	}
+	if hotel.TimeZone != "" {
+		existing.TimeZone = hotel.TimeZone
+	}
+	if hotel.Longitude != nil {
+		existing.Longitude = hotel.Longitude
+	}
+	if hotel.DeliveryRadiusKm != nil {
+		existing.DeliveryRadiusKm = hotel.DeliveryRadiusKm
+	}
+	r.hotels[hotel.ID] = existing
+	return existing, nil
+}
+
+// GetHotels - Fetches one page of the hotels matching the filters
+func (r *repository) GetHotels(query domain.HotelQuery) ([]domain.Hotel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hotels := make([]domain.Hotel, 0, len(r.hotels))
+	for _, hotel := range r.hotels {
+		if (query.City == "" || strings.EqualFold(hotel.City, query.City)) &&
+			(query.State == "" || strings.EqualFold(hotel.State, query.State)) {
+			hotels = append(hotels, hotel)
+		}
+	}
+	return paginate(hotels, query.ListOptions,
+		func(hotel domain.Hotel) int { return int(hotel.ID) },
+		func(hotel domain.Hotel, field string) any {
+			if field == domain.SortByName {
+				return hotel.Name
+			}
+			return nil
+		})
+}
+
+// GetHotelsWithin - Fetches the hotels located inside the bounds
+func (r *repository) GetHotelsWithin(bounds domain.GeoBounds) ([]domain.Hotel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var hotels []domain.Hotel
+	for _, hotel := range r.hotels {
+		if hotel.Latitude == nil || hotel.Longitude == nil {
+			continue
+		}
+		if *hotel.Latitude >= bounds.MinLatitude && *hotel.Latitude <= bounds.MaxLatitude &&
+			*hotel.Longitude >= bounds.MinLongitude && *hotel.Longitude <= bounds.MaxLongitude {
+			hotels = append(hotels, hotel)
+		}
+	}
+	sort.Slice(hotels, func(i, j int) bool { return hotels[i].ID < hotels[j].ID })
+	return hotels, nil
+}
+
+// GetHotelByID - Fetches a hotel by its ID
+func (r *repository) GetHotelByID(hotelID int) (*domain.Hotel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hotel, ok := r.hotels[int16(hotelID)]
+	if !ok {
+		return nil, fmt.Errorf("failed to get hotel with ID %d: %w", hotelID, errNotFound)
+	}
+	return &hotel, nil
+}
+
+// AddProductToCart - Adds a cart row, the user and product must exist
+func (r *repository) AddProductToCart(cartProduct domain.CartProducts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[int32(cartProduct.UserID)]; !ok {
+		return fmt.Errorf("failed to add product to cart: user %d does not exist", cartProduct.UserID)
+	}
+	if _, ok := r.products[int32(cartProduct.ProductID)]; !ok {
+		return fmt.Errorf("failed to add product to cart: product %d does not exist", cartProduct.ProductID)
+	}
+	r.carts = append(r.carts, cartProduct)
+	return nil
+}
+
+// DeleteProductFromCart - Removes every cart row of the product for the user
+func (r *repository) DeleteProductFromCart(cartProduct domain.CartProducts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.carts[:0]
+	for _, item := range r.carts {
+		if item.UserID != cartProduct.UserID || item.ProductID != cartProduct.ProductID {
+			kept = append(kept, item)
+		}
+	}
+	r.carts = kept
+	return nil
+}
+
+// UpdateQuantityInCart - Sets the quantity of the product in the user's cart
+func (r *repository) UpdateQuantityInCart(cartProduct domain.CartProducts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, item := range r.carts {
+		if item.UserID == cartProduct.UserID && item.ProductID == cartProduct.ProductID {
+			r.carts[i].Quantity = cartProduct.Quantity
+		}
+	}
+	return nil
+}
+
+// GetUserCart - Fetches every cart row of the user
+func (r *repository) GetUserCart(userID int) ([]domain.CartProducts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var cart []domain.CartProducts
+	for _, item := range r.carts {
+		if item.UserID == userID {
+			cart = append(cart, item)
+		}
+	}
+	return cart, nil
+}
+
+// GetProductDetails - Fetches the products with the given IDs ordered by ID
+func (r *repository) GetProductDetails(productIDs []int) ([]domain.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var products []domain.Product
+	seen := make(map[int]bool)
+	for _, id := range productIDs {
+		product, ok := r.products[int32(id)]
+		if ok && !seen[id] {
+			seen[id] = true
+			products = append(products, product)
+		}
+	}
+	sortProducts(products)
+	return products, nil
+}
+
+// CreateOrder - Stores the order and its products, reserves their stock and returns it with the generated IDs
+func (r *repository) CreateOrder(order domain.Order) (domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkStock(order.Products); err != nil {
+		return order, err
+	}
+	created, err := r.insertOrder(order)
+	if err != nil {
+		return order, err
+	}
+	r.reserveStock(created)
+	return created, nil
+}
+
+// insertOrder stores the order, the caller must hold the write lock
+func (r *repository) insertOrder(order domain.Order) (domain.Order, error) {
+	if _, ok := r.users[int32(order.UserID)]; !ok {
+		return order, fmt.Errorf("failed to create order: user %d does not exist", order.UserID)
+	}
+	if _, ok := r.hotels[int16(order.HotelID)]; !ok {
+		return order, fmt.Errorf("failed to create order: hotel %d does not exist", order.HotelID)
+	}
+
+	r.lastOrderID++
+	order.ID = r.lastOrderID
+	now := time.Now()
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	if order.OrderStatus == "" {
+		order.OrderStatus = domain.OrderPaymentPending
+	}
+
+	products := make([]domain.OrderProduct, len(order.Products))
+	for i, product := range order.Products {
+		r.lastOrderProductID++
+		product.ID = r.lastOrderProductID
+		product.OrderID = order.ID
+		products[i] = product
+	}
+	order.Products = products
+	r.orders[order.ID] = order
+	r.recordStatusChange(domain.OrderStatusChange{OrderID: order.ID, ToStatus: order.OrderStatus, ChangedBy: &order.UserID})
+	return copyOrder(order), nil
+}
+
+// GetUserOrders - Fetches one page of a user's orders matching the filters, with their products
+func (r *repository) GetUserOrders(query domain.OrderQuery) ([]domain.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []domain.Order
+	for _, order := range r.orders {
+		if order.UserID != query.UserID || (query.Status != "" && order.OrderStatus != query.Status) {
+			continue
+		}
+		if (query.CreatedFrom != nil && order.CreatedAt.Before(*query.CreatedFrom)) ||
+			(query.CreatedBefore != nil && !order.CreatedAt.Before(*query.CreatedBefore)) {
+			continue
+		}
+		orders = append(orders, copyOrder(order))
+	}
+	return paginate(orders, query.ListOptions,
+		func(order domain.Order) int { return order.ID },
+		func(order domain.Order, field string) any {
+			if field == domain.SortByCreatedAt {
+				return order.CreatedAt
+			}
+			return nil
+		})
+}
+
+// copyOrder returns the order with its own copy of the products slice
+func copyOrder(order domain.Order) domain.Order {
+	order.Products = append([]domain.OrderProduct(nil), order.Products...)
+	return order
+}
+
+func sortProducts(products []domain.Product) {
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+}
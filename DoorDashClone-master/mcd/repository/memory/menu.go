@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"sort"
+)
+
+// sectionOf returns the menu section of a product, 0 when it has none
+func sectionOf(product domain.Product) int {
+	if product.SectionID == nil {
+		return 0
+	}
+	return *product.SectionID
+}
+
+// GetMenuSections - Fetches the menu sections of a hotel by position, then id
+func (r *repository) GetMenuSections(hotelID int) ([]domain.MenuSection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sections []domain.MenuSection
+	for _, section := range r.menuSections {
+		if section.HotelID == hotelID {
+			sections = append(sections, section)
+		}
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		if sections[i].Position != sections[j].Position {
+			return sections[i].Position < sections[j].Position
+		}
+		return sections[i].ID < sections[j].ID
+	})
+	return sections, nil
+}
+
+// CreateMenuSection - Adds a menu section to a hotel
+func (r *repository) CreateMenuSection(section domain.MenuSection) (domain.MenuSection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hotels[int16(section.HotelID)]; !ok {
+		return section, fmt.Errorf("failed to create menu section: hotel %d does not exist", section.HotelID)
+	}
+	r.lastMenuSectionID++
+	section.ID = r.lastMenuSectionID
+	section.Products = nil
+	r.menuSections[section.ID] = section
+	return section, nil
+}
+
+// UpdateMenuSection - Replaces the name, description and position of a menu section
+func (r *repository) UpdateMenuSection(section domain.MenuSection) (domain.MenuSection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.menuSections[section.ID]
+	if !ok {
+		return section, fmt.Errorf("failed to update menu section: %w", errNotFound)
+	}
+	existing.Name = section.Name
+	existing.Description = section.Description
+	existing.Position = section.Position
+	r.menuSections[section.ID] = existing
+	return existing, nil
+}
+
+// DeleteMenuSection - Deletes a menu section, its products move to no section
+func (r *repository) DeleteMenuSection(sectionID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.menuSections, sectionID)
+	for id, product := range r.products {
+		if product.SectionID != nil && *product.SectionID == sectionID {
+			product.SectionID = nil
+			r.products[id] = product
+		}
+	}
+	return nil
+}
+
+// SetProductAvailable - Takes a product off or back on sale
+func (r *repository) SetProductAvailable(productID int, available bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[int32(productID)]
+	if !ok {
+		return fmt.Errorf("failed to update product availability: %w", errNotFound)
+	}
+	product.Available = available
+	r.products[product.ID] = product
+	return nil
+}
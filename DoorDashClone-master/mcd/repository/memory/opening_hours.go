@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"sort"
+	"time"
+)
+
+// GetOpeningHours - Fetches the weekly opening hours of the hotels, by hotel id
+func (r *repository) GetOpeningHours(hotelIDs []int) (map[int][]domain.OpeningHours, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byHotel := make(map[int][]domain.OpeningHours, len(hotelIDs))
+	for _, hotelID := range hotelIDs {
+		if hours := r.openingHours[hotelID]; len(hours) > 0 {
+			byHotel[hotelID] = append([]domain.OpeningHours(nil), hours...)
+		}
+	}
+	return byHotel, nil
+}
+
+// ReplaceOpeningHours - Sets the hotel's time zone and replaces all its opening hours
+func (r *repository) ReplaceOpeningHours(hotelID int, timeZone string, hours []domain.OpeningHours) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hotel, ok := r.hotels[int16(hotelID)]
+	if !ok {
+		return fmt.Errorf("failed to replace opening hours: %w", errNotFound)
+	}
+	hotel.TimeZone = timeZone
+	r.hotels[hotel.ID] = hotel
+
+	replaced := make([]domain.OpeningHours, 0, len(hours))
+	for _, period := range hours {
+		r.lastOpeningHoursID++
+		period.ID = r.lastOpeningHoursID
+		period.HotelID = hotelID
+		replaced = append(replaced, period)
+	}
+	sort.SliceStable(replaced, func(i, j int) bool {
+		if replaced[i].Weekday != replaced[j].Weekday {
+			return replaced[i].Weekday < replaced[j].Weekday
+		}
+		return replaced[i].Opens < replaced[j].Opens
+	})
+	r.openingHours[hotelID] = replaced
+	return nil
+}
+
+// GetHotelClosures - Fetches the closures of the hotels that end after the given time, by hotel
+// id and earliest first
+func (r *repository) GetHotelClosures(hotelIDs []int, endingAfter time.Time) (map[int][]domain.HotelClosure, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[int]bool, len(hotelIDs))
+	for _, hotelID := range hotelIDs {
+		wanted[hotelID] = true
+	}
+	byHotel := make(map[int][]domain.HotelClosure, len(hotelIDs))
+	for _, closure := range r.closures {
+		if wanted[closure.HotelID] && closure.EndsAt.After(endingAfter) {
+			byHotel[closure.HotelID] = append(byHotel[closure.HotelID], closure)
+		}
+	}
+	for _, closures := range byHotel {
+		sort.Slice(closures, func(i, j int) bool { return closures[i].StartsAt.Before(closures[j].StartsAt) })
+	}
+	return byHotel, nil
+}
+
+// CreateHotelClosure - Stores a one-off closure of a hotel
+func (r *repository) CreateHotelClosure(closure domain.HotelClosure) (domain.HotelClosure, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.hotels[int16(closure.HotelID)]; !ok {
+		return closure, fmt.Errorf("failed to create hotel closure: hotel %d does not exist", closure.HotelID)
+	}
+	r.lastClosureID++
+	closure.ID = r.lastClosureID
+	closure.CreatedAt = time.Now()
+	r.closures = append(r.closures, closure)
+	return closure, nil
+}
+
+// SetHotelPaused - Pauses or resumes taking orders at a hotel
+func (r *repository) SetHotelPaused(hotelID int, paused bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hotel, ok := r.hotels[int16(hotelID)]
+	if !ok {
+		return fmt.Errorf("failed to update hotel pause: %w", errNotFound)
+	}
+	hotel.Paused = paused
+	r.hotels[hotel.ID] = hotel
+	return nil
+}
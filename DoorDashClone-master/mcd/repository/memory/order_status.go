@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// GetOrderByID - Fetches an order with its products
+func (r *repository) GetOrderByID(orderID int) (domain.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, ok := r.orders[orderID]
+	if !ok {
+		return order, fmt.Errorf("failed to get order: %w", errNotFound)
+	}
+	return copyOrder(order), nil
+}
+
+// changeOrderStatus moves the order from FromStatus to ToStatus and records the change,
+// the caller must hold the write lock
+func (r *repository) changeOrderStatus(change domain.OrderStatusChange) error {
+	order, ok := r.orders[change.OrderID]
+	if !ok || order.OrderStatus != change.FromStatus {
+		return domain.OrderStatusConflict
+	}
+	order.OrderStatus = change.ToStatus
+	order.UpdatedAt = time.Now()
+	switch change.ToStatus {
+	case domain.OrderPickedUp:
+		order.CourierID = change.ChangedBy
+	case domain.OrderDelivered:
+		order.IsDelivered = true
+	}
+	r.orders[order.ID] = order
+	r.recordStatusChange(change)
+	return nil
+}
+
+// recordStatusChange appends the change to the status history, the caller must hold the write lock
+func (r *repository) recordStatusChange(change domain.OrderStatusChange) {
+	r.lastStatusChangeID++
+	change.ID = r.lastStatusChangeID
+	change.CreatedAt = time.Now()
+	r.statusHistory = append(r.statusHistory, change)
+}
+
+// UpdateOrderStatus - Moves the order to a new status and records the change
+func (r *repository) UpdateOrderStatus(change domain.OrderStatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changeOrderStatus(change)
+}
+
+// GetOrderStatusHistory - Fetches every status change of the order, oldest first
+func (r *repository) GetOrderStatusHistory(orderID int) ([]domain.OrderStatusChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var history []domain.OrderStatusChange
+	for _, change := range r.statusHistory {
+		if change.OrderID == orderID {
+			history = append(history, change)
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreateOTP - Stores a new OTP and supersedes any OTP still pending for the user
+func (r *repository) CreateOTP(otp domain.OTP) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, pending := range r.otps {
+		if pending.UserID == otp.UserID && pending.ConsumedAt == nil {
+			pending.ConsumedAt = &now
+			r.otps[id] = pending
+		}
+	}
+	r.lastOTPID++
+	otp.ID = r.lastOTPID
+	otp.CreatedAt = now
+	r.otps[otp.ID] = otp
+	return nil
+}
+
+// GetLatestOTP - Fetches the most recently issued OTP of a user
+func (r *repository) GetLatestOTP(userID int) (domain.OTP, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest domain.OTP
+	for _, otp := range r.otps {
+		if otp.UserID == userID && otp.ID > latest.ID {
+			latest = otp
+		}
+	}
+	if latest.ID == 0 {
+		return latest, fmt.Errorf("failed to get latest otp: %w", errNotFound)
+	}
+	return latest, nil
+}
+
+// IncrementOTPAttempts - Records a verification attempt unless the attempts are used up
+func (r *repository) IncrementOTPAttempts(otpID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	otp, ok := r.otps[otpID]
+	if !ok || otp.Attempts >= otp.MaxAttempts {
+		return domain.OTPAttemptsExceeded
+	}
+	otp.Attempts++
+	r.otps[otpID] = otp
+	return nil
+}
+
+// ConsumeOTP - Marks an OTP as used, failing if it was already used
+func (r *repository) ConsumeOTP(otpID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	otp, ok := r.otps[otpID]
+	if !ok || otp.ConsumedAt != nil {
+		return domain.InvalidOTP
+	}
+	now := time.Now()
+	otp.ConsumedAt = &now
+	r.otps[otpID] = otp
+	return nil
+}
+
+// MarkUserVerified - Flips the isVerified flag of a user
+func (r *repository) MarkUserVerified(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[int32(userID)]; ok {
+		user.IsVerified = true
+		r.users[user.ID] = user
+	}
+	return nil
+}
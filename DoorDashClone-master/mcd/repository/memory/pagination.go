@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"mcd/domain"
+	"sort"
+	"strings"
+	"time"
+)
+
+// compareKeys orders two sort keys of the same type, nil keys are equal
+func compareKeys(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		// MySQL's default collation compares names without case
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b.(string)))
+	case int64:
+		switch b := b.(int64); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// paginate sorts the rows by the sort field and id like ORDER BY, keeps the rows after the
+// cursor and returns at most options.Limit of them. key returns the sort key of a row.
+func paginate[T any](rows []T, options domain.ListOptions, id func(T) int, key func(T, string) any) ([]T, error) {
+	field := options.Sort.Field
+	order := func(a, b T) int {
+		result := compareKeys(key(a, field), key(b, field))
+		if result == 0 {
+			result = id(a) - id(b)
+		}
+		if options.Sort.Descending {
+			return -result
+		}
+		return result
+	}
+	sort.Slice(rows, func(i, j int) bool { return order(rows[i], rows[j]) < 0 })
+
+	if options.After != nil {
+		afterKey, err := options.After.KeyValue()
+		if err != nil {
+			return nil, err
+		}
+		start := sort.Search(len(rows), func(i int) bool {
+			result := compareKeys(key(rows[i], field), afterKey)
+			if result == 0 {
+				result = id(rows[i]) - options.After.ID
+			}
+			if options.Sort.Descending {
+				result = -result
+			}
+			return result > 0
+		})
+		rows = rows[start:]
+	}
+	if len(rows) > options.Limit {
+		rows = rows[:options.Limit]
+	}
+	return rows, nil
+}
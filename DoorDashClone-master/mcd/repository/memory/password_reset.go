@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreatePasswordReset - Stores a new reset token and supersedes any token still pending for the user
+func (r *repository) CreatePasswordReset(reset domain.PasswordReset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, pending := range r.passwordResets {
+		if pending.UserID == reset.UserID && pending.UsedAt == nil {
+			pending.UsedAt = &now
+			r.passwordResets[id] = pending
+		}
+	}
+	r.lastPasswordResetID++
+	reset.ID = r.lastPasswordResetID
+	reset.CreatedAt = now
+	r.passwordResets[reset.ID] = reset
+	return nil
+}
+
+// GetPasswordResetByHash - Fetches a reset token by the hash of its value
+func (r *repository) GetPasswordResetByHash(tokenHash string) (domain.PasswordReset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, reset := range r.passwordResets {
+		if reset.TokenHash == tokenHash {
+			return reset, nil
+		}
+	}
+	return domain.PasswordReset{}, fmt.Errorf("failed to get password reset: %w", errNotFound)
+}
+
+// ResetPassword - Uses the reset token, stores the new password hash and revokes
+// every refresh token of the user
+func (r *repository) ResetPassword(resetID int, userID int, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset, ok := r.passwordResets[resetID]
+	if !ok || reset.UsedAt != nil {
+		return domain.InvalidResetToken
+	}
+	now := time.Now()
+	reset.UsedAt = &now
+	r.passwordResets[resetID] = reset
+
+	if user, ok := r.users[int32(userID)]; ok {
+		user.PasswordHash = passwordHash
+		r.users[user.ID] = user
+	}
+	r.revokeRefreshTokens(func(token domain.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"sort"
+	"time"
+)
+
+// CreatePayment - Stores the payment intent of an order
+func (r *repository) CreatePayment(payment domain.Payment) (domain.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastPaymentID++
+	payment.ID = r.lastPaymentID
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = payment.CreatedAt
+	r.payments[payment.ID] = payment
+	return payment, nil
+}
+
+// GetPaymentByOrderID - Fetches the latest payment of an order
+func (r *repository) GetPaymentByOrderID(orderID int) (domain.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest domain.Payment
+	for _, payment := range r.payments {
+		if payment.OrderID == orderID && payment.ID > latest.ID {
+			latest = payment
+		}
+	}
+	if latest.ID == 0 {
+		return latest, fmt.Errorf("failed to get payment: %w", errNotFound)
+	}
+	return latest, nil
+}
+
+// GetPaymentByTransactionID - Fetches the payment with the gateway's transaction reference
+func (r *repository) GetPaymentByTransactionID(transactionID string) (domain.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, payment := range r.payments {
+		if payment.TransactionID == transactionID {
+			return payment, nil
+		}
+	}
+	return domain.Payment{}, fmt.Errorf("failed to get payment: %w", errNotFound)
+}
+
+// CapturePayment - Marks the payment captured, writes the charge to the ledger and applies the
+// order's status change. If the order ended in the meantime the payment is kept as
+// refund_pending and domain.OrderStatusConflict is returned.
+func (r *repository) CapturePayment(paymentID int, change domain.OrderStatusChange, journal []domain.LedgerEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.payments[paymentID]
+	if !ok || (payment.Status != domain.PaymentAuthorized && payment.Status != domain.PaymentVoidPending && payment.Status != domain.PaymentVoided) {
+		return domain.OrderStatusConflict
+	}
+	payment.UpdatedAt = time.Now()
+	r.writeJournal(journal)
+
+	err := r.changeOrderStatus(change)
+	if err == nil {
+		payment.Status = domain.PaymentCaptured
+	} else {
+		payment.Status = domain.PaymentRefundPending
+	}
+	r.payments[paymentID] = payment
+	return err
+}
+
+// GetPaymentsPendingVoid - Fetches the payments left void_pending since before the given time, oldest first
+func (r *repository) GetPaymentsPendingVoid(updatedBefore time.Time) ([]domain.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var payments []domain.Payment
+	for _, payment := range r.payments {
+		if payment.Status == domain.PaymentVoidPending && payment.UpdatedAt.Before(updatedBefore) {
+			payments = append(payments, payment)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].ID < payments[j].ID })
+	return payments, nil
+}
+
+// VoidPayment - Marks a void_pending payment voided once the gateway dropped its authorization
+func (r *repository) VoidPayment(paymentID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return fmt.Errorf("failed to void payment: %w", errNotFound)
+	}
+	if payment.Status == domain.PaymentVoidPending {
+		payment.Status = domain.PaymentVoided
+		payment.UpdatedAt = time.Now()
+		r.payments[paymentID] = payment
+	}
+	return nil
+}
+
+// setPaymentStatus moves the order's payments in status from to status to,
+// the caller must hold the write lock
+func (r *repository) setPaymentStatus(orderID int, from, to domain.PaymentStatus) {
+	for id, payment := range r.payments {
+		if payment.OrderID == orderID && payment.Status == from {
+			payment.Status = to
+			payment.UpdatedAt = time.Now()
+			r.payments[id] = payment
+		}
+	}
+}
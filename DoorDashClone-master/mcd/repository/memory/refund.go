@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"slices"
+	"sort"
+	"time"
+)
+
+// refundableStatuses are the payment statuses that still have captured money to return
+var refundableStatuses = []domain.PaymentStatus{domain.PaymentCaptured, domain.PaymentRefundPending, domain.PaymentPartlyRefunded}
+
+// writeJournal appends balanced entries to the payment ledger, the caller must hold the write lock
+func (r *repository) writeJournal(journal []domain.LedgerEntry) {
+	now := time.Now()
+	for _, entry := range journal {
+		r.lastLedgerEntryID++
+		entry.ID = r.lastLedgerEntryID
+		entry.CreatedAt = now
+		r.ledger = append(r.ledger, entry)
+	}
+}
+
+// copyRefund returns a copy of the refund that does not share its items
+func copyRefund(refund domain.Refund) domain.Refund {
+	refund.Items = append([]domain.RefundItem(nil), refund.Items...)
+	return refund
+}
+
+// GetRefunds - Fetches every refund of a payment with its items, oldest first
+func (r *repository) GetRefunds(paymentID int) ([]domain.Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var refunds []domain.Refund
+	for _, refund := range r.refunds {
+		if refund.PaymentID == paymentID {
+			refunds = append(refunds, copyRefund(refund))
+		}
+	}
+	sort.Slice(refunds, func(i, j int) bool { return refunds[i].ID < refunds[j].ID })
+	return refunds, nil
+}
+
+// GetPendingRefunds - Fetches the refunds created before the given time that are still pending, oldest first
+func (r *repository) GetPendingRefunds(createdBefore time.Time) ([]domain.Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var refunds []domain.Refund
+	for _, refund := range r.refunds {
+		if refund.Status == domain.RefundPending && refund.CreatedAt.Before(createdBefore) {
+			refunds = append(refunds, copyRefund(refund))
+		}
+	}
+	sort.Slice(refunds, func(i, j int) bool { return refunds[i].ID < refunds[j].ID })
+	return refunds, nil
+}
+
+// CreateRefund - Reserves a pending refund against its payment. Returns domain.RefundExceedsCapture
+// if together with the other refunds it would exceed the captured amount or the ordered
+// quantity of a line.
+func (r *repository) CreateRefund(refund domain.Refund) (domain.Refund, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.payments[refund.PaymentID]
+	if !ok {
+		return refund, fmt.Errorf("failed to get payment: %w", errNotFound)
+	}
+	if !slices.Contains(refundableStatuses, payment.Status) {
+		return refund, domain.PaymentNotRefundable
+	}
+
+	var refunded int64
+	refundedQuantity := make(map[int]int)
+	for _, existing := range r.refunds {
+		if existing.PaymentID != refund.PaymentID || existing.Status == domain.RefundFailed {
+			continue
+		}
+		refunded += existing.Amount.Amount
+		for _, item := range existing.Items {
+			refundedQuantity[item.OrderProductID] += item.Quantity
+		}
+	}
+	if refunded+refund.Amount.Amount > payment.Amount.Amount {
+		return refund, domain.RefundExceedsCapture
+	}
+	for _, item := range refund.Items {
+		ordered := 0
+		for _, line := range r.orders[refund.OrderID].Products {
+			if line.ID == item.OrderProductID {
+				ordered = line.Quantity
+			}
+		}
+		if refundedQuantity[item.OrderProductID]+item.Quantity > ordered {
+			return refund, domain.RefundExceedsCapture
+		}
+	}
+
+	r.lastRefundID++
+	refund.ID = r.lastRefundID
+	refund.CreatedAt = time.Now()
+	refund.UpdatedAt = refund.CreatedAt
+	refund.Items = append([]domain.RefundItem(nil), refund.Items...)
+	for i := range refund.Items {
+		r.lastRefundItemID++
+		refund.Items[i].ID = r.lastRefundItemID
+		refund.Items[i].RefundID = refund.ID
+	}
+	r.refunds[refund.ID] = refund
+	return copyRefund(refund), nil
+}
+
+// CompleteRefund - Marks a pending refund as succeeded, writes its journal to the ledger and
+// updates the payment to partially_refunded or refunded
+func (r *repository) CompleteRefund(refundID int, journal []domain.LedgerEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	refund, ok := r.refunds[refundID]
+	if !ok || refund.Status != domain.RefundPending {
+		return fmt.Errorf("refund %d is not pending", refundID)
+	}
+	refund.Status = domain.RefundSucceeded
+	refund.UpdatedAt = time.Now()
+	r.refunds[refundID] = refund
+	r.writeJournal(journal)
+
+	var refunded int64
+	for _, existing := range r.refunds {
+		if existing.PaymentID == refund.PaymentID && existing.Status == domain.RefundSucceeded {
+			refunded += existing.Amount.Amount
+		}
+	}
+	payment := r.payments[refund.PaymentID]
+	payment.Status = domain.PaymentPartlyRefunded
+	if refunded >= payment.Amount.Amount {
+		payment.Status = domain.PaymentRefunded
+	}
+	payment.UpdatedAt = refund.UpdatedAt
+	r.payments[payment.ID] = payment
+	return nil
+}
+
+// FailRefund - Marks a pending refund as failed, which frees its amount to be refunded again
+func (r *repository) FailRefund(refundID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	refund, ok := r.refunds[refundID]
+	if !ok {
+		return fmt.Errorf("failed to fail refund: %w", errNotFound)
+	}
+	if refund.Status == domain.RefundPending {
+		refund.Status = domain.RefundFailed
+		refund.UpdatedAt = time.Now()
+		r.refunds[refundID] = refund
+	}
+	return nil
+}
+
+// GetLedgerEntries - Fetches every ledger entry of an order, oldest first
+func (r *repository) GetLedgerEntries(orderID int) ([]domain.LedgerEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []domain.LedgerEntry
+	for _, entry := range r.ledger {
+		if entry.OrderID == orderID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// WriteLedgerAdjustment - Appends the balanced entries of a manual adjustment to the payment ledger
+func (r *repository) WriteLedgerAdjustment(journal []domain.LedgerEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeJournal(journal)
+	return nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreateRefreshToken - Stores a newly issued refresh token
+func (r *repository) CreateRefreshToken(token domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.insertRefreshToken(token)
+	return nil
+}
+
+func (r *repository) insertRefreshToken(token domain.RefreshToken) {
+	r.lastRefreshTokenID++
+	token.ID = r.lastRefreshTokenID
+	token.CreatedAt = time.Now()
+	r.refreshTokens[token.ID] = token
+}
+
+// GetRefreshTokenByHash - Fetches a refresh token by the hash of its value
+func (r *repository) GetRefreshTokenByHash(tokenHash string) (domain.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return domain.RefreshToken{}, fmt.Errorf("failed to get refresh token: %w", errNotFound)
+}
+
+// RotateRefreshToken - Revokes the old token and stores its replacement.
+// If the old token was already revoked, the rotation is treated as reuse.
+func (r *repository) RotateRefreshToken(oldTokenID int, newToken domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.refreshTokens[oldTokenID]
+	if !ok || old.RevokedAt != nil {
+		return domain.RefreshTokenReused
+	}
+	now := time.Now()
+	old.RevokedAt = &now
+	r.refreshTokens[oldTokenID] = old
+	r.insertRefreshToken(newToken)
+	return nil
+}
+
+// RevokeRefreshTokenFamily - Revokes every token rotated from the same login
+func (r *repository) RevokeRefreshTokenFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revokeRefreshTokens(func(token domain.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+// RevokeUserRefreshTokens - Revokes every active refresh token of a user
+func (r *repository) RevokeUserRefreshTokens(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revokeRefreshTokens(func(token domain.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
+
+// revokeRefreshTokens revokes the active tokens matching the filter, the lock must be held
+func (r *repository) revokeRefreshTokens(match func(domain.RefreshToken) bool) {
+	now := time.Now()
+	for id, token := range r.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+			r.refreshTokens[id] = token
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"mcd/domain"
+	"time"
+)
+
+// WebhookEventExists - Reports whether the gateway event was already processed
+func (r *repository) WebhookEventExists(eventID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.webhookEvents[eventID]
+	return ok, nil
+}
+
+// CreateWebhookEvent - Records a processed gateway event, an event that was recorded
+// concurrently by another delivery is left as it is
+func (r *repository) CreateWebhookEvent(event domain.WebhookEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhookEvents[event.EventID]; ok {
+		return nil
+	}
+	r.lastWebhookEventID++
+	event.ID = r.lastWebhookEventID
+	event.CreatedAt = time.Now()
+	r.webhookEvents[event.EventID] = event
+	return nil
+}
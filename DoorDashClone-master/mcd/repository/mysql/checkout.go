@@ -0,0 +1,42 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+)
+
+// CheckoutCart - Stores the order, reserves stock for every product and removes the
+// checked out products from the user's cart in one transaction. A product whose stock
+// ran out since it was read fails the whole checkout with domain.InsufficientStock.
+func (r *repository) CheckoutCart(order domain.Order) (domain.Order, error) {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return order, fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	if err := insertOrder(tx, &order); err != nil {
+		tx.Rollback()
+		return order, err
+	}
+	if err := reserveStock(tx, order); err != nil {
+		tx.Rollback()
+		return order, err
+	}
+
+	productIDs := make([]int, 0, len(order.Products))
+	for _, product := range order.Products {
+		productIDs = append(productIDs, product.ProductID)
+	}
+	err := tx.Exec(`DELETE FROM user_carts WHERE user_id = ? AND product_id IN ?;`, order.UserID, productIDs).Error
+	if err != nil {
+		tx.Rollback()
+		return order, fmt.Errorf("failed to empty cart: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return order, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	r.writes.markWrite(order.UserID)
+	return order, nil
+}
@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreateIdempotencyKey - Stores a new key, returns domain.IdempotencyKeyInFlight if the user already has it
+func (r *repository) CreateIdempotencyKey(record domain.IdempotencyKey) (domain.IdempotencyKey, error) {
+	err := r.db.WithContext(context.Background()).Table("idempotency_keys").Create(&record).Error
+	if isDuplicateKeyError(err) {
+		return record, domain.IdempotencyKeyInFlight
+	}
+	if err != nil {
+		return record, fmt.Errorf("failed to create idempotency key: %w", err)
+	}
+	return record, nil
+}
+
+// GetIdempotencyKey - Fetches the user's record for the key
+func (r *repository) GetIdempotencyKey(userID int, key string) (domain.IdempotencyKey, error) {
+	var record domain.IdempotencyKey
+	err := r.primary().Table("idempotency_keys").
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		First(&record).Error
+	if err != nil {
+		return record, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return record, nil
+}
+
+// CompleteIdempotencyKey - Stores the response of the request made with the key
+func (r *repository) CompleteIdempotencyKey(recordID int, statusCode int, responseBody []byte) error {
+	err := r.db.WithContext(context.Background()).Table("idempotency_keys").
+		Where("id = ?", recordID).
+		Updates(map[string]any{"status_code": statusCode, "response_body": responseBody}).Error
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// TakeOverIdempotencyKey - Renews the lease of a key still in flight whose lease ended before
+// now, in one statement so only one retry takes it over
+func (r *repository) TakeOverIdempotencyKey(recordID int, now time.Time, leaseExpiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(context.Background()).
+		Exec(`UPDATE idempotency_keys SET lease_expires_at = ? WHERE id = ? AND status_code = 0 AND lease_expires_at < ?;`, leaseExpiresAt, recordID, now)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to take over idempotency key: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteIdempotencyKey - Deletes a key so it can be claimed again
+func (r *repository) DeleteIdempotencyKey(recordID int) error {
+	err := r.db.WithContext(context.Background()).Exec(`DELETE FROM idempotency_keys WHERE id = ?;`, recordID).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys - Deletes every key that expired before now
+func (r *repository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	result := r.db.WithContext(context.Background()).Exec(`DELETE FROM idempotency_keys WHERE expires_at < ?;`, now)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
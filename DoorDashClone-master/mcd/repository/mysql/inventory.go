@@ -0,0 +1,125 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reserveStock takes the ordered quantities out of products.stockLeft and records a
+// reservation for each of them. The decrement is conditional, so of two concurrent orders
+// for the last item only one matches a row and the other gets domain.InsufficientStock.
+// Rows are locked in product id order whatever the order lists, so two orders for the same
+// products cannot each hold a row the other waits for and deadlock.
+func reserveStock(tx *gorm.DB, order domain.Order) error {
+	products := slices.Clone(order.Products)
+	slices.SortFunc(products, func(a, b domain.OrderProduct) int { return a.ProductID - b.ProductID })
+	for _, product := range products {
+		result := tx.Table("products").
+			Where("id = ? AND stockLeft >= ?", product.ProductID, product.Quantity).
+			Update("stockLeft", gorm.Expr("stockLeft - ?", product.Quantity))
+		if result.Error != nil {
+			return fmt.Errorf("failed to decrement stock: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return domain.InsufficientStock
+		}
+
+		reservation := domain.StockReservation{
+			OrderID:   order.ID,
+			ProductID: product.ProductID,
+			Quantity:  product.Quantity,
+			Status:    domain.ReservationReserved,
+		}
+		if err := tx.Table("stock_reservations").Create(&reservation).Error; err != nil {
+			return fmt.Errorf("failed to create stock reservation: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetOrdersPastPaymentDue - Fetches the IDs of unpaid orders whose payment deadline has passed
+func (r *repository) GetOrdersPastPaymentDue(now time.Time) ([]int, error) {
+	var orderIDs []int
+	err := r.primary().Table("user_orders").
+		Where("order_status = ? AND payment_due_at IS NOT NULL AND payment_due_at <= ?", domain.OrderPaymentPending, now).
+		Pluck("id", &orderIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders past payment due: %w", err)
+	}
+	return orderIDs, nil
+}
+
+// CancelOrder - Applies the cancelled or rejected status change, puts the order's reserved
+// stock back, voids payments that were only authorized and marks captured payments for refund
+// in one transaction. If the order has already left FromStatus, nothing is released and
+// domain.OrderStatusConflict is returned.
+func (r *repository) CancelOrder(change domain.OrderStatusChange) error {
+	orderID := change.OrderID
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	userID, err := changeOrderStatus(tx, change)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var reservations []domain.StockReservation
+	err = tx.Table("stock_reservations").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, domain.ReservationReserved).
+		Order("product_id"). // Products are locked in the same order as reserveStock locks them
+		Find(&reservations).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to get stock reservations: %w", err)
+	}
+
+	for _, reservation := range reservations {
+		err := tx.Table("products").
+			Where("id = ?", reservation.ProductID).
+			Update("stockLeft", gorm.Expr("stockLeft + ?", reservation.Quantity)).Error
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to restore stock: %w", err)
+		}
+	}
+
+	err = tx.Table("stock_reservations").
+		Where("order_id = ? AND status = ?", orderID, domain.ReservationReserved).
+		Update("status", domain.ReservationReleased).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to release stock reservations: %w", err)
+	}
+
+	err = tx.Table("PAYMENTS").
+		Where("order_id = ? AND status = ?", orderID, domain.PaymentAuthorized).
+		Update("status", domain.PaymentVoidPending).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to void payments: %w", err)
+	}
+
+	err = tx.Table("PAYMENTS").
+		Where("order_id = ? AND status = ?", orderID, domain.PaymentCaptured).
+		Update("status", domain.PaymentRefundPending).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to mark payments for refund: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	r.writes.markWrite(userID)
+	return nil
+}
@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+)
+
+// GetMenuSections - Fetches the menu sections of a hotel by position, then id
+func (r *repository) GetMenuSections(hotelID int) ([]domain.MenuSection, error) {
+	var sections []domain.MenuSection
+	err := r.db.WithContext(context.Background()).
+		Table("menu_sections").
+		Where("hotel_id = ?", hotelID).
+		Order("position, id").
+		Find(&sections).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu sections: %w", err)
+	}
+	return sections, nil
+}
+
+// CreateMenuSection - Adds a menu section to a hotel
+func (r *repository) CreateMenuSection(section domain.MenuSection) (domain.MenuSection, error) {
+	err := r.db.WithContext(context.Background()).Table("menu_sections").Create(&section).Error
+	if err != nil {
+		return section, fmt.Errorf("failed to create menu section: %w", err)
+	}
+	return section, nil
+}
+
+// UpdateMenuSection - Replaces the name, description and position of a menu section
+func (r *repository) UpdateMenuSection(section domain.MenuSection) (domain.MenuSection, error) {
+	err := r.db.WithContext(context.Background()).Table("menu_sections").
+		Where("id = ?", section.ID).
+		Select("name", "description", "position").
+		Updates(&section).Error
+	if err != nil {
+		return section, fmt.Errorf("failed to update menu section: %w", err)
+	}
+	var updated domain.MenuSection
+	if err := r.primary().Table("menu_sections").Where("id = ?", section.ID).First(&updated).Error; err != nil {
+		return section, fmt.Errorf("failed to get menu section: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteMenuSection - Deletes a menu section, the foreign key moves its products to no section
+func (r *repository) DeleteMenuSection(sectionID int) error {
+	err := r.db.WithContext(context.Background()).Table("menu_sections").Where("id = ?", sectionID).Delete(&domain.MenuSection{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete menu section: %w", err)
+	}
+	return nil
+}
+
+// SetProductAvailable - Takes a product off or back on sale
+func (r *repository) SetProductAvailable(productID int, available bool) error {
+	err := r.db.WithContext(context.Background()).Table("products").Where("id = ?", productID).Update("available", available).Error
+	if err != nil {
+		return fmt.Errorf("failed to update product availability: %w", err)
+	}
+	return nil
+}
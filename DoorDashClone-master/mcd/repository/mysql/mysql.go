@@ -2,24 +2,43 @@ package mysql
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"mcd/domain"
+	"strconv"
+	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation
+const mysqlDuplicateEntry = 1062
+
+// isDuplicateKeyError reports whether err is a unique key violation
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
 type repository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	writes *writeTracker
 }
 
-func NewRepository(db *gorm.DB) domain.MCDRepository {
-	return &repository{db: db}
+// NewRepository - readYourWritesWindow is how long a user's reads stay on the primary after they write
+func NewRepository(db *gorm.DB, readYourWritesWindow time.Duration) domain.MCDRepository {
+	return &repository{db: db, writes: newWriteTracker(readYourWritesWindow)}
 }
 
 // CreateUser - Adds a new user to the database
 func (r *repository) CreateUser(user domain.User) error {
 	err := r.db.WithContext(context.Background()).Table("users").Create(&user).Error
+	if isDuplicateKeyError(err) {
+		return domain.EmailAlreadyExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -41,13 +60,15 @@ func (r *repository) UpdateUser(user domain.User) error {
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
+	r.writes.markWrite(int(user.ID))
 	return nil
 }
 
 // GetUserById - Fetches a user from the database by their ID
 func (r *repository) GetUserById(userID string) (domain.User, error) {
 	var user domain.User
-	err := r.db.WithContext(context.Background()).Table("users").Where("id = ?", userID).First(&user).Error
+	id, _ := strconv.Atoi(userID)
+	err := r.readerFor(id).Table("users").Where("id = ?", userID).First(&user).Error
 	if err != nil {
 		return user, fmt.Errorf("failed to get user by id: %w", err)
 	}
@@ -63,13 +84,33 @@ func (r *repository) GetUserByEmail(email string) (domain.User, error) {
 	return user, nil
 }
 
+// EmailExists - Reports whether a user is registered with the email
+func (r *repository) EmailExists(email string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(context.Background()).Table("users").Where("email = ?", email).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check email: %w", err)
+	}
+	return count > 0, nil
+}
+
+// PhoneNumberExists - Reports whether a user is registered with the phone number
+func (r *repository) PhoneNumberExists(phoneNumber string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(context.Background()).Table("users").Where("phone_number = ?", phoneNumber).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check phone number: %w", err)
+	}
+	return count > 0, nil
+}
+
 // CreateProduct - Adds a new product to the database
-func (r *repository) CreateProduct(product domain.Product) error {
+func (r *repository) CreateProduct(product domain.Product) (domain.Product, error) {
 	err := r.db.WithContext(context.Background()).Table("products").Create(&product).Error
 	if err != nil {
-		return fmt.Errorf("failed to create product: %w", err)
+		return product, fmt.Errorf("failed to create product: %w", err)
 	}
-	return nil
+	return product, nil
 }
 
 // DeleteProduct - Deletes a product from the database by ID
@@ -82,12 +123,18 @@ func (r *repository) DeleteProduct(productID string) error {
 }
 
 // UpdateProduct - Updates an existing product's information
-func (r *repository) UpdateProduct(product domain.Product) error {
+func (r *repository) UpdateProduct(product domain.Product) (domain.Product, error) {
 	err := r.db.WithContext(context.Background()).Table("products").Where("id = ?", product.ID).Updates(product).Error
 	if err != nil {
-		return fmt.Errorf("failed to update product: %w", err)
+		return product, fmt.Errorf("failed to update product: %w", err)
 	}
-	return nil
+	// Read it back from the primary, a replica may not have the update yet
+	var updated domain.Product
+	err = r.primary().Table("products").Where("id = ?", product.ID).First(&updated).Error
+	if err != nil {
+		return updated, fmt.Errorf("failed to get updated product: %w", err)
+	}
+	return updated, nil
 }
 
 // GetProductById - Fetches a product by its ID
@@ -100,23 +147,42 @@ func (r *repository) GetProductById(productID string) (domain.Product, error) {
 	return product, nil
 }
 
-// GetProductsByHotel - Fetches all products for a specific hotel
-func (r *repository) GetProductsByHotel(hotelID string) ([]domain.Product, error) {
-	var products []domain.Product
-	err := r.db.WithContext(context.Background()).Table("products").Where("hotel_id = ?", hotelID).Find(&products).Error
+// GetProductsByHotel - Fetches one page of a hotel's products matching the filters
+func (r *repository) GetProductsByHotel(query domain.ProductQuery) ([]domain.Product, error) {
+	db := r.db.WithContext(context.Background()).Table("products").Where("hotel_id = ?", query.HotelID)
+	if query.Category != "" {
+		db = db.Where("category = ?", query.Category)
+	}
+	if query.MinPrice != nil {
+		db = db.Where("price_amount >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		db = db.Where("price_amount <= ?", *query.MaxPrice)
+	}
+	if query.Section != nil && *query.Section == 0 {
+		db = db.Where("section_id IS NULL")
+	} else if query.Section != nil {
+		db = db.Where("section_id = ?", *query.Section)
+	}
+	db, err := paginate(db, query.ListOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products by hotel: %w", err)
 	}
+
+	var products []domain.Product
+	if err := db.Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to get products by hotel: %w", err)
+	}
 	return products, nil
 }
 
 // CreateHotel - Adds a new hotel to the database
-func (r *repository) CreateHotel(hotel domain.Hotel) error {
+func (r *repository) CreateHotel(hotel domain.Hotel) (domain.Hotel, error) {
 	err := r.db.WithContext(context.Background()).Table("hotels").Create(&hotel).Error
 	if err != nil {
-		return fmt.Errorf("failed to create hotel: %w", err)
+		return hotel, fmt.Errorf("failed to create hotel: %w", err)
 	}
-	return nil
+	return hotel, nil
 }
 
 // DeleteHotel - Deletes a hotel from the database by ID
@@ -128,22 +194,54 @@ func (r *repository) DeleteHotel(hotelID string) error {
 	return nil
 }
 
-// UpdateHotel - Updates an existing hotel's information
-func (r *repository) UpdateHotel(hotel domain.Hotel) error {
+// UpdateHotel - Updates an existing hotel's information, the non-zero fields only. A delivery
+// radius pointing at 0 is written, making the hotel pickup only.
+func (r *repository) UpdateHotel(hotel domain.Hotel) (domain.Hotel, error) {
 	err := r.db.WithContext(context.Background()).Table("hotels").Where("id = ?", hotel.ID).Updates(hotel).Error
 	if err != nil {
-		return fmt.Errorf("failed to update hotel: %w", err)
+		return hotel, fmt.Errorf("failed to update hotel: %w", err)
 	}
-	return nil
+	// Read it back from the primary, a replica may not have the update yet
+	var updated domain.Hotel
+	err = r.primary().Table("hotels").Where("id = ?", hotel.ID).First(&updated).Error
+	if err != nil {
+		return updated, fmt.Errorf("failed to get updated hotel: %w", err)
+	}
+	return updated, nil
 }
 
-// GetHotels - Fetches all hotels from the database
-func (r *repository) GetHotels() ([]domain.Hotel, error) {
-	var hotels []domain.Hotel
-	err := r.db.WithContext(context.Background()).Table("hotels").Find(&hotels).Error
+// GetHotels - Fetches one page of the hotels matching the filters
+func (r *repository) GetHotels(query domain.HotelQuery) ([]domain.Hotel, error) {
+	db := r.db.WithContext(context.Background()).Table("hotels")
+	if query.City != "" {
+		db = db.Where("city = ?", query.City)
+	}
+	if query.State != "" {
+		db = db.Where("state = ?", query.State)
+	}
+	db, err := paginate(db, query.ListOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get hotels: %w", err)
 	}
+
+	var hotels []domain.Hotel
+	if err := db.Find(&hotels).Error; err != nil {
+		return nil, fmt.Errorf("failed to get hotels: %w", err)
+	}
+	return hotels, nil
+}
+
+// GetHotelsWithin - Fetches the hotels located inside the bounds, using the latitude/longitude index
+func (r *repository) GetHotelsWithin(bounds domain.GeoBounds) ([]domain.Hotel, error) {
+	var hotels []domain.Hotel
+	err := r.db.WithContext(context.Background()).
+		Table("hotels").
+		Where("latitude BETWEEN ? AND ?", bounds.MinLatitude, bounds.MaxLatitude).
+		Where("longitude BETWEEN ? AND ?", bounds.MinLongitude, bounds.MaxLongitude).
+		Find(&hotels).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hotels within bounds: %w", err)
+	}
 	return hotels, nil
 }
 
@@ -169,6 +267,7 @@ func (r *repository) AddProductToCart(cartProduct domain.CartProducts) error {
 		log.Printf("Error adding product to cart: %v", tx.Error)
 		return tx.Error
 	}
+	r.writes.markWrite(cartProduct.UserID)
 	return nil
 }
 
@@ -181,6 +280,7 @@ func (r *repository) DeleteProductFromCart(cartProduct domain.CartProducts) erro
 		log.Printf("Error deleting product from cart: %v", tx.Error)
 		return tx.Error
 	}
+	r.writes.markWrite(cartProduct.UserID)
 
 	return nil
 }
@@ -195,6 +295,7 @@ func (r *repository) UpdateQuantityInCart(cartProduct domain.CartProducts) error
 		log.Printf("Error updating quantity in cart: %v", tx.Error)
 		return tx.Error
 	}
+	r.writes.markWrite(cartProduct.UserID)
 
 	return nil
 }
@@ -206,7 +307,7 @@ func (r *repository) GetUserCart(userID int) ([]domain.CartProducts, error) {
               FROM user_carts WHERE user_id = ?`
 
 	// Use the GORM Query method to execute the SQL query
-	rows, err := r.db.Raw(query, userID).Rows()
+	rows, err := r.readerFor(userID).Raw(query, userID).Rows()
 	if err != nil {
 		log.Printf("Error getting user cart: %v", err)
 		return nil, err
@@ -243,75 +344,86 @@ func (r *repository) GetProductDetails(productIDs []int) ([]domain.Product, erro
 	return products, nil
 }
 
-func (r *repository) CreateOrder(order domain.Order) error {
+// CreateOrder - Stores the order and its products and reserves their stock in one transaction.
+// Returns the order with the generated IDs.
+func (r *repository) CreateOrder(order domain.Order) (domain.Order, error) {
 	ctx := context.Background()
 
 	// Start a database transaction to ensure atomicity
 	tx := r.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
-		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+		return order, fmt.Errorf("failed to start transaction: %w", tx.Error)
 	}
 
-	// Insert the main order record into the user_orders table
-	if err := tx.Table("user_orders").Create(&order).Error; err != nil {
-		fmt.Println("ERRRRR:", err)
-		tx.Rollback() // Roll back the transaction on error
-		return fmt.Errorf("failed to create order: %w", err)
+	if err := insertOrder(tx, &order); err != nil {
+		tx.Rollback()
+		return order, err
 	}
-
-	// Log the order and its products for debugging
-	fmt.Println("ORDER::", order.Products)
-
-	// Assign the generated OrderID to each product in the order
-	for i := range order.Products {
-		// Ensure ID is reset for auto-generation
-		order.Products[i].ID = 0
-		order.Products[i].OrderID = order.ID
+	if err := reserveStock(tx, order); err != nil {
+		tx.Rollback()
+		return order, err
 	}
 
-	// Log the products being inserted for debugging
-	fmt.Printf("Products to Insert: %+v\n", order.Products)
-	fmt.Printf("Products Count: %d\n", len(order.Products))
-
-	// Insert the products into the order_products table
-	// if len(order.Products) > 0 {
-	// 	if err := tx.Table("order_products").Create(&order.Products).Error; err != nil {
-	// 		fmt.Println("QQQQQQQQQQQQQQ", err)
-	// 		tx.Rollback() // Roll back the transaction on error
-	// 		return fmt.Errorf("failed to create order products: %w", err)
-	// 	}
-	// }
-
 	// Commit the transaction if everything is successful
 	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return order, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	r.writes.markWrite(order.UserID)
 
-	return nil
+	return order, nil
 }
 
-// MarkOrderCompleted - Marks an order as completed
-func (r *repository) MarkOrderCompleted(orderID int) error {
-	err := r.db.WithContext(context.Background()).Table("orders").Where("id = ?", orderID).Update("order_completed", true).Error
+// insertOrder inserts the order, its first status history entry and then its products with the generated order ID
+func insertOrder(tx *gorm.DB, order *domain.Order) error {
+	if err := tx.Table("user_orders").Omit(clause.Associations).Create(order).Error; err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	err := recordStatusChange(tx, domain.OrderStatusChange{
+		OrderID:   order.ID,
+		ToStatus:  order.OrderStatus,
+		ChangedBy: &order.UserID,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to mark order as completed: %w", err)
+		return err
+	}
+	if len(order.Products) == 0 {
+		return nil
+	}
+
+	for i := range order.Products {
+		order.Products[i].ID = 0
+		order.Products[i].OrderID = order.ID
+	}
+	if err := tx.Table("order_products").Create(&order.Products).Error; err != nil {
+		return fmt.Errorf("failed to create order products: %w", err)
 	}
 	return nil
 }
 
-// GetUserOrders - Fetches all orders for a user based on user ID
-func (r *repository) GetUserOrders(userID int) ([]domain.Order, error) {
-	var orders []domain.Order
-
+// GetUserOrders - Fetches one page of a user's orders matching the filters
+func (r *repository) GetUserOrders(query domain.OrderQuery) ([]domain.Order, error) {
 	// Use Preload to load the associated products for each order
-	err := r.db.WithContext(context.Background()).Table("user_orders").
+	db := r.readerFor(query.UserID).Table("user_orders").
 		Preload("Products"). // Match the field name in the Order struct
-		Where("user_id = ?", userID).
-		Find(&orders).Error
-
+		Where("user_id = ?", query.UserID)
+	if query.Status != "" {
+		db = db.Where("order_status = ?", query.Status)
+	}
+	if query.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedBefore != nil {
+		db = db.Where("created_at < ?", *query.CreatedBefore)
+	}
+	db, err := paginate(db, query.ListOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user orders: %w", err)
 	}
 
+	var orders []domain.Order
+	if err := db.Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user orders: %w", err)
+	}
+
 	return orders, nil
 }
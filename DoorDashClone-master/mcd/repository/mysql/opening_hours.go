@@ -0,0 +1,101 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// GetOpeningHours - Fetches the weekly opening hours of the hotels in one query, by hotel id
+func (r *repository) GetOpeningHours(hotelIDs []int) (map[int][]domain.OpeningHours, error) {
+	byHotel := make(map[int][]domain.OpeningHours, len(hotelIDs))
+	if len(hotelIDs) == 0 {
+		return byHotel, nil
+	}
+	var hours []domain.OpeningHours
+	err := r.db.WithContext(context.Background()).
+		Table("hotel_opening_hours").
+		Where("hotel_id IN ?", hotelIDs).
+		Order("hotel_id, weekday, opens").
+		Find(&hours).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening hours: %w", err)
+	}
+	for _, period := range hours {
+		byHotel[period.HotelID] = append(byHotel[period.HotelID], period)
+	}
+	return byHotel, nil
+}
+
+// ReplaceOpeningHours - Sets the hotel's time zone and replaces all its opening hours in one transaction
+func (r *repository) ReplaceOpeningHours(hotelID int, timeZone string, hours []domain.OpeningHours) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	if err := tx.Table("hotels").Where("id = ?", hotelID).Update("time_zone", timeZone).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update time zone: %w", err)
+	}
+	if err := tx.Table("hotel_opening_hours").Where("hotel_id = ?", hotelID).Delete(&domain.OpeningHours{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete opening hours: %w", err)
+	}
+	if len(hours) > 0 {
+		for i := range hours {
+			hours[i].ID = 0
+			hours[i].HotelID = hotelID
+		}
+		if err := tx.Table("hotel_opening_hours").Create(&hours).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to create opening hours: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetHotelClosures - Fetches the closures of the hotels that end after the given time in one
+// query, by hotel id and earliest first
+func (r *repository) GetHotelClosures(hotelIDs []int, endingAfter time.Time) (map[int][]domain.HotelClosure, error) {
+	byHotel := make(map[int][]domain.HotelClosure, len(hotelIDs))
+	if len(hotelIDs) == 0 {
+		return byHotel, nil
+	}
+	var closures []domain.HotelClosure
+	err := r.db.WithContext(context.Background()).
+		Table("hotel_closures").
+		Where("hotel_id IN ? AND ends_at > ?", hotelIDs, endingAfter).
+		Order("hotel_id, starts_at").
+		Find(&closures).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hotel closures: %w", err)
+	}
+	for _, closure := range closures {
+		byHotel[closure.HotelID] = append(byHotel[closure.HotelID], closure)
+	}
+	return byHotel, nil
+}
+
+// CreateHotelClosure - Stores a one-off closure of a hotel
+func (r *repository) CreateHotelClosure(closure domain.HotelClosure) (domain.HotelClosure, error) {
+	err := r.db.WithContext(context.Background()).Table("hotel_closures").Create(&closure).Error
+	if err != nil {
+		return closure, fmt.Errorf("failed to create hotel closure: %w", err)
+	}
+	return closure, nil
+}
+
+// SetHotelPaused - Pauses or resumes taking orders at a hotel
+func (r *repository) SetHotelPaused(hotelID int, paused bool) error {
+	err := r.db.WithContext(context.Background()).Table("hotels").Where("id = ?", hotelID).Update("paused", paused).Error
+	if err != nil {
+		return fmt.Errorf("failed to update hotel pause: %w", err)
+	}
+	return nil
+}
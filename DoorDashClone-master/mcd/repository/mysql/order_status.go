@@ -0,0 +1,102 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+
+	"gorm.io/gorm"
+)
+
+// GetOrderByID - Fetches an order with its products
+func (r *repository) GetOrderByID(orderID int) (domain.Order, error) {
+	var order domain.Order
+	err := r.primary().Table("user_orders").
+		Preload("Products").
+		Where("id = ?", orderID).
+		First(&order).Error
+	if err != nil {
+		return order, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+// statusUpdates returns the user_orders columns to set when the order moves into the change's status
+func statusUpdates(change domain.OrderStatusChange) map[string]any {
+	updates := map[string]any{"order_status": change.ToStatus}
+	switch change.ToStatus {
+	case domain.OrderPickedUp:
+		updates["courier_id"] = change.ChangedBy
+	case domain.OrderDelivered:
+		updates["is_delivered"] = true
+	}
+	return updates
+}
+
+// changeOrderStatus moves the order from FromStatus to ToStatus, records the change and returns
+// the ID of the order's customer, whose reads the caller pins to the primary once it commits.
+// If the order is no longer in FromStatus, domain.OrderStatusConflict is returned.
+func changeOrderStatus(tx *gorm.DB, change domain.OrderStatusChange) (int, error) {
+	result := tx.Table("user_orders").
+		Where("id = ? AND order_status = ?", change.OrderID, change.FromStatus).
+		Updates(statusUpdates(change))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to update order status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, domain.OrderStatusConflict
+	}
+	if err := recordStatusChange(tx, change); err != nil {
+		return 0, err
+	}
+	return orderOwner(tx, change.OrderID)
+}
+
+// orderOwner returns the ID of the customer who placed the order
+func orderOwner(tx *gorm.DB, orderID int) (int, error) {
+	var userID int
+	err := tx.Table("user_orders").Select("user_id").Where("id = ?", orderID).Scan(&userID).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order owner: %w", err)
+	}
+	return userID, nil
+}
+
+// recordStatusChange appends the change to the order's status history
+func recordStatusChange(tx *gorm.DB, change domain.OrderStatusChange) error {
+	if err := tx.Table("order_status_history").Create(&change).Error; err != nil {
+		return fmt.Errorf("failed to record order status change: %w", err)
+	}
+	return nil
+}
+
+// UpdateOrderStatus - Moves the order to a new status and records the change in one transaction
+func (r *repository) UpdateOrderStatus(change domain.OrderStatusChange) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+	userID, err := changeOrderStatus(tx, change)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	r.writes.markWrite(userID)
+	return nil
+}
+
+// GetOrderStatusHistory - Fetches every status change of the order, oldest first
+func (r *repository) GetOrderStatusHistory(orderID int) ([]domain.OrderStatusChange, error) {
+	var history []domain.OrderStatusChange
+	err := r.primary().Table("order_status_history").
+		Where("order_id = ?", orderID).
+		Order("id").
+		Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order status history: %w", err)
+	}
+	return history, nil
+}
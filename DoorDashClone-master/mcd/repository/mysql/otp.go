@@ -0,0 +1,89 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateOTP - Stores a new OTP and supersedes any OTP still pending for the user
+func (r *repository) CreateOTP(otp domain.OTP) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	err := tx.Table("otps").
+		Where("user_id = ? AND consumed_at IS NULL", otp.UserID).
+		Update("consumed_at", time.Now()).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to supersede pending otps: %w", err)
+	}
+
+	if err := tx.Table("otps").Create(&otp).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to create otp: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetLatestOTP - Fetches the most recently issued OTP of a user
+func (r *repository) GetLatestOTP(userID int) (domain.OTP, error) {
+	var otp domain.OTP
+	err := r.primary().Table("otps").
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		First(&otp).Error
+	if err != nil {
+		return otp, fmt.Errorf("failed to get latest otp: %w", err)
+	}
+	return otp, nil
+}
+
+// IncrementOTPAttempts - Records a verification attempt unless the attempts are used up, in one
+// statement so concurrent attempts cannot exceed max_attempts
+func (r *repository) IncrementOTPAttempts(otpID int) error {
+	result := r.db.WithContext(context.Background()).Table("otps").
+		Where("id = ? AND attempts < max_attempts", otpID).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to increment otp attempts: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.OTPAttemptsExceeded
+	}
+	return nil
+}
+
+// ConsumeOTP - Marks an OTP as used, failing if it was already used
+func (r *repository) ConsumeOTP(otpID int) error {
+	result := r.db.WithContext(context.Background()).Table("otps").
+		Where("id = ? AND consumed_at IS NULL", otpID).
+		Update("consumed_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to consume otp: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.InvalidOTP
+	}
+	return nil
+}
+
+// MarkUserVerified - Flips the isVerified flag of a user
+func (r *repository) MarkUserVerified(userID int) error {
+	err := r.db.WithContext(context.Background()).Table("users").
+		Where("id = ?", userID).
+		Update("isVerified", true).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark user verified: %w", err)
+	}
+	return nil
+}
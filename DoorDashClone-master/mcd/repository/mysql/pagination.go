@@ -0,0 +1,45 @@
+package mysql
+
+import (
+	"fmt"
+	"mcd/domain"
+
+	"gorm.io/gorm"
+)
+
+// sortColumns maps the sortable fields to their columns
+var sortColumns = map[string]string{
+	domain.SortByID:        "id",
+	domain.SortByName:      "name",
+	domain.SortByPrice:     "price_amount",
+	domain.SortByCreatedAt: "created_at",
+}
+
+// paginate orders the query by the sort column and id, continues after the cursor and limits
+// the rows, so paging uses the indexes instead of an OFFSET scan
+func paginate(query *gorm.DB, options domain.ListOptions) (*gorm.DB, error) {
+	column, ok := sortColumns[options.Sort.Field]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort %q", options.Sort)
+	}
+	direction, comparison := "ASC", ">"
+	if options.Sort.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if after := options.After; after != nil {
+		key, err := after.KeyValue()
+		if err != nil {
+			return nil, err
+		}
+		if column == "id" {
+			query = query.Where("id "+comparison+" ?", after.ID)
+		} else {
+			query = query.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, comparison), key, key, after.ID)
+		}
+	}
+	if column != "id" {
+		query = query.Order(column + " " + direction)
+	}
+	return query.Order("id " + direction).Limit(options.Limit), nil
+}
@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreatePasswordReset - Stores a new reset token and supersedes any token still pending for the user
+func (r *repository) CreatePasswordReset(reset domain.PasswordReset) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	err := tx.Table("password_resets").
+		Where("user_id = ? AND used_at IS NULL", reset.UserID).
+		Update("used_at", time.Now()).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to supersede pending password resets: %w", err)
+	}
+
+	if err := tx.Table("password_resets").Create(&reset).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetPasswordResetByHash - Fetches a reset token by the hash of its value
+func (r *repository) GetPasswordResetByHash(tokenHash string) (domain.PasswordReset, error) {
+	var reset domain.PasswordReset
+	err := r.primary().Table("password_resets").Where("token_hash = ?", tokenHash).First(&reset).Error
+	if err != nil {
+		return reset, fmt.Errorf("failed to get password reset: %w", err)
+	}
+	return reset, nil
+}
+
+// ResetPassword - Uses the reset token, stores the new password hash and revokes
+// every refresh token of the user in one transaction
+func (r *repository) ResetPassword(resetID int, userID int, passwordHash string) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	now := time.Now()
+	result := tx.Table("password_resets").
+		Where("id = ? AND used_at IS NULL", resetID).
+		Update("used_at", now)
+	if result.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to use password reset: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return domain.InvalidResetToken
+	}
+
+	if err := tx.Table("users").Where("id = ?", userID).Update("password_hash", passwordHash).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	err := tx.Table("refresh_tokens").
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to revoke user refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
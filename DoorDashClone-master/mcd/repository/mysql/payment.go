@@ -0,0 +1,125 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreatePayment - Stores the payment intent of an order
+func (r *repository) CreatePayment(payment domain.Payment) (domain.Payment, error) {
+	err := r.db.WithContext(context.Background()).Table("PAYMENTS").Create(&payment).Error
+	if err != nil {
+		return payment, fmt.Errorf("failed to create payment: %w", err)
+	}
+	r.writes.markWrite(payment.UserID)
+	return payment, nil
+}
+
+// GetPaymentByOrderID - Fetches the latest payment of an order
+func (r *repository) GetPaymentByOrderID(orderID int) (domain.Payment, error) {
+	var payment domain.Payment
+	err := r.primary().Table("PAYMENTS").
+		Where("order_id = ?", orderID).
+		Order("id DESC").
+		First(&payment).Error
+	if err != nil {
+		return payment, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// GetPaymentByTransactionID - Fetches the payment with the gateway's transaction reference
+func (r *repository) GetPaymentByTransactionID(transactionID string) (domain.Payment, error) {
+	var payment domain.Payment
+	err := r.primary().Table("PAYMENTS").
+		Where("transaction_id = ?", transactionID).
+		First(&payment).Error
+	if err != nil {
+		return payment, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// CapturePayment - Marks the payment captured, writes the charge to the ledger and applies the
+// order's status change in one transaction. The money has already been taken at the gateway, so
+// if the order ended in the meantime the payment is kept as refund_pending and
+// domain.OrderStatusConflict is returned.
+func (r *repository) CapturePayment(paymentID int, change domain.OrderStatusChange, journal []domain.LedgerEntry) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	result := tx.Table("PAYMENTS").
+		Where("id = ? AND status IN ?", paymentID, []domain.PaymentStatus{domain.PaymentAuthorized, domain.PaymentVoidPending, domain.PaymentVoided}).
+		Update("status", domain.PaymentCaptured)
+	if result.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to capture payment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return domain.OrderStatusConflict
+	}
+	if err := writeJournal(tx, journal); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	userID, err := changeOrderStatus(tx, change)
+	if errors.Is(err, domain.OrderStatusConflict) {
+		if userID, err = orderOwner(tx, change.OrderID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = tx.Table("PAYMENTS").
+			Where("id = ?", paymentID).
+			Update("status", domain.PaymentRefundPending).Error
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to mark payment for refund: %w", err)
+		}
+		if err := tx.Commit().Error; err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		r.writes.markWrite(userID)
+		return domain.OrderStatusConflict
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	r.writes.markWrite(userID)
+	return nil
+}
+
+// GetPaymentsPendingVoid - Fetches the payments left void_pending since before the given time, oldest first
+func (r *repository) GetPaymentsPendingVoid(updatedBefore time.Time) ([]domain.Payment, error) {
+	var payments []domain.Payment
+	err := r.primary().Table("PAYMENTS").
+		Where("status = ? AND updated_at < ?", domain.PaymentVoidPending, updatedBefore).
+		Order("id").
+		Find(&payments).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments pending void: %w", err)
+	}
+	return payments, nil
+}
+
+// VoidPayment - Marks a void_pending payment voided once the gateway dropped its authorization
+func (r *repository) VoidPayment(paymentID int) error {
+	err := r.db.WithContext(context.Background()).Table("PAYMENTS").
+		Where("id = ? AND status = ?", paymentID, domain.PaymentVoidPending).
+		Update("status", domain.PaymentVoided).Error
+	if err != nil {
+		return fmt.Errorf("failed to void payment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// refundableStatuses are the payment statuses that still have captured money to return
+var refundableStatuses = []domain.PaymentStatus{domain.PaymentCaptured, domain.PaymentRefundPending, domain.PaymentPartlyRefunded}
+
+// writeJournal appends balanced entries to the payment ledger
+func writeJournal(tx *gorm.DB, journal []domain.LedgerEntry) error {
+	if len(journal) == 0 {
+		return nil
+	}
+	if err := tx.Table("ledger_entries").Create(&journal).Error; err != nil {
+		return fmt.Errorf("failed to write ledger entries: %w", err)
+	}
+	return nil
+}
+
+// GetRefunds - Fetches every refund of a payment with its items, oldest first
+func (r *repository) GetRefunds(paymentID int) ([]domain.Refund, error) {
+	var refunds []domain.Refund
+	err := r.primary().Table("refunds").
+		Preload("Items").
+		Where("payment_id = ?", paymentID).
+		Order("id").
+		Find(&refunds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// GetPendingRefunds - Fetches the refunds created before the given time that are still pending, oldest first
+func (r *repository) GetPendingRefunds(createdBefore time.Time) ([]domain.Refund, error) {
+	var refunds []domain.Refund
+	err := r.primary().Table("refunds").
+		Preload("Items").
+		Where("status = ? AND created_at < ?", domain.RefundPending, createdBefore).
+		Order("id").
+		Find(&refunds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// CreateRefund - Reserves a pending refund against its payment. The payment row is locked while
+// the refunds and refunded quantities are summed, so no two refunds can together exceed the
+// captured amount or the ordered quantity of a line; domain.RefundExceedsCapture is returned instead.
+func (r *repository) CreateRefund(refund domain.Refund) (domain.Refund, error) {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return refund, fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	var payment domain.Payment
+	err := tx.Table("PAYMENTS").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", refund.PaymentID).
+		First(&payment).Error
+	if err != nil {
+		tx.Rollback()
+		return refund, fmt.Errorf("failed to get payment: %w", err)
+	}
+	if !slices.Contains(refundableStatuses, payment.Status) {
+		tx.Rollback()
+		return refund, domain.PaymentNotRefundable
+	}
+
+	var refunded int64
+	err = tx.Table("refunds").
+		Where("payment_id = ? AND status <> ?", refund.PaymentID, domain.RefundFailed).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&refunded).Error
+	if err != nil {
+		tx.Rollback()
+		return refund, fmt.Errorf("failed to sum refunds: %w", err)
+	}
+	if refunded+refund.Amount.Amount > payment.Amount.Amount {
+		tx.Rollback()
+		return refund, domain.RefundExceedsCapture
+	}
+
+	for _, item := range refund.Items {
+		var line struct {
+			Quantity int
+			Refunded int
+		}
+		err := tx.Table("order_products").
+			Select("order_products.quantity AS quantity, "+
+				"(SELECT COALESCE(SUM(refund_items.quantity), 0) FROM refund_items "+
+				"JOIN refunds ON refunds.id = refund_items.refund_id "+
+				"WHERE refund_items.order_product_id = order_products.id AND refunds.status <> ?) AS refunded", domain.RefundFailed).
+			Where("order_products.id = ?", item.OrderProductID).
+			Scan(&line).Error
+		if err != nil {
+			tx.Rollback()
+			return refund, fmt.Errorf("failed to sum refunded quantity: %w", err)
+		}
+		if line.Refunded+item.Quantity > line.Quantity {
+			tx.Rollback()
+			return refund, domain.RefundExceedsCapture
+		}
+	}
+
+	if err := tx.Table("refunds").Omit(clause.Associations).Create(&refund).Error; err != nil {
+		tx.Rollback()
+		return refund, fmt.Errorf("failed to create refund: %w", err)
+	}
+	if len(refund.Items) > 0 {
+		for i := range refund.Items {
+			refund.Items[i].RefundID = refund.ID
+		}
+		if err := tx.Table("refund_items").Create(&refund.Items).Error; err != nil {
+			tx.Rollback()
+			return refund, fmt.Errorf("failed to create refund items: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return refund, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return refund, nil
+}
+
+// CompleteRefund - Marks a pending refund as succeeded, writes its journal to the ledger and
+// updates the payment to partially_refunded or refunded in one transaction
+func (r *repository) CompleteRefund(refundID int, journal []domain.LedgerEntry) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	var refund domain.Refund
+	if err := tx.Table("refunds").Where("id = ?", refundID).First(&refund).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to get refund: %w", err)
+	}
+	result := tx.Table("refunds").
+		Where("id = ? AND status = ?", refundID, domain.RefundPending).
+		Update("status", domain.RefundSucceeded)
+	if result.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to complete refund: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("refund %d is not pending", refundID)
+	}
+	if err := writeJournal(tx, journal); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var payment domain.Payment
+	err := tx.Table("PAYMENTS").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", refund.PaymentID).
+		First(&payment).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	var refunded int64
+	err = tx.Table("refunds").
+		Where("payment_id = ? AND status = ?", refund.PaymentID, domain.RefundSucceeded).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&refunded).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to sum refunds: %w", err)
+	}
+	status := domain.PaymentPartlyRefunded
+	if refunded >= payment.Amount.Amount {
+		status = domain.PaymentRefunded
+	}
+	if err := tx.Table("PAYMENTS").Where("id = ?", payment.ID).Update("status", status).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// FailRefund - Marks a pending refund as failed, which frees its amount to be refunded again
+func (r *repository) FailRefund(refundID int) error {
+	err := r.db.WithContext(context.Background()).Table("refunds").
+		Where("id = ? AND status = ?", refundID, domain.RefundPending).
+		Update("status", domain.RefundFailed).Error
+	if err != nil {
+		return fmt.Errorf("failed to fail refund: %w", err)
+	}
+	return nil
+}
+
+// GetLedgerEntries - Fetches every ledger entry of an order, oldest first
+func (r *repository) GetLedgerEntries(orderID int) ([]domain.LedgerEntry, error) {
+	var entries []domain.LedgerEntry
+	err := r.primary().Table("ledger_entries").
+		Where("order_id = ?", orderID).
+		Order("id").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	return entries, nil
+}
+
+// WriteLedgerAdjustment - Appends the balanced entries of a manual adjustment to the payment ledger
+func (r *repository) WriteLedgerAdjustment(journal []domain.LedgerEntry) error {
+	return writeJournal(r.db.WithContext(context.Background()), journal)
+}
@@ -0,0 +1,161 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// ReplicaPolicy is a dbresolver.Policy that only routes reads to replicas that answer
+// health checks and are not lagging behind the primary.
+//
+// It expects the resolver to be registered with the replicas in the same order as the
+// DSNs given to NewReplicaPolicy, followed by one connection to the primary that is
+// used when no replica is healthy.
+type ReplicaPolicy struct {
+	probes  []*sql.DB
+	maxLag  time.Duration
+	mu      sync.RWMutex
+	healthy []bool
+	next    uint64
+}
+
+// NewReplicaPolicy opens a small probe connection to every replica DSN
+func NewReplicaPolicy(replicaDSNs []string, maxLag time.Duration) (*ReplicaPolicy, error) {
+	policy := &ReplicaPolicy{maxLag: maxLag, healthy: make([]bool, len(replicaDSNs))}
+	for _, dsn := range replicaDSNs {
+		probe, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open replica probe: %w", err)
+		}
+		probe.SetMaxOpenConns(1)
+		policy.probes = append(policy.probes, probe)
+	}
+	return policy, nil
+}
+
+// Resolve picks the next healthy replica round robin, falling back to the primary
+// registered after the replicas when none of them is healthy
+func (policy *ReplicaPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	policy.mu.RLock()
+	defer policy.mu.RUnlock()
+
+	var candidates []int
+	for i, healthy := range policy.healthy {
+		if healthy && i < len(connPools) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return connPools[len(connPools)-1]
+	}
+	n := atomic.AddUint64(&policy.next, 1)
+	return connPools[candidates[n%uint64(len(candidates))]]
+}
+
+// Monitor checks every replica immediately and then on every interval until ctx is done
+func (policy *ReplicaPolicy) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		policy.checkReplicas(ctx, interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (policy *ReplicaPolicy) checkReplicas(ctx context.Context, timeout time.Duration) {
+	for i, probe := range policy.probes {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := policy.checkReplica(checkCtx, probe)
+		cancel()
+
+		policy.mu.Lock()
+		wasHealthy := policy.healthy[i]
+		policy.healthy[i] = err == nil
+		policy.mu.Unlock()
+
+		if err != nil && wasHealthy {
+			log.Printf("Replica %d removed from rotation: %v", i, err)
+		} else if err == nil && !wasHealthy {
+			log.Printf("Replica %d added to rotation", i)
+		}
+	}
+}
+
+// checkReplica pings the replica and compares its replication lag against the allowed maximum
+func (policy *ReplicaPolicy) checkReplica(ctx context.Context, probe *sql.DB) error {
+	if err := probe.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	lag, err := replicationLag(ctx, probe)
+	if err != nil {
+		return err
+	}
+	if lag > policy.maxLag {
+		return fmt.Errorf("replication lag %s exceeds %s", lag, policy.maxLag)
+	}
+	return nil
+}
+
+// mysqlParseError is the MySQL error number for a statement the server cannot parse, which is
+// what servers older than 8.0.22 answer to SHOW REPLICA STATUS
+const mysqlParseError = 1064
+
+// replicationLag reads the lag reported by SHOW REPLICA STATUS. Only a server answering with
+// no replication status at all (for example a managed read endpoint) is treated as caught up.
+// Any failure to read the status, and a stopped replication thread, is reported as an error so
+// the replica leaves the rotation.
+func replicationLag(ctx context.Context, probe *sql.DB) (time.Duration, error) {
+	rows, err := probe.QueryContext(ctx, "SHOW REPLICA STATUS")
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlParseError {
+		rows, err = probe.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read replication status: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, fmt.Errorf("replication is not running")
+		}
+		var seconds int64
+		if _, err := fmt.Sscan(values[i].String, &seconds); err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// CreateRefreshToken - Stores a newly issued refresh token
+func (r *repository) CreateRefreshToken(token domain.RefreshToken) error {
+	err := r.db.WithContext(context.Background()).Table("refresh_tokens").Create(&token).Error
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash - Fetches a refresh token by the hash of its value
+func (r *repository) GetRefreshTokenByHash(tokenHash string) (domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.primary().Table("refresh_tokens").Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return token, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// RotateRefreshToken - Revokes the old token and stores its replacement in one transaction.
+// If the old token was revoked concurrently, the rotation is treated as reuse.
+func (r *repository) RotateRefreshToken(oldTokenID int, newToken domain.RefreshToken) error {
+	tx := r.db.WithContext(context.Background()).Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	result := tx.Table("refresh_tokens").
+		Where("id = ? AND revoked_at IS NULL", oldTokenID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to revoke refresh token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return domain.RefreshTokenReused
+	}
+
+	if err := tx.Table("refresh_tokens").Create(&newToken).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RevokeRefreshTokenFamily - Revokes every token rotated from the same login
+func (r *repository) RevokeRefreshTokenFamily(familyID string) error {
+	err := r.db.WithContext(context.Background()).Table("refresh_tokens").
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens - Revokes every active refresh token of a user
+func (r *repository) RevokeUserRefreshTokens(userID int) error {
+	err := r.db.WithContext(context.Background()).Table("refresh_tokens").
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke user refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// writeTracker remembers which users wrote recently so that their reads can be pinned
+// to the primary until the replicas have caught up. It is per process, which is enough
+// because a user's follow-up read usually reaches the instance that served the write.
+type writeTracker struct {
+	window time.Duration
+	mu     sync.Mutex
+	writes map[int]time.Time
+}
+
+func newWriteTracker(window time.Duration) *writeTracker {
+	return &writeTracker{window: window, writes: make(map[int]time.Time)}
+}
+
+// markWrite records that the user has just written
+func (tracker *writeTracker) markWrite(userID int) {
+	if tracker.window <= 0 {
+		return
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	now := time.Now()
+	tracker.writes[userID] = now
+	// Drop entries that have left the window so the map does not grow forever
+	for id, at := range tracker.writes {
+		if now.Sub(at) > tracker.window {
+			delete(tracker.writes, id)
+		}
+	}
+}
+
+// wroteRecently reports whether the user wrote within the window
+func (tracker *writeTracker) wroteRecently(userID int) bool {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	at, ok := tracker.writes[userID]
+	return ok && time.Since(at) <= tracker.window
+}
+
+// readerFor returns a handle that reads from the primary if the user wrote recently
+// and from the replicas otherwise
+func (r *repository) readerFor(userID int) *gorm.DB {
+	db := r.db.WithContext(context.Background())
+	if r.writes.wroteRecently(userID) {
+		return db.Clauses(dbresolver.Write)
+	}
+	return db
+}
+
+// primary returns a handle that always reads from the primary, for lookups that must
+// observe the latest state such as token revocation
+func (r *repository) primary() *gorm.DB {
+	return r.db.WithContext(context.Background()).Clauses(dbresolver.Write)
+}
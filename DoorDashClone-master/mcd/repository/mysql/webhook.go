@@ -0,0 +1,27 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"mcd/domain"
+)
+
+// WebhookEventExists - Reports whether the gateway event was already processed
+func (r *repository) WebhookEventExists(eventID string) (bool, error) {
+	var count int64
+	err := r.primary().Table("webhook_events").Where("event_id = ?", eventID).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to look up webhook event: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CreateWebhookEvent - Records a processed gateway event, an event that was recorded
+// concurrently by another delivery is left as it is
+func (r *repository) CreateWebhookEvent(event domain.WebhookEvent) error {
+	err := r.db.WithContext(context.Background()).Table("webhook_events").Create(&event).Error
+	if err != nil && !isDuplicateKeyError(err) {
+		return fmt.Errorf("failed to create webhook event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package search
+
+import (
+	"math"
+	"mcd/domain"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Field weights, a word of a name counts for more than one of a category or dietary tag, and
+// those for more than one of a description
+const (
+	nameWeight        = 3.0
+	categoryWeight    = 1.5
+	descriptionWeight = 1.0
+)
+
+// How well a query word matches an indexed word
+const (
+	exactMatch  = 1.0
+	prefixMatch = 0.75 // The query word starts the indexed word, "burg" finds "burger"
+	oneTypo     = 0.6
+	twoTypos    = 0.4
+)
+
+// documentKey identifies an indexed hotel or product
+type documentKey struct {
+	kind domain.SearchKind
+	id   int
+}
+
+// document is what the index remembers about a hotel or product to update and scope it
+type document struct {
+	hotelID int
+	city    string // Only kept for hotels, products are scoped by the city of their hotel
+	terms   map[string]float64
+}
+
+// LocalIndex is an in-process inverted index of hotel and product words. Fuzzy matches are found
+// by comparing each query word against the whole vocabulary, which is fine for the menus of a
+// single deployment but is what a dedicated search service would do better. Every instance of
+// the service holds its own index, which only follows the changes made through that instance
+// until the next periodic RebuildSearchIndex.
+type LocalIndex struct {
+	mu        sync.RWMutex
+	documents map[documentKey]*document
+	postings  map[string]map[documentKey]float64 // Word to the documents holding it, with its field weight
+}
+
+// NewLocalIndex returns an empty index, the usecase fills it with RebuildSearchIndex
+func NewLocalIndex() *LocalIndex {
+	return &LocalIndex{
+		documents: make(map[documentKey]*document),
+		postings:  make(map[string]map[documentKey]float64),
+	}
+}
+
+func (index *LocalIndex) IndexHotel(hotel domain.Hotel) error {
+	terms := make(map[string]float64)
+	addTerms(terms, hotel.Name, nameWeight)
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.put(documentKey{domain.SearchHotel, int(hotel.ID)}, &document{hotelID: int(hotel.ID), city: hotel.City, terms: terms})
+	return nil
+}
+
+func (index *LocalIndex) IndexProduct(product domain.Product) error {
+	terms := make(map[string]float64)
+	addTerms(terms, product.Description, descriptionWeight)
+	for _, tag := range product.DietaryTags {
+		addTerms(terms, string(tag), categoryWeight)
+	}
+	addTerms(terms, product.Category, categoryWeight)
+	addTerms(terms, product.Name, nameWeight)
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.put(documentKey{domain.SearchProduct, int(product.ID)}, &document{hotelID: product.HotelID, terms: terms})
+	return nil
+}
+
+func (index *LocalIndex) RemoveHotel(hotelID int) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	for key, document := range index.documents {
+		if document.hotelID == hotelID {
+			index.remove(key)
+		}
+	}
+	return nil
+}
+
+func (index *LocalIndex) RemoveProduct(productID int) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.remove(documentKey{domain.SearchProduct, productID})
+	return nil
+}
+
+// Search returns the documents matching every query word, best first. A document scores the
+// field weight of each query word times how closely the word matched.
+func (index *LocalIndex) Search(query domain.SearchQuery) ([]domain.SearchHit, error) {
+	words := tokenize(query.Text)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	var scores map[documentKey]float64
+	for _, word := range words {
+		wordScores := make(map[documentKey]float64)
+		for term, documents := range index.postings {
+			quality := matchQuality(word, term)
+			if quality == 0 {
+				continue
+			}
+			for key, weight := range documents {
+				wordScores[key] = max(wordScores[key], quality*weight)
+			}
+		}
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		for key, score := range scores {
+			if wordScore, ok := wordScores[key]; ok {
+				scores[key] = score + wordScore
+			} else {
+				delete(scores, key)
+			}
+		}
+	}
+
+	hits := make([]domain.SearchHit, 0, len(scores))
+	for key, score := range scores {
+		if query.City != "" && !strings.EqualFold(index.cityOf(key), query.City) {
+			continue
+		}
+		hits = append(hits, domain.SearchHit{Kind: key.kind, ID: key.id, Score: math.Round(score*100) / 100})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Kind != hits[j].Kind {
+			return hits[i].Kind == domain.SearchHotel
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+// put replaces the document under key, the caller holds the write lock
+func (index *LocalIndex) put(key documentKey, document *document) {
+	index.remove(key)
+	index.documents[key] = document
+	for term, weight := range document.terms {
+		if index.postings[term] == nil {
+			index.postings[term] = make(map[documentKey]float64)
+		}
+		index.postings[term][key] = weight
+	}
+}
+
+// remove drops the document under key and its postings, the caller holds the write lock
+func (index *LocalIndex) remove(key documentKey) {
+	document, ok := index.documents[key]
+	if !ok {
+		return
+	}
+	for term := range document.terms {
+		delete(index.postings[term], key)
+		if len(index.postings[term]) == 0 {
+			delete(index.postings, term)
+		}
+	}
+	delete(index.documents, key)
+}
+
+// cityOf returns the city of a hotel, or of the hotel serving a product, the caller holds the lock
+func (index *LocalIndex) cityOf(key documentKey) string {
+	hotel, ok := index.documents[documentKey{domain.SearchHotel, index.documents[key].hotelID}]
+	if !ok {
+		return ""
+	}
+	return hotel.city
+}
+
+// addTerms records the words of text with weight, keeping the highest weight of a repeated word
+func addTerms(terms map[string]float64, text string, weight float64) {
+	for _, word := range tokenize(text) {
+		terms[word] = max(terms[word], weight)
+	}
+}
+
+// tokenize lower-cases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchQuality returns how well a query word matches an indexed term, 0 when it does not.
+// Short words must match exactly, longer ones tolerate one typo and words of 8 letters two.
+func matchQuality(word, term string) float64 {
+	if word == term {
+		return exactMatch
+	}
+	wordLength := len([]rune(word))
+	if wordLength >= 3 && strings.HasPrefix(term, word) {
+		return prefixMatch
+	}
+	allowed := 0
+	switch {
+	case wordLength >= 8:
+		allowed = 2
+	case wordLength >= 4:
+		allowed = 1
+	}
+	if allowed == 0 {
+		return 0
+	}
+	switch distance := editDistance(word, term, allowed); {
+	case distance > allowed:
+		return 0
+	case distance == 1:
+		return oneTypo
+	}
+	return twoTypos
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and swaps of adjacent
+// letters turning a into b, or limit+1 as soon as it is known to exceed limit
+func editDistance(a, b string, limit int) int {
+	first, second := []rune(a), []rune(b)
+	if diff := len(first) - len(second); diff > limit || -diff > limit {
+		return limit + 1
+	}
+	// Three rows of the dynamic programming table are enough to see a swap
+	previous2 := make([]int, len(second)+1)
+	previous := make([]int, len(second)+1)
+	current := make([]int, len(second)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(first); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && first[i-1] == second[j-2] && first[i-2] == second[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(second)]
+}
@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"log"
+	"mcd/domain"
+)
+
+// authorizeHotelOwner checks that the actor may manage the given hotel.
+// Admins may manage any hotel, restaurant owners only the hotels recorded against them.
+func (usecase *usecase) authorizeHotelOwner(actor domain.AuthClaims, hotelID int) error {
+	hotel, err := usecase.repository.GetHotelByID(hotelID)
+	if err != nil {
+		log.Printf("Error getting hotel %d: %v", hotelID, err)
+		return domain.HotelNotFound
+	}
+	if actor.Role == domain.RoleAdmin {
+		return nil
+	}
+	if hotel.OwnerID == nil || *hotel.OwnerID != actor.UserID {
+		return domain.NotHotelOwner
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"mcd/domain"
+	"strings"
+)
+
+// CancelOrder - Lets the customer cancel their order until the restaurant accepts it.
+// Reserved stock goes back on sale and a captured payment is marked for refund.
+func (usecase *usecase) CancelOrder(actor domain.AuthClaims, orderID int, request domain.CancelOrderRequest) (domain.Order, error) {
+	return usecase.transitionOrder(actor, orderID, domain.OrderCancelled, strings.TrimSpace(request.Reason))
+}
+
+// RejectOrder - Lets the restaurant turn down an order it has not started preparing.
+// The reason is required so it can be shown to the customer.
+func (usecase *usecase) RejectOrder(actor domain.AuthClaims, orderID int, request domain.CancelOrderRequest) (domain.Order, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return domain.Order{}, domain.RejectionReasonRequired
+	}
+	return usecase.transitionOrder(actor, orderID, domain.OrderRejected, reason)
+}
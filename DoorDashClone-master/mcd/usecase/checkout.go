@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"fmt"
+	"mcd/domain"
+	"sort"
+	"strconv"
+)
+
+// CheckoutCart - Turns the user's cart into an order at a single hotel. The repository
+// decrements stock, stores the order and empties the checked out cart rows atomically, then
+// the order total is authorized at the payment gateway.
+func (usecase *usecase) CheckoutCart(userID int) (domain.Order, error) {
+	cart, err := usecase.repository.GetUserCart(userID)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to get user cart: %v", err)
+	}
+
+	// The same product can be added to the cart more than once
+	quantities := make(map[int]int)
+	for _, item := range cart {
+		quantities[item.ProductID] += item.Quantity
+	}
+	if len(quantities) == 0 {
+		return domain.Order{}, domain.CartEmpty
+	}
+	productIDs := make([]int, 0, len(quantities))
+	for productID := range quantities {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Ints(productIDs)
+
+	products, err := usecase.repository.GetProductDetails(productIDs)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to get product details: %v", err)
+	}
+	if len(products) != len(productIDs) {
+		return domain.Order{}, domain.InvalidOrderProduct
+	}
+
+	user, err := usecase.repository.GetUserById(strconv.Itoa(userID))
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	order := domain.Order{
+		UserID:       userID,
+		HotelID:      products[0].HotelID,
+		PhoneNumber:  user.PhoneNumber,
+		OrderStatus:  domain.OrderPaymentPending,
+		PaymentDueAt: usecase.paymentDueAt(),
+	}
+	for _, product := range products {
+		quantity := quantities[int(product.ID)]
+		if product.HotelID != order.HotelID {
+			return domain.Order{}, domain.CartMultipleHotels
+		}
+		if quantity <= 0 {
+			return domain.Order{}, domain.InvalidOrderProduct
+		}
+		if !product.Available {
+			return domain.Order{}, domain.ProductUnavailable
+		}
+		if product.StockLeft < quantity {
+			return domain.Order{}, domain.InsufficientStock
+		}
+		if len(order.Products) > 0 && !product.Price.SameCurrency(order.OrderTotal) {
+			return domain.Order{}, domain.CurrencyMismatch
+		}
+		order.Products = append(order.Products, domain.OrderProduct{
+			ProductID:       int(product.ID),
+			Quantity:        quantity,
+			PriceAtPurchase: product.Price,
+		})
+		order.OrderTotal = order.OrderTotal.Add(product.Price.Mul(quantity))
+	}
+
+	if err := usecase.ensureHotelOpen(order.HotelID); err != nil {
+		return domain.Order{}, err
+	}
+
+	created, err := usecase.repository.CheckoutCart(order)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to check out cart: %w", err)
+	}
+	return usecase.startPayment(created)
+}
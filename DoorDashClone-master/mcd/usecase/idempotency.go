@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"mcd/domain"
+	"time"
+)
+
+// maxIdempotencyKeyLength matches the idempotency_keys.idempotency_key column
+const maxIdempotencyKeyLength = 255
+
+// BeginIdempotentRequest - Claims the key for a request. The first request with a key gets a new
+// record to complete once it has a response. A retry with the same request gets the stored
+// record back with replay set, while a retry with a different request is refused. A retry of a
+// request still in flight is refused too, until the lease of the first request ends: it is then
+// assumed to have crashed and the retry takes the key over.
+func (usecase *usecase) BeginIdempotentRequest(userID int, key string, requestHash string) (domain.IdempotencyKey, bool, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return domain.IdempotencyKey{}, false, domain.InvalidIdempotencyKey
+	}
+	now := time.Now()
+	record := domain.IdempotencyKey{
+		UserID:         userID,
+		Key:            key,
+		RequestHash:    requestHash,
+		LeaseExpiresAt: now.Add(usecase.options.IdempotencyLease),
+		ExpiresAt:      now.Add(usecase.options.IdempotencyKeyTTL),
+	}
+
+	// An expired record is deleted and the key claimed again, which needs a second attempt
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := usecase.repository.CreateIdempotencyKey(record)
+		if err == nil {
+			return created, false, nil
+		}
+		if !errors.Is(err, domain.IdempotencyKeyInFlight) {
+			return record, false, fmt.Errorf("failed to store idempotency key: %w", err)
+		}
+
+		existing, err := usecase.repository.GetIdempotencyKey(userID, key)
+		if err != nil {
+			return record, false, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+		if now.After(existing.ExpiresAt) {
+			if err := usecase.repository.DeleteIdempotencyKey(existing.ID); err != nil {
+				return record, false, fmt.Errorf("failed to delete expired idempotency key: %w", err)
+			}
+			continue
+		}
+		if existing.RequestHash != requestHash {
+			return record, false, domain.IdempotencyKeyReused
+		}
+		if existing.StatusCode == 0 {
+			if !now.After(existing.LeaseExpiresAt) {
+				return record, false, domain.IdempotencyKeyInFlight
+			}
+			taken, err := usecase.repository.TakeOverIdempotencyKey(existing.ID, now, record.LeaseExpiresAt)
+			if err != nil {
+				return record, false, fmt.Errorf("failed to take over idempotency key: %w", err)
+			}
+			if !taken {
+				return record, false, domain.IdempotencyKeyInFlight
+			}
+			existing.LeaseExpiresAt = record.LeaseExpiresAt
+			return existing, false, nil
+		}
+		return existing, true, nil
+	}
+	return record, false, domain.IdempotencyKeyInFlight
+}
+
+// CompleteIdempotentRequest - Stores the response so retries with the same key replay it
+func (usecase *usecase) CompleteIdempotentRequest(recordID int, statusCode int, responseBody []byte) error {
+	err := usecase.repository.CompleteIdempotencyKey(recordID, statusCode, responseBody)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %v", err)
+	}
+	return nil
+}
+
+// AbandonIdempotentRequest - Releases the key of a request that failed unexpectedly so it can be retried
+func (usecase *usecase) AbandonIdempotentRequest(recordID int) error {
+	err := usecase.repository.DeleteIdempotencyKey(recordID)
+	if err != nil {
+		return fmt.Errorf("failed to abandon idempotency key: %v", err)
+	}
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys - Deletes keys whose replay window has passed
+func (usecase *usecase) PurgeExpiredIdempotencyKeys() (int64, error) {
+	deleted, err := usecase.repository.DeleteExpiredIdempotencyKeys(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired idempotency keys: %v", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mcd/domain"
+	"time"
+)
+
+// paymentDueAt returns the deadline for paying a new order, nil when unpaid orders never expire
+func (usecase *usecase) paymentDueAt() *time.Time {
+	if usecase.options.PaymentTimeout <= 0 {
+		return nil
+	}
+	dueAt := time.Now().Add(usecase.options.PaymentTimeout)
+	return &dueAt
+}
+
+// ReleaseExpiredReservations - Cancels orders that were not paid in time and puts their
+// reserved stock back. Returns how many orders were cancelled.
+func (usecase *usecase) ReleaseExpiredReservations() (int, error) {
+	orderIDs, err := usecase.repository.GetOrdersPastPaymentDue(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired orders: %v", err)
+	}
+
+	released := 0
+	for _, orderID := range orderIDs {
+		err := usecase.repository.CancelOrder(domain.OrderStatusChange{
+			OrderID:    orderID,
+			FromStatus: domain.OrderPaymentPending,
+			ToStatus:   domain.OrderCancelled,
+			Reason:     "payment was not completed in time",
+		})
+		if errors.Is(err, domain.OrderStatusConflict) {
+			// The order moved on since it was read, its stock is no longer ours to release
+			continue
+		}
+		if err != nil {
+			return released, fmt.Errorf("failed to release stock of order %d: %v", orderID, err)
+		}
+		usecase.voidEndedPayment(orderID)
+		log.Printf("Order %d was not paid in time, its stock has been released", orderID)
+		released++
+	}
+	return released, nil
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"fmt"
+	"mcd/domain"
+	"strings"
+)
+
+// journal returns the two balanced ledger entries moving amount out of the credited account into the debited one
+func journal(journalID string, kind domain.LedgerEntryKind, payment domain.Payment, refundID *int, debit, credit domain.LedgerAccount, amount domain.Money) []domain.LedgerEntry {
+	entry := domain.LedgerEntry{
+		JournalID: journalID,
+		OrderID:   payment.OrderID,
+		PaymentID: payment.ID,
+		RefundID:  refundID,
+		Kind:      kind,
+	}
+	// The empty side still names the currency, so every column of the entry is in one currency
+	none := domain.Money{Currency: amount.Currency}
+	debitEntry, creditEntry := entry, entry
+	debitEntry.Account, debitEntry.Debit, debitEntry.Credit = debit, amount, none
+	creditEntry.Account, creditEntry.Debit, creditEntry.Credit = credit, none, amount
+	return []domain.LedgerEntry{debitEntry, creditEntry}
+}
+
+// chargeJournal records the captured payment as money held at the gateway for the customer's order
+func chargeJournal(payment domain.Payment) []domain.LedgerEntry {
+	journalID := fmt.Sprintf("charge-%d", payment.ID)
+	return journal(journalID, domain.LedgerCharge, payment, nil, domain.AccountGateway, domain.AccountCustomerOrders, payment.Amount)
+}
+
+// refundJournal records money going back from the gateway to the customer
+func refundJournal(payment domain.Payment, refund domain.Refund) []domain.LedgerEntry {
+	journalID := fmt.Sprintf("refund-%d", refund.ID)
+	return journal(journalID, domain.LedgerRefund, payment, &refund.ID, domain.AccountCustomerOrders, domain.AccountGateway, refund.Amount)
+}
+
+// ReconcileOrderPayment - Checks that every ledger journal of the order balances and that the
+// ledger agrees with what was captured and refunded through the gateway
+func (usecase *usecase) ReconcileOrderPayment(actor domain.AuthClaims, orderID int) (domain.PaymentReconciliation, error) {
+	if _, err := usecase.getOrderFor(actor, orderID); err != nil {
+		return domain.PaymentReconciliation{}, err
+	}
+	payment, err := usecase.getPayment(orderID)
+	if err != nil {
+		return domain.PaymentReconciliation{}, err
+	}
+	refunds, err := usecase.repository.GetRefunds(payment.ID)
+	if err != nil {
+		return domain.PaymentReconciliation{}, fmt.Errorf("failed to get refunds: %v", err)
+	}
+	entries, err := usecase.repository.GetLedgerEntries(orderID)
+	if err != nil {
+		return domain.PaymentReconciliation{}, fmt.Errorf("failed to get ledger entries: %v", err)
+	}
+
+	// Everything is counted in the payment's currency, an amount in any other unbalances it
+	zero := domain.Money{Currency: payment.Amount.Currency}
+	reconciliation := domain.PaymentReconciliation{
+		OrderID:       orderID,
+		PaymentID:     payment.ID,
+		Captured:      zero,
+		Refunded:      zero,
+		LedgerBalance: zero,
+		Refunds:       refunds,
+		Entries:       entries,
+		Balanced:      true,
+	}
+	if isCaptured(payment.Status) {
+		reconciliation.Captured = payment.Amount
+	}
+	for _, refund := range refunds {
+		if !refund.Amount.SameCurrency(zero) {
+			reconciliation.Balanced = false
+		}
+		if refund.Status == domain.RefundSucceeded {
+			reconciliation.Refunded = reconciliation.Refunded.Add(refund.Amount)
+		}
+	}
+	reconciliation.Refundable = reconciliation.Captured.Sub(reserved(refunds))
+
+	journals := make(map[string]int64)
+	for _, entry := range entries {
+		if !entry.Debit.SameCurrency(zero) || !entry.Credit.SameCurrency(zero) {
+			reconciliation.Balanced = false
+		}
+		journals[entry.JournalID] += entry.Debit.Amount - entry.Credit.Amount
+		if entry.Account == domain.AccountCustomerOrders {
+			reconciliation.LedgerBalance = reconciliation.LedgerBalance.Add(entry.Credit.Sub(entry.Debit))
+		}
+	}
+	for _, balance := range journals {
+		if balance != 0 {
+			reconciliation.Balanced = false
+		}
+	}
+	if reconciliation.LedgerBalance != reconciliation.Captured.Sub(reconciliation.Refunded) ||
+		reconciliation.Refunded.Amount > reconciliation.Captured.Amount {
+		reconciliation.Balanced = false
+	}
+	return reconciliation, nil
+}
+
+// AdjustLedger - Writes a manual correction to the ledger of an order, for finance to bring it
+// back in line with the gateway, and returns the reconciliation of the order with it. The
+// adjustment records who made it and why, it is never updated or deleted.
+func (usecase *usecase) AdjustLedger(actor domain.AuthClaims, orderID int, request domain.LedgerAdjustmentRequest) (domain.PaymentReconciliation, error) {
+	if !actor.Role.HasPermission(domain.PermissionOrderRefund) {
+		return domain.PaymentReconciliation{}, domain.Forbidden
+	}
+	if _, err := usecase.getOrderFor(actor, orderID); err != nil {
+		return domain.PaymentReconciliation{}, err
+	}
+	payment, err := usecase.getPayment(orderID)
+	if err != nil {
+		return domain.PaymentReconciliation{}, err
+	}
+
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Amount.IsZero() || request.Reason == "" || len(request.Reason) > domain.MaxLedgerReasonLength {
+		return domain.PaymentReconciliation{}, domain.InvalidLedgerAdjustment
+	}
+	amount := request.Amount
+	amount.Currency = strings.ToUpper(strings.TrimSpace(amount.Currency))
+	if amount.Currency == "" {
+		amount.Currency = payment.Amount.Currency
+	}
+	if !amount.SameCurrency(payment.Amount) {
+		return domain.PaymentReconciliation{}, domain.CurrencyMismatch
+	}
+
+	// Both sides of an entry are positive, a negative adjustment moves money the other way
+	debit, credit := domain.AccountGateway, domain.AccountCustomerOrders
+	if amount.Amount < 0 {
+		debit, credit = credit, debit
+		amount.Amount = -amount.Amount
+	}
+	token, err := randomToken(12)
+	if err != nil {
+		return domain.PaymentReconciliation{}, fmt.Errorf("failed to generate journal id: %v", err)
+	}
+	entries := journal("adjustment-"+token, domain.LedgerAdjustment, payment, nil, debit, credit, amount)
+	for i := range entries {
+		entries[i].CreatedBy = &actor.UserID
+		entries[i].Reason = request.Reason
+	}
+	if err := usecase.repository.WriteLedgerAdjustment(entries); err != nil {
+		return domain.PaymentReconciliation{}, fmt.Errorf("failed to adjust ledger: %v", err)
+	}
+	return usecase.ReconcileOrderPayment(actor, orderID)
+}
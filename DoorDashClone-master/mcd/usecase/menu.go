@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"mcd/domain"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateMenuItem checks the menu fields of a product. New products need a name, updates
+// leave out the fields they do not change.
+func validateMenuItem(product domain.Product, creating bool) error {
+	if creating && strings.TrimSpace(product.Name) == "" {
+		return domain.InvalidMenuItem
+	}
+	if utf8.RuneCountInString(product.Name) > domain.MaxMenuNameLength ||
+		utf8.RuneCountInString(product.Description) > domain.MaxMenuDescriptionLength ||
+		product.SpiceLevel < 0 || product.SpiceLevel > domain.MaxSpiceLevel || product.StockLeft < 0 {
+		return domain.InvalidMenuItem
+	}
+	if product.ImageURL != "" {
+		imageURL, err := url.Parse(product.ImageURL)
+		if err != nil || (imageURL.Scheme != "http" && imageURL.Scheme != "https") || imageURL.Host == "" ||
+			len(product.ImageURL) > domain.MaxImageURLLength {
+			return domain.InvalidMenuItem
+		}
+	}
+	for _, tag := range product.DietaryTags {
+		if !tag.IsValid() {
+			return domain.InvalidMenuItem
+		}
+	}
+	for _, allergen := range product.Allergens {
+		if !allergen.IsValid() {
+			return domain.InvalidMenuItem
+		}
+	}
+	return nil
+}
+
+// ensureMenuSection checks that the section, when given, belongs to the hotel
+func (usecase *usecase) ensureMenuSection(hotelID int, sectionID *int) error {
+	if sectionID == nil {
+		return nil
+	}
+	_, err := usecase.getMenuSection(hotelID, *sectionID)
+	return err
+}
+
+// getMenuSection fetches a section of the hotel's menu
+func (usecase *usecase) getMenuSection(hotelID, sectionID int) (domain.MenuSection, error) {
+	sections, err := usecase.repository.GetMenuSections(hotelID)
+	if err != nil {
+		return domain.MenuSection{}, fmt.Errorf("failed to get menu sections: %v", err)
+	}
+	for _, section := range sections {
+		if section.ID == sectionID {
+			return section, nil
+		}
+	}
+	return domain.MenuSection{}, domain.MenuSectionNotFound
+}
+
+// validateMenuSection checks the fields of a section being created or updated
+func validateMenuSection(section domain.MenuSection) error {
+	if strings.TrimSpace(section.Name) == "" || utf8.RuneCountInString(section.Name) > domain.MaxMenuNameLength ||
+		utf8.RuneCountInString(section.Description) > domain.MaxMenuDescriptionLength {
+		return domain.InvalidMenuSection
+	}
+	return nil
+}
+
+// CreateMenuSection - Adds a section to the menu of a hotel managed by the actor
+func (usecase *usecase) CreateMenuSection(actor domain.AuthClaims, section domain.MenuSection) (domain.MenuSection, error) {
+	if err := usecase.authorizeHotelOwner(actor, section.HotelID); err != nil {
+		return domain.MenuSection{}, err
+	}
+	if err := validateMenuSection(section); err != nil {
+		return domain.MenuSection{}, err
+	}
+	created, err := usecase.repository.CreateMenuSection(section)
+	if err != nil {
+		return domain.MenuSection{}, fmt.Errorf("failed to create menu section: %v", err)
+	}
+	return created, nil
+}
+
+// UpdateMenuSection - Renames, describes or moves a section of the menu of a hotel managed by
+// the actor. The name, description and position are all replaced.
+func (usecase *usecase) UpdateMenuSection(actor domain.AuthClaims, section domain.MenuSection) (domain.MenuSection, error) {
+	if err := usecase.authorizeHotelOwner(actor, section.HotelID); err != nil {
+		return domain.MenuSection{}, err
+	}
+	if err := validateMenuSection(section); err != nil {
+		return domain.MenuSection{}, err
+	}
+	if _, err := usecase.getMenuSection(section.HotelID, section.ID); err != nil {
+		return domain.MenuSection{}, err
+	}
+	updated, err := usecase.repository.UpdateMenuSection(section)
+	if err != nil {
+		return domain.MenuSection{}, fmt.Errorf("failed to update menu section: %v", err)
+	}
+	return updated, nil
+}
+
+// DeleteMenuSection - Deletes a section of the menu of a hotel managed by the actor. Its
+// products stay on the menu under OtherMenuSection.
+func (usecase *usecase) DeleteMenuSection(actor domain.AuthClaims, hotelID, sectionID int) error {
+	if err := usecase.authorizeHotelOwner(actor, hotelID); err != nil {
+		return err
+	}
+	if _, err := usecase.getMenuSection(hotelID, sectionID); err != nil {
+		return err
+	}
+	if err := usecase.repository.DeleteMenuSection(sectionID); err != nil {
+		return fmt.Errorf("failed to delete menu section: %v", err)
+	}
+	return nil
+}
+
+// SetProductAvailability - Takes a product of a hotel managed by the actor off or back on
+// sale. Products off sale stay on the menu but cannot be ordered.
+func (usecase *usecase) SetProductAvailability(actor domain.AuthClaims, productID int, available bool) error {
+	product, err := usecase.repository.GetProductById(strconv.Itoa(productID))
+	if err != nil {
+		log.Printf("Error getting product %d: %v", productID, err)
+		return domain.ProductNotFound
+	}
+	if err := usecase.authorizeHotelOwner(actor, product.HotelID); err != nil {
+		return err
+	}
+	if err := usecase.repository.SetProductAvailable(productID, available); err != nil {
+		return fmt.Errorf("failed to set product availability: %v", err)
+	}
+	return nil
+}
+
+// GetMenu - Fetches a page of a hotel's menu: its products filtered like GetProductsByHotel,
+// section by section in menu order and sorted within each section. The sections are read one
+// after the other until the page is full, and the cursor remembers the section it stopped in.
+func (usecase *usecase) GetMenu(query domain.ProductQuery, page domain.PageRequest) (domain.Menu, error) {
+	options, err := productListOptions(query, page)
+	if err != nil {
+		return domain.Menu{}, err
+	}
+	sections, err := usecase.repository.GetMenuSections(query.HotelID)
+	if err != nil {
+		return domain.Menu{}, fmt.Errorf("failed to get menu sections: %v", err)
+	}
+	sections = append(sections, domain.MenuSection{HotelID: query.HotelID, Name: domain.OtherMenuSection})
+
+	first := 0
+	if options.After != nil {
+		first = slices.IndexFunc(sections, func(section domain.MenuSection) bool { return section.ID == options.After.Section })
+		if first < 0 {
+			return domain.Menu{}, domain.InvalidCursor
+		}
+	}
+
+	// listOptions asks for one product more than the page holds, to know whether another follows
+	menu := domain.Menu{HotelID: query.HotelID, Sections: []domain.MenuSection{}}
+	remaining := options.Limit
+	for i := first; i < len(sections) && remaining > 0; i++ {
+		sectionQuery := query
+		sectionQuery.Section = &sections[i].ID
+		sectionQuery.ListOptions = domain.ListOptions{Sort: options.Sort, Limit: remaining}
+		if i == first {
+			sectionQuery.After = options.After
+		}
+		products, err := usecase.repository.GetProductsByHotel(sectionQuery)
+		if err != nil {
+			return domain.Menu{}, fmt.Errorf("failed to get products by hotel: %v", err)
+		}
+		if len(products) == 0 {
+			continue
+		}
+		remaining -= len(products)
+		section := sections[i]
+		section.Products = products
+		menu.Sections = append(menu.Sections, section)
+	}
+	if remaining > 0 {
+		return menu, nil
+	}
+
+	last := &menu.Sections[len(menu.Sections)-1]
+	last.Products = last.Products[:len(last.Products)-1]
+	if len(last.Products) == 0 {
+		menu.Sections = menu.Sections[:len(menu.Sections)-1]
+		last = &menu.Sections[len(menu.Sections)-1]
+	}
+	product := last.Products[len(last.Products)-1]
+	menu.NextCursor = domain.Cursor{
+		Sort:    options.Sort.String(),
+		Key:     productSortKey(product, options.Sort.Field),
+		ID:      int(product.ID),
+		Section: last.ID,
+	}.Encode()
+	return menu, nil
+}
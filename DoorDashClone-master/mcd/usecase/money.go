@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"mcd/domain"
+	"strings"
+)
+
+// normalizePrice puts a price in the configured currency when none is given and checks the code
+// and the amount. Free items are allowed, negative prices are not.
+func (usecase *usecase) normalizePrice(price domain.Money) (domain.Money, error) {
+	if price.Amount < 0 {
+		return price, domain.InvalidPrice
+	}
+	price.Currency = strings.ToUpper(strings.TrimSpace(price.Currency))
+	if price.Currency == "" {
+		price.Currency = usecase.options.Currency
+	}
+	if !domain.ValidCurrency(price.Currency) {
+		return price, domain.InvalidCurrency
+	}
+	return price, nil
+}
+
+// matchesPrice reports whether an amount sent by the client agrees with the server's. A client
+// that leaves out the currency is taken to mean the server's currency.
+func matchesPrice(expected domain.Money, sent domain.Money) bool {
+	if sent.Currency != "" && !strings.EqualFold(sent.Currency, expected.Currency) {
+		return false
+	}
+	return sent.Amount == expected.Amount
+}
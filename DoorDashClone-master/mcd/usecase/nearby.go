@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"mcd/domain"
+	"sort"
+)
+
+const (
+	defaultSearchRadiusKm = 10.0
+	maxSearchRadiusKm     = 50.0
+)
+
+// validateHotelLocation checks that a hotel's coordinates come in pairs and lie on the globe
+func validateHotelLocation(hotel domain.Hotel) error {
+	if (hotel.Latitude == nil) != (hotel.Longitude == nil) || (hotel.DeliveryRadiusKm != nil && *hotel.DeliveryRadiusKm < 0) {
+		return domain.InvalidLocation
+	}
+	if hotel.Latitude != nil && !domain.ValidCoordinates(*hotel.Latitude, *hotel.Longitude) {
+		return domain.InvalidLocation
+	}
+	return nil
+}
+
+// GetNearbyHotels - Fetches the hotels within radiusKm of the location, nearest first. A zero
+// radius searches the default radius. Hotels whose delivery radius does not reach the location
+// are flagged as pickup only. At most domain.MaxNearbyHotels of the nearest are returned.
+func (usecase *usecase) GetNearbyHotels(latitude, longitude, radiusKm float64) ([]domain.NearbyHotel, error) {
+	if !domain.ValidCoordinates(latitude, longitude) {
+		return nil, domain.InvalidLocation
+	}
+	if radiusKm == 0 {
+		radiusKm = defaultSearchRadiusKm
+	}
+	if radiusKm < 0 || radiusKm > maxSearchRadiusKm || math.IsNaN(radiusKm) {
+		return nil, domain.InvalidSearchRadius
+	}
+
+	hotels, err := usecase.repository.GetHotelsWithin(domain.BoundsAround(latitude, longitude, radiusKm))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hotels: %v", err)
+	}
+	nearby := make([]domain.NearbyHotel, 0, len(hotels))
+	for _, hotel := range hotels {
+		distance := domain.DistanceKm(latitude, longitude, *hotel.Latitude, *hotel.Longitude)
+		if distance > radiusKm {
+			continue
+		}
+		nearby = append(nearby, domain.NearbyHotel{
+			Hotel:      hotel,
+			DistanceKm: math.Round(distance*1000) / 1000,
+			PickupOnly: hotel.DeliveryRadiusKm == nil || distance > *hotel.DeliveryRadiusKm,
+		})
+	}
+	sort.SliceStable(nearby, func(i, j int) bool { return nearby[i].DistanceKm < nearby[j].DistanceKm })
+	if len(nearby) > domain.MaxNearbyHotels {
+		nearby = nearby[:domain.MaxNearbyHotels]
+	}
+
+	within := make([]domain.Hotel, len(nearby))
+	for i := range nearby {
+		within[i] = nearby[i].Hotel
+	}
+	if err := usecase.withAvailability(within); err != nil {
+		return nil, err
+	}
+	for i := range nearby {
+		nearby[i].Hotel = within[i]
+	}
+	return nearby, nil
+}
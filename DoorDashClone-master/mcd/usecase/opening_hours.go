@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"fmt"
+	"mcd/domain"
+	"sort"
+	"strings"
+	"time"
+)
+
+// availabilityHorizon is how far ahead the next opening of a closed hotel is looked for
+const availabilityHorizon = 14 * 24 * time.Hour
+
+// openPeriod is a concrete span of time in which a hotel is open
+type openPeriod struct {
+	start time.Time
+	end   time.Time
+}
+
+// clockMinutes returns the minutes since midnight of an HH:MM time
+func clockMinutes(clock string) (int, error) {
+	parsed, err := time.Parse(domain.ClockLayout, clock)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// validateSchedule checks the weekdays and times of the schedule and returns its time zone
+func validateSchedule(schedule domain.OpeningSchedule) (string, error) {
+	timeZone := strings.TrimSpace(schedule.TimeZone)
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return "", domain.InvalidTimeZone
+	}
+	for _, period := range schedule.Hours {
+		if period.Weekday < 0 || period.Weekday > 6 {
+			return "", domain.InvalidOpeningHours
+		}
+		if _, err := clockMinutes(period.Opens); err != nil {
+			return "", domain.InvalidOpeningHours
+		}
+		if _, err := clockMinutes(period.Closes); err != nil {
+			return "", domain.InvalidOpeningHours
+		}
+	}
+	return timeZone, nil
+}
+
+// weeklyPeriods lays the weekly hours out on the calendar between from and until, merging
+// periods that touch. Without any hours the hotel is open the whole time.
+func weeklyPeriods(hours []domain.OpeningHours, location *time.Location, from, until time.Time) []openPeriod {
+	if len(hours) == 0 {
+		return []openPeriod{{from, until}}
+	}
+
+	var periods []openPeriod
+	local := from.In(location)
+	// Start a day early for periods that run past midnight into today
+	for day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, location); day.Before(until); day = day.AddDate(0, 0, 1) {
+		for _, period := range hours {
+			if time.Weekday(period.Weekday) != day.Weekday() {
+				continue
+			}
+			opens, _ := clockMinutes(period.Opens)
+			closes, _ := clockMinutes(period.Closes)
+			start := time.Date(day.Year(), day.Month(), day.Day(), opens/60, opens%60, 0, 0, location)
+			closingDay := day
+			if closes <= opens {
+				closingDay = day.AddDate(0, 0, 1)
+			}
+			end := time.Date(closingDay.Year(), closingDay.Month(), closingDay.Day(), closes/60, closes%60, 0, 0, location)
+			if end.After(from) && start.Before(until) {
+				periods = append(periods, openPeriod{start, end})
+			}
+		}
+	}
+
+	sort.Slice(periods, func(i, j int) bool { return periods[i].start.Before(periods[j].start) })
+	merged := periods[:0]
+	for _, period := range periods {
+		if last := len(merged) - 1; last >= 0 && !period.start.After(merged[last].end) {
+			if period.end.After(merged[last].end) {
+				merged[last].end = period.end
+			}
+			continue
+		}
+		merged = append(merged, period)
+	}
+	return merged
+}
+
+// withoutClosures cuts the closures out of the open periods
+func withoutClosures(periods []openPeriod, closures []domain.HotelClosure) []openPeriod {
+	for _, closure := range closures {
+		kept := make([]openPeriod, 0, len(periods)+1)
+		for _, period := range periods {
+			if !closure.StartsAt.Before(period.end) || !closure.EndsAt.After(period.start) {
+				kept = append(kept, period)
+				continue
+			}
+			if period.start.Before(closure.StartsAt) {
+				kept = append(kept, openPeriod{period.start, closure.StartsAt})
+			}
+			if closure.EndsAt.Before(period.end) {
+				kept = append(kept, openPeriod{closure.EndsAt, period.end})
+			}
+		}
+		periods = kept
+	}
+	return periods
+}
+
+// hotelAvailability reports whether the hotel takes orders at now and, if not, when it opens
+// next within the availability horizon. A paused hotel has no known next opening.
+func hotelAvailability(hotel domain.Hotel, hours []domain.OpeningHours, closures []domain.HotelClosure, now time.Time) (bool, *time.Time) {
+	if hotel.Paused {
+		return false, nil
+	}
+	location, err := time.LoadLocation(hotel.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	periods := withoutClosures(weeklyPeriods(hours, location, now, now.Add(availabilityHorizon)), closures)
+	for _, period := range periods {
+		if !period.end.After(now) {
+			continue
+		}
+		if !period.start.After(now) {
+			return true, nil
+		}
+		opensAt := period.start
+		return false, &opensAt
+	}
+	return false, nil
+}
+
+// withAvailability fills in whether each hotel is open and when it opens next, reading the
+// opening hours and closures of all of them in two queries
+func (usecase *usecase) withAvailability(hotels []domain.Hotel) error {
+	if len(hotels) == 0 {
+		return nil
+	}
+	now := time.Now()
+	hotelIDs := make([]int, len(hotels))
+	for i, hotel := range hotels {
+		hotelIDs[i] = int(hotel.ID)
+	}
+	hours, err := usecase.repository.GetOpeningHours(hotelIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get opening hours: %v", err)
+	}
+	closures, err := usecase.repository.GetHotelClosures(hotelIDs, now)
+	if err != nil {
+		return fmt.Errorf("failed to get hotel closures: %v", err)
+	}
+	for i := range hotels {
+		hotelID := int(hotels[i].ID)
+		hotels[i].IsOpen, hotels[i].NextOpensAt = hotelAvailability(hotels[i], hours[hotelID], closures[hotelID], now)
+	}
+	return nil
+}
+
+// ensureHotelOpen refuses orders for a hotel that is closed, paused or unknown
+func (usecase *usecase) ensureHotelOpen(hotelID int) error {
+	hotel, err := usecase.repository.GetHotelByID(hotelID)
+	if err != nil {
+		return domain.HotelNotFound
+	}
+	available := []domain.Hotel{*hotel}
+	if err := usecase.withAvailability(available); err != nil {
+		return err
+	}
+	if !available[0].IsOpen {
+		return domain.HotelClosed
+	}
+	return nil
+}
+
+// SetOpeningHours - Replaces the weekly opening hours and time zone of a hotel the actor manages
+func (usecase *usecase) SetOpeningHours(actor domain.AuthClaims, hotelID int, schedule domain.OpeningSchedule) error {
+	if err := usecase.authorizeHotelOwner(actor, hotelID); err != nil {
+		return err
+	}
+	timeZone, err := validateSchedule(schedule)
+	if err != nil {
+		return err
+	}
+	if err := usecase.repository.ReplaceOpeningHours(hotelID, timeZone, schedule.Hours); err != nil {
+		return fmt.Errorf("failed to set opening hours: %v", err)
+	}
+	return nil
+}
+
+// AddHotelClosure - Closes a hotel the actor manages for a one-off period, such as a holiday
+func (usecase *usecase) AddHotelClosure(actor domain.AuthClaims, hotelID int, request domain.HotelClosureRequest) (domain.HotelClosure, error) {
+	if err := usecase.authorizeHotelOwner(actor, hotelID); err != nil {
+		return domain.HotelClosure{}, err
+	}
+	if !request.EndsAt.After(request.StartsAt) || !request.EndsAt.After(time.Now()) {
+		return domain.HotelClosure{}, domain.InvalidClosure
+	}
+	closure, err := usecase.repository.CreateHotelClosure(domain.HotelClosure{
+		HotelID:   hotelID,
+		StartsAt:  request.StartsAt,
+		EndsAt:    request.EndsAt,
+		Reason:    strings.TrimSpace(request.Reason),
+		CreatedBy: actor.UserID,
+	})
+	if err != nil {
+		return domain.HotelClosure{}, fmt.Errorf("failed to add hotel closure: %v", err)
+	}
+	return closure, nil
+}
+
+// SetHotelPaused - Stops or resumes taking orders at a hotel the actor manages, whatever its hours
+func (usecase *usecase) SetHotelPaused(actor domain.AuthClaims, hotelID int, paused bool) error {
+	if err := usecase.authorizeHotelOwner(actor, hotelID); err != nil {
+		return err
+	}
+	if err := usecase.repository.SetHotelPaused(hotelID, paused); err != nil {
+		return fmt.Errorf("failed to pause hotel: %v", err)
+	}
+	return nil
+}
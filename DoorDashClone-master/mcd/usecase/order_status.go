@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"mcd/domain"
+	"strings"
+)
+
+// orderActor is who may make a status transition, on top of the permission it requires
+type orderActor int
+
+const (
+	actorRestaurant orderActor = iota // Owner of the hotel the order was placed at
+	actorCourier                      // Any courier for pickup, then only the courier who picked it up
+	actorCustomer                     // The user who placed the order
+	actorStaff                        // Admins only
+)
+
+type orderTransition struct {
+	actor      orderActor
+	permission domain.Permission
+}
+
+// orderTransitions is the order state machine, every transition not listed here is refused.
+// Admins hold every permission and pass every actor check, so they can make any listed transition.
+// Orders leave payment_pending for placed only through CapturePayment.
+var orderTransitions = map[domain.OrderStatus]map[domain.OrderStatus]orderTransition{
+	domain.OrderPaymentPending: {
+		domain.OrderCancelled: {actorCustomer, domain.PermissionOrderPlace},
+	},
+	domain.OrderPlaced: {
+		domain.OrderAccepted:  {actorRestaurant, domain.PermissionOrderFulfil},
+		domain.OrderRejected:  {actorRestaurant, domain.PermissionOrderFulfil},
+		domain.OrderCancelled: {actorCustomer, domain.PermissionOrderPlace},
+	},
+	domain.OrderAccepted: {
+		domain.OrderPreparing: {actorRestaurant, domain.PermissionOrderFulfil},
+		domain.OrderRejected:  {actorRestaurant, domain.PermissionOrderFulfil},
+	},
+	domain.OrderPreparing: {
+		domain.OrderReady: {actorRestaurant, domain.PermissionOrderFulfil},
+	},
+	domain.OrderReady: {
+		domain.OrderPickedUp: {actorCourier, domain.PermissionOrderDeliver},
+	},
+	domain.OrderPickedUp: {
+		domain.OrderDelivered: {actorCourier, domain.PermissionOrderDeliver},
+	},
+	domain.OrderDelivered: {
+		domain.OrderRefunded: {actorStaff, domain.PermissionOrderRefund},
+	},
+	domain.OrderCancelled: {
+		domain.OrderRefunded: {actorStaff, domain.PermissionOrderRefund},
+	},
+	domain.OrderRejected: {
+		domain.OrderRefunded: {actorStaff, domain.PermissionOrderRefund},
+	},
+}
+
+// releasesStock reports whether moving into the status gives the order's reserved stock back
+func releasesStock(status domain.OrderStatus) bool {
+	return status == domain.OrderCancelled || status == domain.OrderRejected
+}
+
+func isKnownOrderStatus(status domain.OrderStatus) bool {
+	if _, ok := orderTransitions[status]; ok {
+		return true
+	}
+	for _, next := range orderTransitions {
+		if _, ok := next[status]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getOrderFor loads the order and checks the actor takes part in it, an order the actor has
+// nothing to do with is reported as not found so order IDs cannot be probed. Couriers see any
+// order not picked up yet, but only through its pickup view until one of them is assigned.
+func (usecase *usecase) getOrderFor(actor domain.AuthClaims, orderID int) (domain.Order, error) {
+	order, err := usecase.repository.GetOrderByID(orderID)
+	if err != nil {
+		log.Printf("Error getting order %d: %v", orderID, err)
+		return order, domain.OrderNotFound
+	}
+
+	switch {
+	case actor.Role == domain.RoleAdmin:
+		return order, nil
+	case actor.Role == domain.RoleCustomer && order.UserID == actor.UserID:
+		return order, nil
+	case actor.Role == domain.RoleCourier && order.CourierID == nil:
+		return pickupView(order), nil
+	case actor.Role == domain.RoleCourier && *order.CourierID == actor.UserID:
+		return order, nil
+	case actor.Role == domain.RoleRestaurantOwner && usecase.authorizeHotelOwner(actor, order.HotelID) == nil:
+		return order, nil
+	}
+	return domain.Order{}, domain.OrderNotFound
+}
+
+// pickupView leaves out who placed the order, how to reach them and how they paid, which an
+// unassigned courier has no need for
+func pickupView(order domain.Order) domain.Order {
+	return domain.Order{
+		ID:          order.ID,
+		HotelID:     order.HotelID,
+		OrderStatus: order.OrderStatus,
+		IsDelivered: order.IsDelivered,
+		CreatedAt:   order.CreatedAt,
+		UpdatedAt:   order.UpdatedAt,
+		Products:    order.Products,
+	}
+}
+
+// isPickupView reports whether getOrderFor gave the actor the pickup view of the order
+func isPickupView(actor domain.AuthClaims, order domain.Order) bool {
+	return actor.Role == domain.RoleCourier && order.CourierID == nil
+}
+
+// authorizeTransition checks that the actor may move the order along the transition
+func authorizeTransition(actor domain.AuthClaims, order domain.Order, transition orderTransition) error {
+	if !actor.Role.HasPermission(transition.permission) {
+		return domain.Forbidden
+	}
+	if actor.Role == domain.RoleAdmin {
+		return nil
+	}
+
+	switch transition.actor {
+	case actorRestaurant:
+		// getOrderFor already checked that a restaurant owner owns the order's hotel
+		if actor.Role == domain.RoleRestaurantOwner {
+			return nil
+		}
+	case actorCourier:
+		if order.CourierID == nil || *order.CourierID == actor.UserID {
+			return nil
+		}
+	case actorCustomer:
+		if order.UserID == actor.UserID {
+			return nil
+		}
+	}
+	return domain.Forbidden
+}
+
+// UpdateOrderStatus - Moves an order along the order state machine and records the change.
+// Cancelling or rejecting an order puts its reserved stock back, refunding it returns the payment.
+func (usecase *usecase) UpdateOrderStatus(actor domain.AuthClaims, orderID int, request domain.UpdateOrderStatusRequest) (domain.Order, error) {
+	switch request.Status {
+	case domain.OrderCancelled:
+		return usecase.CancelOrder(actor, orderID, domain.CancelOrderRequest{Reason: request.Reason})
+	case domain.OrderRejected:
+		return usecase.RejectOrder(actor, orderID, domain.CancelOrderRequest{Reason: request.Reason})
+	case domain.OrderRefunded:
+		return usecase.refundOrderInFull(actor, orderID, request.Reason)
+	}
+	if !isKnownOrderStatus(request.Status) {
+		return domain.Order{}, domain.InvalidOrderStatus
+	}
+	return usecase.transitionOrder(actor, orderID, request.Status, request.Reason)
+}
+
+// transitionOrder moves the order to the given status if the state machine and the actor allow it
+func (usecase *usecase) transitionOrder(actor domain.AuthClaims, orderID int, status domain.OrderStatus, reason string) (domain.Order, error) {
+	order, err := usecase.getOrderFor(actor, orderID)
+	if err != nil {
+		return order, err
+	}
+
+	transition, ok := orderTransitions[order.OrderStatus][status]
+	if !ok {
+		return domain.Order{}, refusedTransition(order.OrderStatus, status)
+	}
+	if err := authorizeTransition(actor, order, transition); err != nil {
+		return domain.Order{}, err
+	}
+
+	change := domain.OrderStatusChange{
+		OrderID:       order.ID,
+		FromStatus:    order.OrderStatus,
+		ToStatus:      status,
+		ChangedBy:     &actor.UserID,
+		ChangedByRole: actor.Role,
+		Reason:        reason,
+	}
+	if releasesStock(status) {
+		err = usecase.repository.CancelOrder(change)
+	} else {
+		err = usecase.repository.UpdateOrderStatus(change)
+	}
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to update order status: %w", err)
+	}
+	if releasesStock(status) {
+		usecase.voidEndedPayment(orderID)
+	}
+
+	updated, err := usecase.repository.GetOrderByID(orderID)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to get order: %v", err)
+	}
+	return updated, nil
+}
+
+// refusedTransition explains why the order cannot move from one status to the other
+func refusedTransition(from, to domain.OrderStatus) error {
+	if to != domain.OrderCancelled {
+		return domain.OrderStatusConflict
+	}
+	refused := domain.CancellationRefused
+	switch from {
+	case domain.OrderCancelled, domain.OrderRejected, domain.OrderRefunded:
+		refused.ErrorDescription = fmt.Sprintf("order is already %s", from)
+	default:
+		refused.ErrorDescription = fmt.Sprintf("order is already %s, %s", strings.ReplaceAll(string(from), "_", " "), domain.CancellationRefused.ErrorDescription)
+	}
+	return refused
+}
+
+// GetOrderStatusHistory - Lists every status change of an order the actor takes part in, oldest first
+func (usecase *usecase) GetOrderStatusHistory(actor domain.AuthClaims, orderID int) ([]domain.OrderStatusChange, error) {
+	order, err := usecase.getOrderFor(actor, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if isPickupView(actor, order) {
+		return nil, domain.OrderNotFound
+	}
+	history, err := usecase.repository.GetOrderStatusHistory(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order status history: %v", err)
+	}
+	return history, nil
+}
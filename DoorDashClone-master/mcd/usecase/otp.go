@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"mcd/domain"
+	"strconv"
+	"time"
+)
+
+const (
+	otpTTL            = 5 * time.Minute
+	otpResendInterval = time.Minute
+	otpMaxAttempts    = 5
+)
+
+// generateOTPCode returns a random six digit code
+func generateOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashOTPCode binds the code to the user so equal codes never share a hash
+func hashOTPCode(userID int, code string) string {
+	return hashToken(strconv.Itoa(userID) + ":" + code)
+}
+
+// SendOTP - Issues a new OTP for the user and delivers it on the requested channel
+func (usecase *usecase) SendOTP(request domain.SendOTPRequest) error {
+	if request.Channel == "" {
+		request.Channel = domain.ChannelEmail
+	}
+	if request.Channel != domain.ChannelEmail && request.Channel != domain.ChannelSMS {
+		return domain.InvalidOTPChannel
+	}
+
+	user, err := usecase.repository.GetUserByEmail(request.Email)
+	if err != nil {
+		// Do not reveal whether an account exists for the email
+		log.Printf("Error getting user for otp: %v", err)
+		return nil
+	}
+	if user.IsVerified {
+		return domain.UserAlreadyVerified
+	}
+
+	latest, err := usecase.repository.GetLatestOTP(int(user.ID))
+	if err == nil && time.Since(latest.CreatedAt) < otpResendInterval {
+		return domain.OTPResendTooSoon
+	}
+
+	destination := user.Email
+	if request.Channel == domain.ChannelSMS {
+		destination = user.PhoneNumber
+	}
+
+	code, err := generateOTPCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate otp: %v", err)
+	}
+	otp := domain.OTP{
+		UserID:      int(user.ID),
+		Channel:     request.Channel,
+		Destination: destination,
+		CodeHash:    hashOTPCode(int(user.ID), code),
+		MaxAttempts: otpMaxAttempts,
+		ExpiresAt:   time.Now().Add(otpTTL),
+	}
+	if err := usecase.repository.CreateOTP(otp); err != nil {
+		return fmt.Errorf("failed to store otp: %v", err)
+	}
+
+	err = usecase.options.Notifier.Send(domain.Notification{
+		Channel: request.Channel,
+		To:      destination,
+		Subject: "Otp - Quick Byte",
+		Body:    fmt.Sprintf("Your Otp for QuickByte is %s. It expires in %d minutes.", code, int(otpTTL.Minutes())),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send otp: %v", err)
+	}
+	return nil
+}
+
+// VerifyOTP - Checks the latest OTP of the user and marks the user verified on success
+func (usecase *usecase) VerifyOTP(request domain.VerifyOTPRequest) error {
+	user, err := usecase.repository.GetUserByEmail(request.Email)
+	if err != nil {
+		log.Printf("Error getting user for otp verification: %v", err)
+		return domain.InvalidOTP
+	}
+
+	otp, err := usecase.repository.GetLatestOTP(int(user.ID))
+	if err != nil || otp.ConsumedAt != nil {
+		return domain.InvalidOTP
+	}
+	if time.Now().After(otp.ExpiresAt) {
+		return domain.OTPExpired
+	}
+
+	// Every attempt is counted before the code is compared, so concurrent guesses cannot all
+	// slip in under the limit
+	if err := usecase.repository.IncrementOTPAttempts(otp.ID); err != nil {
+		if errors.Is(err, domain.OTPAttemptsExceeded) {
+			return domain.OTPAttemptsExceeded
+		}
+		return fmt.Errorf("failed to record otp attempt: %v", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(hashOTPCode(int(user.ID), request.Code))) != 1 {
+		return domain.InvalidOTP
+	}
+
+	if err := usecase.repository.ConsumeOTP(otp.ID); err != nil {
+		return err
+	}
+	if err := usecase.repository.MarkUserVerified(int(user.ID)); err != nil {
+		return fmt.Errorf("failed to verify user: %v", err)
+	}
+	return nil
+}
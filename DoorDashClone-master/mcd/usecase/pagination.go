@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"mcd/domain"
+	"slices"
+)
+
+// listOptions resolves the page a client asked for. The page size defaults to
+// domain.DefaultPageSize and is capped to domain.MaxPageSize; one extra row is requested to
+// learn whether another page follows. A cursor is only valid with the sort it was issued for.
+func listOptions(request domain.PageRequest, sortable []string, fallback domain.Sort) (domain.ListOptions, error) {
+	sort := domain.ParseSort(request.Sort, fallback)
+	if !slices.Contains(sortable, sort.Field) {
+		return domain.ListOptions{}, domain.InvalidSort
+	}
+
+	size := request.Limit
+	if size <= 0 {
+		size = domain.DefaultPageSize
+	}
+	options := domain.ListOptions{Sort: sort, Limit: min(size, domain.MaxPageSize) + 1}
+
+	if request.Cursor != "" {
+		cursor, err := domain.DecodeCursor(request.Cursor)
+		if err != nil {
+			return domain.ListOptions{}, err
+		}
+		if cursor.Sort != sort.String() {
+			return domain.ListOptions{}, domain.InvalidCursor
+		}
+		options.After = &cursor
+	}
+	return options, nil
+}
+
+// newPage trims the extra row fetched by listOptions and, when it was there, points the next
+// cursor at the last item of the page. key returns the sort key of an item as a cursor key.
+func newPage[T any](items []T, options domain.ListOptions, id func(T) int, key func(T, string) string) domain.Page[T] {
+	page := domain.Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if size := options.Limit - 1; len(items) > size {
+		page.Items = items[:size]
+		last := page.Items[size-1]
+		page.NextCursor = domain.Cursor{
+			Sort: options.Sort.String(),
+			Key:  key(last, options.Sort.Field),
+			ID:   id(last),
+		}.Encode()
+	}
+	return page
+}
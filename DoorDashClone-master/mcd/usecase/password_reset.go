@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"mcd/domain"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = 30 * time.Minute
+
+// RequestPasswordReset - Issues a single use reset token and delivers it to the user's email
+func (usecase *usecase) RequestPasswordReset(request domain.PasswordResetRequest) error {
+	user, err := usecase.repository.GetUserByEmail(request.Email)
+	if err != nil {
+		// Do not reveal whether an account exists for the email
+		log.Printf("Error getting user for password reset: %v", err)
+		return nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %v", err)
+	}
+	reset := domain.PasswordReset{
+		UserID:    int(user.ID),
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	if err := usecase.repository.CreatePasswordReset(reset); err != nil {
+		return fmt.Errorf("failed to store password reset: %v", err)
+	}
+
+	err = usecase.options.Notifier.Send(domain.Notification{
+		Channel: domain.ChannelEmail,
+		To:      user.Email,
+		Subject: "Reset your QuickByte password",
+		Body:    fmt.Sprintf("Use this code to reset your password: %s. It expires in %d minutes.", token, int(passwordResetTTL.Minutes())),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send password reset: %v", err)
+	}
+	return nil
+}
+
+// ResetPassword - Sets a new password with a reset token and signs the user out of every session
+func (usecase *usecase) ResetPassword(request domain.PasswordResetConfirm) error {
+	if request.Token == "" {
+		return domain.InvalidResetToken
+	}
+	if err := validatePassword(request.NewPassword); err != nil {
+		return err
+	}
+
+	reset, err := usecase.repository.GetPasswordResetByHash(hashToken(request.Token))
+	if err != nil {
+		log.Printf("Error looking up password reset: %v", err)
+		return domain.InvalidResetToken
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return domain.InvalidResetToken
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(request.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return usecase.repository.ResetPassword(reset.ID, reset.UserID, string(password))
+}
@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mcd/domain"
+	"time"
+)
+
+// startPayment authorizes the order total at the gateway and stores the payment intent. If the
+// payment cannot be started, the order is cancelled straight away so its reserved stock goes
+// back on sale, and an authorization that was already given is voided.
+func (usecase *usecase) startPayment(order domain.Order) (domain.Order, error) {
+	amount := order.OrderTotal
+	transactionID, err := usecase.options.PaymentGateway.Authorize(domain.PaymentIntent{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Amount:  amount,
+	})
+	if err != nil {
+		usecase.abandonPayment(order.ID, "payment could not be authorized")
+		if errors.Is(err, domain.PaymentDeclined) {
+			return domain.Order{}, domain.PaymentDeclined
+		}
+		return domain.Order{}, fmt.Errorf("failed to authorize payment: %v", err)
+	}
+
+	payment, err := usecase.repository.CreatePayment(domain.Payment{
+		UserID:        order.UserID,
+		OrderID:       order.ID,
+		Amount:        amount,
+		TransactionID: transactionID,
+		Status:        domain.PaymentAuthorized,
+	})
+	if err != nil {
+		if voidErr := usecase.options.PaymentGateway.Void(transactionID); voidErr != nil {
+			log.Printf("Error voiding authorization %s of order %d, it stays held at the gateway: %v", transactionID, order.ID, voidErr)
+		}
+		usecase.abandonPayment(order.ID, "payment could not be recorded")
+		return domain.Order{}, fmt.Errorf("failed to create payment: %v", err)
+	}
+	order.Payment = &payment
+	return order, nil
+}
+
+// abandonPayment cancels an order whose payment could not be started, releasing its stock
+func (usecase *usecase) abandonPayment(orderID int, reason string) {
+	err := usecase.repository.CancelOrder(domain.OrderStatusChange{
+		OrderID:    orderID,
+		FromStatus: domain.OrderPaymentPending,
+		ToStatus:   domain.OrderCancelled,
+		Reason:     reason,
+	})
+	if err != nil {
+		log.Printf("Error cancelling order %d after its payment failed: %v", orderID, err)
+	}
+}
+
+// voidEndedPayment drops at the gateway the authorization of an order that ended unpaid, which
+// the repository left void_pending, and marks the payment voided. The order has already ended,
+// so a void that fails is only logged and left for RetryPendingVoids.
+func (usecase *usecase) voidEndedPayment(orderID int) {
+	payment, err := usecase.repository.GetPaymentByOrderID(orderID)
+	if err != nil || payment.Status != domain.PaymentVoidPending {
+		return
+	}
+	if err := usecase.voidPayment(payment); err != nil {
+		log.Printf("Error voiding the authorization of order %d, it will be retried: %v", orderID, err)
+	}
+}
+
+// voidPayment asks the gateway to drop the authorization of a void_pending payment and records it
+func (usecase *usecase) voidPayment(payment domain.Payment) error {
+	if err := usecase.options.PaymentGateway.Void(payment.TransactionID); err != nil {
+		return fmt.Errorf("failed to void authorization %s: %v", payment.TransactionID, err)
+	}
+	if err := usecase.repository.VoidPayment(payment.ID); err != nil {
+		return fmt.Errorf("failed to mark payment %d voided: %v", payment.ID, err)
+	}
+	return nil
+}
+
+// RetryPendingVoids - Drops the authorizations left void_pending for longer than
+// RefundRetryAfter, because the gateway could not be reached when their order ended. A void the
+// gateway declines, because it captured the payment on its own, stays pending until the capture
+// webhook moves the payment to refund_pending. Returns how many were voided.
+func (usecase *usecase) RetryPendingVoids() (int, error) {
+	payments, err := usecase.repository.GetPaymentsPendingVoid(time.Now().Add(-usecase.options.RefundRetryAfter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get payments pending void: %v", err)
+	}
+
+	voided := 0
+	for _, payment := range payments {
+		if err := usecase.voidPayment(payment); err != nil {
+			log.Printf("Error voiding the authorization of order %d: %v", payment.OrderID, err)
+			continue
+		}
+		log.Printf("Pending void of order %d has been completed", payment.OrderID)
+		voided++
+	}
+	return voided, nil
+}
+
+// CapturePayment - Takes the authorized payment of the customer's order and, only once the
+// gateway has captured it, moves the order from payment_pending to placed for the restaurant
+func (usecase *usecase) CapturePayment(actor domain.AuthClaims, orderID int) (domain.Order, error) {
+	order, err := usecase.getOrderFor(actor, orderID)
+	if err != nil {
+		return order, err
+	}
+	if err := authorizeTransition(actor, order, orderTransition{actorCustomer, domain.PermissionOrderPlace}); err != nil {
+		return domain.Order{}, err
+	}
+	if order.OrderStatus != domain.OrderPaymentPending {
+		return domain.Order{}, domain.OrderStatusConflict
+	}
+
+	payment, err := usecase.getPayment(orderID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	if payment.Status != domain.PaymentAuthorized {
+		return domain.Order{}, domain.OrderStatusConflict
+	}
+
+	err = usecase.options.PaymentGateway.Capture(payment.TransactionID, payment.Amount)
+	if errors.Is(err, domain.PaymentDeclined) {
+		return domain.Order{}, domain.PaymentDeclined
+	}
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to capture payment: %v", err)
+	}
+
+	// The charge is written to the ledger with the capture, also when the order has ended
+	err = usecase.repository.CapturePayment(payment.ID, domain.OrderStatusChange{
+		OrderID:       order.ID,
+		FromStatus:    domain.OrderPaymentPending,
+		ToStatus:      domain.OrderPlaced,
+		ChangedBy:     &actor.UserID,
+		ChangedByRole: actor.Role,
+		Reason:        "payment captured",
+	}, chargeJournal(payment))
+	if err != nil {
+		// On a conflict the order ended while the gateway was capturing, the repository
+		// has queued the captured money for refund
+		return domain.Order{}, fmt.Errorf("failed to record captured payment: %w", err)
+	}
+
+	updated, err := usecase.repository.GetOrderByID(orderID)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to get order: %v", err)
+	}
+	payment.Status = domain.PaymentCaptured
+	updated.Payment = &payment
+	return updated, nil
+}
+
+// GetOrderPayment - Fetches the payment of an order the actor takes part in
+func (usecase *usecase) GetOrderPayment(actor domain.AuthClaims, orderID int) (domain.Payment, error) {
+	order, err := usecase.getOrderFor(actor, orderID)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+	if isPickupView(actor, order) {
+		return domain.Payment{}, domain.OrderNotFound
+	}
+	return usecase.getPayment(orderID)
+}
+
+func (usecase *usecase) getPayment(orderID int) (domain.Payment, error) {
+	payment, err := usecase.repository.GetPaymentByOrderID(orderID)
+	if err != nil {
+		log.Printf("Error getting payment of order %d: %v", orderID, err)
+		return payment, domain.PaymentNotFound
+	}
+	return payment, nil
+}
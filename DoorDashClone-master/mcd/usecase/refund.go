@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mcd/domain"
+	"strings"
+	"time"
+)
+
+// isCaptured reports whether money was taken for a payment in the status, refunded or not
+func isCaptured(status domain.PaymentStatus) bool {
+	switch status {
+	case domain.PaymentCaptured, domain.PaymentRefundPending, domain.PaymentPartlyRefunded, domain.PaymentRefunded:
+		return true
+	}
+	return false
+}
+
+// reserved returns how much of the payment the refunds take, failed refunds take nothing
+func reserved(refunds []domain.Refund) domain.Money {
+	var total domain.Money
+	for _, refund := range refunds {
+		if refund.Status != domain.RefundFailed {
+			total = total.Add(refund.Amount)
+		}
+	}
+	return total
+}
+
+// refundReference identifies the refund at the gateway, so asking again cannot refund twice
+func refundReference(refund domain.Refund) string {
+	return fmt.Sprintf("refund-%d", refund.ID)
+}
+
+// RefundOrder - Returns money to the customer through the gateway, for the listed items or for
+// everything not refunded yet when no items are given. The refund is reserved against the
+// payment before the gateway is called, so concurrent refunds cannot exceed the captured amount.
+// Once the whole payment is refunded, a finished order moves to refunded. A refund whose outcome
+// is unknown stays pending and is settled by RetryPendingRefunds.
+func (usecase *usecase) RefundOrder(actor domain.AuthClaims, orderID int, request domain.RefundRequest) (domain.Refund, error) {
+	if !actor.Role.HasPermission(domain.PermissionOrderRefund) {
+		return domain.Refund{}, domain.Forbidden
+	}
+	order, err := usecase.getOrderFor(actor, orderID)
+	if err != nil {
+		return domain.Refund{}, err
+	}
+	payment, err := usecase.getPayment(orderID)
+	if err != nil {
+		return domain.Refund{}, err
+	}
+	if !isCaptured(payment.Status) || payment.Status == domain.PaymentRefunded {
+		return domain.Refund{}, domain.PaymentNotRefundable
+	}
+	refunds, err := usecase.repository.GetRefunds(payment.ID)
+	if err != nil {
+		return domain.Refund{}, fmt.Errorf("failed to get refunds: %v", err)
+	}
+
+	refund := domain.Refund{
+		PaymentID: payment.ID,
+		OrderID:   order.ID,
+		Reason:    strings.TrimSpace(request.Reason),
+		Status:    domain.RefundPending,
+		CreatedBy: actor.UserID,
+	}
+	if len(request.Items) == 0 {
+		refund.Amount = payment.Amount.Sub(reserved(refunds))
+	} else if refund.Items, err = refundItems(order, request.Items); err != nil {
+		return domain.Refund{}, err
+	}
+	for _, item := range refund.Items {
+		if !item.Amount.SameCurrency(payment.Amount) {
+			return domain.Refund{}, domain.CurrencyMismatch
+		}
+		refund.Amount = refund.Amount.Add(item.Amount)
+	}
+	if refund.Amount.Amount <= 0 {
+		return domain.Refund{}, domain.RefundExceedsCapture
+	}
+
+	refund, err = usecase.repository.CreateRefund(refund)
+	if err != nil {
+		return domain.Refund{}, fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	err = usecase.options.PaymentGateway.Refund(payment.TransactionID, refundReference(refund), refund.Amount)
+	if errors.Is(err, domain.PaymentDeclined) {
+		if failErr := usecase.repository.FailRefund(refund.ID); failErr != nil {
+			log.Printf("Error marking refund %d as failed: %v", refund.ID, failErr)
+		}
+		return domain.Refund{}, domain.PaymentDeclined
+	}
+	if err != nil {
+		// The gateway may still have made the refund, so it stays reserved until it is retried
+		return domain.Refund{}, fmt.Errorf("failed to refund payment: %v", err)
+	}
+
+	if err := usecase.repository.CompleteRefund(refund.ID, refundJournal(payment, refund)); err != nil {
+		return domain.Refund{}, fmt.Errorf("failed to complete refund: %v", err)
+	}
+	refund.Status = domain.RefundSucceeded
+
+	if payment, err = usecase.repository.GetPaymentByOrderID(orderID); err == nil && payment.Status == domain.PaymentRefunded {
+		usecase.markRefunded(order, &actor.UserID, actor.Role, refund.Reason)
+	}
+	return refund, nil
+}
+
+// RetryPendingRefunds - Settles the refunds left pending for longer than RefundRetryAfter, whose
+// request failed or stopped after reserving them. The gateway is asked again with the same
+// reference, so a refund it already made is only confirmed. Returns how many were completed.
+func (usecase *usecase) RetryPendingRefunds() (int, error) {
+	refunds, err := usecase.repository.GetPendingRefunds(time.Now().Add(-usecase.options.RefundRetryAfter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending refunds: %v", err)
+	}
+
+	completed := 0
+	for _, refund := range refunds {
+		payment, err := usecase.getPayment(refund.OrderID)
+		if err != nil {
+			return completed, err
+		}
+		err = usecase.options.PaymentGateway.Refund(payment.TransactionID, refundReference(refund), refund.Amount)
+		if errors.Is(err, domain.PaymentDeclined) {
+			if err := usecase.repository.FailRefund(refund.ID); err != nil {
+				return completed, fmt.Errorf("failed to mark refund %d as failed: %v", refund.ID, err)
+			}
+			log.Printf("Pending refund %d was declined by the gateway", refund.ID)
+			continue
+		}
+		if err != nil {
+			return completed, fmt.Errorf("failed to refund payment of refund %d: %v", refund.ID, err)
+		}
+		if err := usecase.repository.CompleteRefund(refund.ID, refundJournal(payment, refund)); err != nil {
+			return completed, fmt.Errorf("failed to complete refund %d: %v", refund.ID, err)
+		}
+		log.Printf("Pending refund %d has been completed", refund.ID)
+		completed++
+
+		if payment, err = usecase.repository.GetPaymentByOrderID(refund.OrderID); err == nil && payment.Status == domain.PaymentRefunded {
+			if order, err := usecase.repository.GetOrderByID(refund.OrderID); err == nil {
+				usecase.markRefunded(order, nil, "", refund.Reason)
+			}
+		}
+	}
+	return completed, nil
+}
+
+// refundItems prices the requested units of each order line at what the customer paid for them
+func refundItems(order domain.Order, requested []domain.RefundItemRequest) ([]domain.RefundItem, error) {
+	lines := make(map[int]domain.OrderProduct, len(order.Products))
+	for _, line := range order.Products {
+		lines[line.ProductID] = line
+	}
+
+	items := make([]domain.RefundItem, 0, len(requested))
+	seen := make(map[int]bool, len(requested))
+	for _, request := range requested {
+		line, ok := lines[request.ProductID]
+		if !ok || request.Quantity <= 0 || seen[request.ProductID] {
+			return nil, domain.InvalidRefundItem
+		}
+		if request.Quantity > line.Quantity {
+			return nil, domain.RefundExceedsCapture
+		}
+		seen[request.ProductID] = true
+		items = append(items, domain.RefundItem{
+			OrderProductID: line.ID,
+			ProductID:      line.ProductID,
+			Quantity:       request.Quantity,
+			Amount:         line.PriceAtPurchase.Mul(request.Quantity),
+		})
+	}
+	return items, nil
+}
+
+// markRefunded moves a fully refunded order to refunded when it has finished. The money is
+// already back with the customer, so a failure is only logged. changedBy is nil when the
+// refund was settled by RetryPendingRefunds.
+func (usecase *usecase) markRefunded(order domain.Order, changedBy *int, changedByRole domain.Role, reason string) {
+	if _, ok := orderTransitions[order.OrderStatus][domain.OrderRefunded]; !ok {
+		return
+	}
+	err := usecase.repository.UpdateOrderStatus(domain.OrderStatusChange{
+		OrderID:       order.ID,
+		FromStatus:    order.OrderStatus,
+		ToStatus:      domain.OrderRefunded,
+		ChangedBy:     changedBy,
+		ChangedByRole: changedByRole,
+		Reason:        reason,
+	})
+	if err != nil {
+		log.Printf("Error marking order %d as refunded: %v", order.ID, err)
+	}
+}
+
+// refundOrderInFull refunds everything not refunded yet, for status changes to refunded
+func (usecase *usecase) refundOrderInFull(actor domain.AuthClaims, orderID int, reason string) (domain.Order, error) {
+	order, err := usecase.getOrderFor(actor, orderID)
+	if err != nil {
+		return order, err
+	}
+	transition, ok := orderTransitions[order.OrderStatus][domain.OrderRefunded]
+	if !ok {
+		return domain.Order{}, refusedTransition(order.OrderStatus, domain.OrderRefunded)
+	}
+	if err := authorizeTransition(actor, order, transition); err != nil {
+		return domain.Order{}, err
+	}
+
+	if _, err := usecase.RefundOrder(actor, orderID, domain.RefundRequest{Reason: reason}); err != nil {
+		return domain.Order{}, err
+	}
+	updated, err := usecase.repository.GetOrderByID(orderID)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to get order: %v", err)
+	}
+	return updated, nil
+}
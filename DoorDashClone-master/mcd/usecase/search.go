@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"mcd/domain"
+	"strings"
+)
+
+// rebuildBatchSize is how many hotels or products RebuildSearchIndex reads at a time
+const rebuildBatchSize = 100
+
+// Search - Finds hotels and dishes matching the words of the query, best match first. Product
+// hits come with the hotel serving them. Hits whose hotel or product can no longer be read are
+// left out, the index catches up with the deletion on the next rebuild.
+func (usecase *usecase) Search(query domain.SearchQuery) ([]domain.SearchResult, error) {
+	if strings.TrimSpace(query.Text) == "" || query.Limit < 0 {
+		return nil, domain.InvalidSearchQuery
+	}
+	if query.Limit == 0 {
+		query.Limit = domain.DefaultPageSize
+	}
+	query.Limit = min(query.Limit, domain.MaxSearchResults)
+
+	hits, err := usecase.options.SearchIndex.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %v", err)
+	}
+
+	// Product hits are read first, so the hotels of all hits get their availability at once
+	results := make([]domain.SearchResult, 0, len(hits))
+	hotelIDs := make([]int, 0, len(hits))
+	for _, hit := range hits {
+		result := domain.SearchResult{Kind: hit.Kind, Score: hit.Score}
+		hotelID := hit.ID
+		if hit.Kind == domain.SearchProduct {
+			product, err := usecase.repository.GetProductById(fmt.Sprint(hit.ID))
+			if err != nil {
+				log.Printf("Skipping search hit for product %d: %v", hit.ID, err)
+				continue
+			}
+			result.Product = &product
+			hotelID = product.HotelID
+		}
+		results = append(results, result)
+		hotelIDs = append(hotelIDs, hotelID)
+	}
+
+	var hotels []domain.Hotel
+	hotelIndex := make(map[int]int)
+	for _, hotelID := range hotelIDs {
+		if _, ok := hotelIndex[hotelID]; ok {
+			continue
+		}
+		hotel, err := usecase.repository.GetHotelByID(hotelID)
+		if err != nil {
+			log.Printf("Skipping search hits for hotel %d: %v", hotelID, err)
+			hotelIndex[hotelID] = -1
+			continue
+		}
+		hotelIndex[hotelID] = len(hotels)
+		hotels = append(hotels, *hotel)
+	}
+	if err := usecase.withAvailability(hotels); err != nil {
+		return nil, err
+	}
+
+	kept := results[:0]
+	for i, result := range results {
+		index := hotelIndex[hotelIDs[i]]
+		if index < 0 {
+			continue
+		}
+		result.Hotel = &hotels[index]
+		kept = append(kept, result)
+	}
+	results = kept
+	return results, nil
+}
+
+// RebuildSearchIndex - Indexes every hotel and product from the repository, for an index that
+// starts empty or missed changes, such as those made through another instance. Hotels and
+// products deleted elsewhere stay indexed but are left out of results by Search. Returns how
+// many hotels and products were indexed.
+func (usecase *usecase) RebuildSearchIndex() (int, error) {
+	indexed := 0
+	hotelQuery := domain.HotelQuery{ListOptions: rebuildListOptions(nil)}
+	for {
+		hotels, err := usecase.repository.GetHotels(hotelQuery)
+		if err != nil {
+			return indexed, fmt.Errorf("failed to get hotels: %v", err)
+		}
+		for _, hotel := range hotels {
+			if err := usecase.options.SearchIndex.IndexHotel(hotel); err != nil {
+				return indexed, fmt.Errorf("failed to index hotel %d: %v", hotel.ID, err)
+			}
+			indexed++
+			count, err := usecase.indexHotelProducts(int(hotel.ID))
+			indexed += count
+			if err != nil {
+				return indexed, err
+			}
+		}
+		if len(hotels) < rebuildBatchSize {
+			return indexed, nil
+		}
+		hotelQuery.ListOptions = rebuildListOptions(&domain.Cursor{Sort: domain.SortByID, ID: int(hotels[len(hotels)-1].ID)})
+	}
+}
+
+// indexHotelProducts indexes every product of a hotel and returns how many there were
+func (usecase *usecase) indexHotelProducts(hotelID int) (int, error) {
+	indexed := 0
+	productQuery := domain.ProductQuery{HotelID: hotelID, ListOptions: rebuildListOptions(nil)}
+	for {
+		products, err := usecase.repository.GetProductsByHotel(productQuery)
+		if err != nil {
+			return indexed, fmt.Errorf("failed to get products by hotel: %v", err)
+		}
+		for _, product := range products {
+			if err := usecase.options.SearchIndex.IndexProduct(product); err != nil {
+				return indexed, fmt.Errorf("failed to index product %d: %v", product.ID, err)
+			}
+			indexed++
+		}
+		if len(products) < rebuildBatchSize {
+			return indexed, nil
+		}
+		productQuery.ListOptions = rebuildListOptions(&domain.Cursor{Sort: domain.SortByID, ID: int(products[len(products)-1].ID)})
+	}
+}
+
+// rebuildListOptions reads a batch in id order after the cursor
+func rebuildListOptions(after *domain.Cursor) domain.ListOptions {
+	return domain.ListOptions{Sort: domain.Sort{Field: domain.SortByID}, Limit: rebuildBatchSize, After: after}
+}
+
+// The index is kept in sync after the repository stored a change. A failed index write does
+// not undo the change, it is logged and repaired by the next RebuildSearchIndex.
+
+func (usecase *usecase) indexHotel(hotel domain.Hotel) {
+	if err := usecase.options.SearchIndex.IndexHotel(hotel); err != nil {
+		log.Printf("Error indexing hotel %d for search: %v", hotel.ID, err)
+	}
+}
+
+func (usecase *usecase) indexProduct(product domain.Product) {
+	if err := usecase.options.SearchIndex.IndexProduct(product); err != nil {
+		log.Printf("Error indexing product %d for search: %v", product.ID, err)
+	}
+}
+
+func (usecase *usecase) unindexHotel(hotelID int) {
+	if err := usecase.options.SearchIndex.RemoveHotel(hotelID); err != nil {
+		log.Printf("Error removing hotel %d from search: %v", hotelID, err)
+	}
+}
+
+func (usecase *usecase) unindexProduct(productID int) {
+	if err := usecase.options.SearchIndex.RemoveProduct(productID); err != nil {
+		log.Printf("Error removing product %d from search: %v", productID, err)
+	}
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mcd/domain"
+	"strconv"
+	"time"
+)
+
+// randomToken returns a URL safe random string built from n random bytes
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the hex encoded SHA-256 of a token, which is what gets persisted
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshToken builds a refresh token for the given family and returns it with its raw value
+func newRefreshToken(userID int, familyID string, ttl time.Duration) (domain.RefreshToken, string, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return domain.RefreshToken{}, "", err
+	}
+	token := domain.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	return token, raw, nil
+}
+
+// issueRefreshToken starts a new refresh token family for a fresh login
+func (usecase *usecase) issueRefreshToken(userID int) (string, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	token, raw, err := newRefreshToken(userID, familyID, usecase.options.RefreshTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := usecase.repository.CreateRefreshToken(token); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// RefreshToken - Exchanges a refresh token for a new access token and a rotated refresh token.
+// Presenting an already rotated token is treated as theft and revokes the whole family.
+func (usecase *usecase) RefreshToken(refreshToken string) (domain.LoginResponse, error) {
+	var loginResponse domain.LoginResponse
+	if refreshToken == "" {
+		return loginResponse, domain.InvalidRefreshToken
+	}
+
+	stored, err := usecase.repository.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		log.Printf("Error looking up refresh token: %v", err)
+		return loginResponse, domain.InvalidRefreshToken
+	}
+
+	if stored.RevokedAt != nil {
+		return loginResponse, usecase.revokeReusedFamily(stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return loginResponse, domain.InvalidRefreshToken
+	}
+
+	user, err := usecase.repository.GetUserById(strconv.Itoa(stored.UserID))
+	if err != nil {
+		return loginResponse, fmt.Errorf("failed to get user for refresh token: %v", err)
+	}
+
+	next, raw, err := newRefreshToken(stored.UserID, stored.FamilyID, usecase.options.RefreshTokenTTL)
+	if err != nil {
+		return loginResponse, err
+	}
+	err = usecase.repository.RotateRefreshToken(stored.ID, next)
+	if errors.Is(err, domain.RefreshTokenReused) {
+		return loginResponse, usecase.revokeReusedFamily(stored)
+	}
+	if err != nil {
+		return loginResponse, fmt.Errorf("failed to rotate refresh token: %v", err)
+	}
+
+	token, err := usecase.generateToken(int(user.ID), user.Email, user.Name, user.Role)
+	if err != nil {
+		return loginResponse, err
+	}
+	loginResponse.Name = user.Name
+	loginResponse.Role = user.Role
+	loginResponse.Token = token
+	loginResponse.RefreshToken = raw
+	return loginResponse, nil
+}
+
+// revokeReusedFamily revokes every token descended from the same login as a reused token
+func (usecase *usecase) revokeReusedFamily(token domain.RefreshToken) error {
+	log.Printf("Refresh token reuse detected for user %d, revoking family %s", token.UserID, token.FamilyID)
+	if err := usecase.repository.RevokeRefreshTokenFamily(token.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %v", err)
+	}
+	return domain.RefreshTokenReused
+}
+
+// Logout - Revokes all refresh tokens of the user so no session can be renewed
+func (usecase *usecase) Logout(userID int) error {
+	err := usecase.repository.RevokeUserRefreshTokens(userID)
+	if err != nil {
+		return fmt.Errorf("failed to logout user: %v", err)
+	}
+	return nil
+}
@@ -1,36 +1,58 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"mcd/domain"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Options holds the collaborators and settings of the usecase that do not come from the repository
+type Options struct {
+	Notifier             domain.Notifier   // Delivers OTP codes to users
+	RequireVerifiedLogin bool              // Refuse login until the user has verified an OTP
+	SigningKeyID         string            // Key id used to sign new access tokens
+	SigningKeys          map[string][]byte // Every key id accepted when verifying access tokens
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	PaymentTimeout       time.Duration // Stock of an unpaid order is released after this, 0 keeps it reserved
+	IdempotencyKeyTTL    time.Duration // How long responses are replayed for retries with the same Idempotency-Key
+	IdempotencyLease     time.Duration // A request in flight for longer is assumed crashed and its key taken over by a retry
+	PaymentGateway       domain.PaymentGateway
+	RefundRetryAfter     time.Duration      // Refunds and voids still pending after this are retried by RetryPendingRefunds and RetryPendingVoids
+	Currency             string             // ISO 4217 code of product prices created without a currency
+	SearchIndex          domain.SearchIndex // Kept in sync with every hotel and product change
+}
+
 type usecase struct {
 	repository domain.MCDRepository
+	options    Options
 }
 
-func NewUseCase(repository domain.MCDRepository) domain.MCDUsecase {
-	return &usecase{repository: repository}
+func NewUseCase(repository domain.MCDRepository, options Options) domain.MCDUsecase {
+	return &usecase{repository: repository, options: options}
 }
 
-func generateToken(user_id int, email string, username string, role string) (string, error) {
+// generateToken signs an access token with the active key and records the key id in the kid header
+func (usecase *usecase) generateToken(user_id int, email string, username string, role domain.Role) (string, error) {
 	claims := jwt.MapClaims{
 		"username": username,
 		"user_id":  user_id,
 		"role":     role,
 		"email":    email,
-		"exp":      time.Now().Add(time.Hour).Unix(),
+		"exp":      time.Now().Add(usecase.options.AccessTokenTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = usecase.options.SigningKeyID
 
-	var signingKey interface{} = []byte("dinesh-bali-secret-key")
-	signedToken, err := token.SignedString(signingKey)
+	signedToken, err := token.SignedString(usecase.options.SigningKeys[usecase.options.SigningKeyID])
 
 	if err != nil {
 		return "", err
@@ -39,14 +61,80 @@ func generateToken(user_id int, email string, username string, role string) (str
 	return signedToken, nil
 }
 
-// Create User - Adds a new user
-func (usecase *usecase) CreateUser(user domain.User) error {
-	password, err := bcrypt.GenerateFromPassword([]byte(user.PasswordHash), bcrypt.DefaultCost)
+// VerifyToken - Validates an access token and returns the identity it carries
+func (usecase *usecase) VerifyToken(tokenString string) (domain.AuthClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := usecase.options.SigningKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key id: %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil || !token.Valid {
+		return domain.AuthClaims{}, domain.InvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return domain.AuthClaims{}, domain.InvalidToken
+	}
+	// Numeric claims are decoded from JSON as float64
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return domain.AuthClaims{}, domain.InvalidToken
+	}
+	role, _ := claims["role"].(string)
+	email, _ := claims["email"].(string)
+
+	return domain.AuthClaims{UserID: int(userID), Email: email, Role: domain.Role(role)}, nil
+}
+
+// Create User - Validates the registration and adds a new user
+func (usecase *usecase) CreateUser(request domain.CreateUserRequest) error {
+	// Admins are never self-registered, they are promoted directly in the database, and couriers
+	// are provisioned by an admin through CreateCourier
+	if request.Role == "" {
+		request.Role = domain.RoleCustomer
+	}
+	if request.Role == domain.RoleCourier {
+		return domain.InvalidRole
+	}
+	return usecase.registerUser(request)
+}
+
+// CreateCourier - Validates the registration and adds a courier, the route is reserved to admins
+func (usecase *usecase) CreateCourier(request domain.CreateUserRequest) error {
+	request.Role = domain.RoleCourier
+	return usecase.registerUser(request)
+}
+
+// registerUser validates the registration and stores the user with a hashed password
+func (usecase *usecase) registerUser(request domain.CreateUserRequest) error {
+	request.Email = strings.TrimSpace(request.Email)
+	request.PhoneNumber = strings.TrimSpace(request.PhoneNumber)
+	if err := usecase.validateCreateUser(request); err != nil {
+		return err
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
-	user.PasswordHash = string(password)
+	user := domain.User{
+		Email:        request.Email,
+		PhoneNumber:  request.PhoneNumber,
+		PasswordHash: string(password),
+		Name:         request.Name,
+		Role:         request.Role,
+	}
 	err = usecase.repository.CreateUser(user)
+	if errors.Is(err, domain.EmailAlreadyExists) {
+		return domain.EmailAlreadyExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %v", err)
 	}
@@ -58,19 +146,28 @@ func (usecase *usecase) UserLogin(userData domain.UserLogin) (domain.LoginRespon
 	var loginResponse domain.LoginResponse
 	user, err := usecase.repository.GetUserByEmail(userData.Email)
 	if err != nil {
-		return loginResponse, err
+		log.Printf("Error getting user for login: %v", err)
+		return loginResponse, domain.InvalidCredentials
 	}
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(userData.Password))
 	if err != nil {
-		return loginResponse, err
+		return loginResponse, domain.InvalidCredentials
 	}
-	token, err := generateToken(int(user.ID), user.Email, user.Name, user.Role)
+	if usecase.options.RequireVerifiedLogin && !user.IsVerified {
+		return loginResponse, domain.UserNotVerified
+	}
+	token, err := usecase.generateToken(int(user.ID), user.Email, user.Name, user.Role)
 	if err != nil {
 		return loginResponse, err
 	}
+	refreshToken, err := usecase.issueRefreshToken(int(user.ID))
+	if err != nil {
+		return loginResponse, fmt.Errorf("failed to issue refresh token: %v", err)
+	}
 	loginResponse.Name = user.Name
 	loginResponse.Role = user.Role
 	loginResponse.Token = token
+	loginResponse.RefreshToken = refreshToken
 	return loginResponse, nil
 }
 
@@ -93,20 +190,43 @@ func (usecase *usecase) UpdateUser(user domain.User) error {
 }
 
 // Get User by ID - Fetches user by their ID
-func (usecase *usecase) GetUserById(userID string) (domain.User, error) {
+func (usecase *usecase) GetUserById(userID string) (domain.UserResponse, error) {
 	user, err := usecase.repository.GetUserById(userID)
 	if err != nil {
-		return domain.User{}, fmt.Errorf("failed to get user by id: %v", err)
+		return domain.UserResponse{}, fmt.Errorf("failed to get user by id: %v", err)
 	}
-	return user, nil
+	return domain.UserResponse{
+		ID:          user.ID,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		Name:        user.Name,
+		Role:        user.Role,
+		IsVerified:  user.IsVerified,
+	}, nil
 }
 
-// Create Product - Adds a new product
-func (usecase *usecase) CreateProduct(product domain.Product) error {
-	err := usecase.repository.CreateProduct(product)
+// Create Product - Adds a new product to a hotel managed by the actor
+func (usecase *usecase) CreateProduct(actor domain.AuthClaims, product domain.Product) error {
+	if err := usecase.authorizeHotelOwner(actor, product.HotelID); err != nil {
+		return err
+	}
+	if err := validateMenuItem(product, true); err != nil {
+		return err
+	}
+	if err := usecase.ensureMenuSection(product.HotelID, product.SectionID); err != nil {
+		return err
+	}
+	price, err := usecase.normalizePrice(product.Price)
+	if err != nil {
+		return err
+	}
+	product.Price = price
+	product.Available = true
+	created, err := usecase.repository.CreateProduct(product)
 	if err != nil {
 		return fmt.Errorf("failed to create product: %v", err)
 	}
+	usecase.indexProduct(created)
 	return nil
 }
 
@@ -116,15 +236,58 @@ func (usecase *usecase) DeleteProduct(productID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %v", err)
 	}
+	if id, err := strconv.Atoi(productID); err == nil {
+		usecase.unindexProduct(id)
+	}
 	return nil
 }
 
-// Update Product - Updates an existing product
-func (usecase *usecase) UpdateProduct(product domain.Product) error {
-	err := usecase.repository.UpdateProduct(product)
+// Update Product - Updates an existing product of a hotel managed by the actor
+func (usecase *usecase) UpdateProduct(actor domain.AuthClaims, product domain.Product) error {
+	existing, err := usecase.repository.GetProductById(strconv.Itoa(int(product.ID)))
+	if err != nil {
+		return fmt.Errorf("failed to get product by id: %v", err)
+	}
+	if err := usecase.authorizeHotelOwner(actor, existing.HotelID); err != nil {
+		return err
+	}
+	// Moving a product to another hotel requires managing that hotel too
+	hotelID := existing.HotelID
+	if product.HotelID != 0 && product.HotelID != existing.HotelID {
+		if err := usecase.authorizeHotelOwner(actor, product.HotelID); err != nil {
+			return err
+		}
+		hotelID = product.HotelID
+		// Its section stays behind, the product needs one at the new hotel
+		if existing.SectionID != nil && product.SectionID == nil {
+			return domain.MenuSectionNotFound
+		}
+	}
+	if err := validateMenuItem(product, false); err != nil {
+		return err
+	}
+	if err := usecase.ensureMenuSection(hotelID, product.SectionID); err != nil {
+		return err
+	}
+	// Availability only changes through SetProductAvailability
+	product.Available = false
+	// A zero amount leaves the price as it is, so does the currency that comes with it. A new
+	// amount without a currency stays in the currency the product is priced in.
+	if product.Price.IsZero() {
+		product.Price.Currency = ""
+	} else {
+		if strings.TrimSpace(product.Price.Currency) == "" {
+			product.Price.Currency = existing.Price.Currency
+		}
+		if product.Price, err = usecase.normalizePrice(product.Price); err != nil {
+			return err
+		}
+	}
+	updated, err := usecase.repository.UpdateProduct(product)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %v", err)
 	}
+	usecase.indexProduct(updated)
 	return nil
 }
 
@@ -137,21 +300,62 @@ func (usecase *usecase) GetProductById(productID string) (domain.Product, error)
 	return product, nil
 }
 
-// Get Products by Hotel - Fetches products for a specific hotel
-func (usecase *usecase) GetProductsByHotel(hotelID string) ([]domain.Product, error) {
-	products, err := usecase.repository.GetProductsByHotel(hotelID)
+// productListOptions checks the filters of a product list and resolves its paging and sorting
+func productListOptions(query domain.ProductQuery, page domain.PageRequest) (domain.ListOptions, error) {
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return domain.ListOptions{}, domain.InvalidListQuery
+	}
+	return listOptions(page, []string{domain.SortByID, domain.SortByName, domain.SortByPrice}, domain.Sort{Field: domain.SortByID})
+}
+
+// productSortKey returns the sort key of a product as a cursor key
+func productSortKey(product domain.Product, field string) string {
+	switch field {
+	case domain.SortByName:
+		return product.Name
+	case domain.SortByPrice:
+		return strconv.FormatInt(product.Price.Amount, 10)
+	}
+	return ""
+}
+
+// Get Products by Hotel - Fetches a page of a hotel's products, filtered by category and price
+func (usecase *usecase) GetProductsByHotel(query domain.ProductQuery, page domain.PageRequest) (domain.Page[domain.Product], error) {
+	options, err := productListOptions(query, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get products by hotel: %v", err)
+		return domain.Page[domain.Product]{}, err
 	}
-	return products, nil
+	query.ListOptions = options
+
+	products, err := usecase.repository.GetProductsByHotel(query)
+	if err != nil {
+		return domain.Page[domain.Product]{}, fmt.Errorf("failed to get products by hotel: %v", err)
+	}
+	return newPage(products, options, func(product domain.Product) int { return int(product.ID) }, productSortKey), nil
 }
 
-// Create Hotel - Adds a new hotel
+// Create Hotel - Adds a new hotel, optionally recording its restaurant owner and location
 func (usecase *usecase) CreateHotel(hotel domain.Hotel) error {
-	err := usecase.repository.CreateHotel(hotel)
+	if err := validateHotelLocation(hotel); err != nil {
+		return err
+	}
+	if hotel.TimeZone == "" {
+		hotel.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(hotel.TimeZone); err != nil {
+		return domain.InvalidTimeZone
+	}
+	if hotel.OwnerID != nil {
+		owner, err := usecase.repository.GetUserById(strconv.Itoa(*hotel.OwnerID))
+		if err != nil || owner.Role != domain.RoleRestaurantOwner {
+			return domain.InvalidHotelOwner
+		}
+	}
+	created, err := usecase.repository.CreateHotel(hotel)
 	if err != nil {
 		return fmt.Errorf("failed to create hotel: %v", err)
 	}
+	usecase.indexHotel(created)
 	return nil
 }
 
@@ -161,25 +365,58 @@ func (usecase *usecase) DeleteHotel(hotelID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete hotel: %v", err)
 	}
+	if id, err := strconv.Atoi(hotelID); err == nil {
+		usecase.unindexHotel(id)
+	}
 	return nil
 }
 
 // Update Hotel - Updates an existing hotel
 func (usecase *usecase) UpdateHotel(hotel domain.Hotel) error {
-	err := usecase.repository.UpdateHotel(hotel)
+	if err := validateHotelLocation(hotel); err != nil {
+		return err
+	}
+	if hotel.TimeZone != "" {
+		if _, err := time.LoadLocation(hotel.TimeZone); err != nil {
+			return domain.InvalidTimeZone
+		}
+	}
+	if _, err := usecase.repository.GetHotelByID(int(hotel.ID)); err != nil {
+		return domain.HotelNotFound
+	}
+	updated, err := usecase.repository.UpdateHotel(hotel)
 	if err != nil {
 		return fmt.Errorf("failed to update hotel: %v", err)
 	}
+	usecase.indexHotel(updated)
 	return nil
 }
 
-// Get Hotels - Fetches all hotels
-func (usecase *usecase) GetHotels() ([]domain.Hotel, error) {
-	hotels, err := usecase.repository.GetHotels()
+// Get Hotels - Fetches a page of hotels, filtered by city and state, with whether they are
+// open and when they open next
+func (usecase *usecase) GetHotels(query domain.HotelQuery, page domain.PageRequest) (domain.Page[domain.Hotel], error) {
+	options, err := listOptions(page, []string{domain.SortByID, domain.SortByName}, domain.Sort{Field: domain.SortByID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get hotels: %v", err)
+		return domain.Page[domain.Hotel]{}, err
+	}
+	query.ListOptions = options
+
+	hotels, err := usecase.repository.GetHotels(query)
+	if err != nil {
+		return domain.Page[domain.Hotel]{}, fmt.Errorf("failed to get hotels: %v", err)
+	}
+	result := newPage(hotels, options,
+		func(hotel domain.Hotel) int { return int(hotel.ID) },
+		func(hotel domain.Hotel, field string) string {
+			if field == domain.SortByName {
+				return hotel.Name
+			}
+			return ""
+		})
+	if err := usecase.withAvailability(result.Items); err != nil {
+		return domain.Page[domain.Hotel]{}, err
 	}
-	return hotels, nil
+	return result, nil
 }
 
 func (usecase *usecase) AddProductToCart(cartProduct domain.CartProducts) error {
@@ -250,93 +487,186 @@ func (usecase *usecase) GetUserCart(userID int) (domain.CartResponse, error) {
 	return cartResponse, nil
 }
 
-// Create Order - Adds a new order
-func (usecase *usecase) CreateOrder(order domain.CreateOrderRequest) error {
-	var db_order domain.Order
-	db_order.UserID = order.UserID
-	db_order.PhoneNumber = order.PhoneNumber
-	db_order.DriveThruCode = order.DriveThruCode
-	db_order.OrderStatus = order.OrderStatus
-	db_order.IsDelivered = order.IsDelivered
-	db_order.OrderTotal = order.OrderTotal
-
-	for i := 0; i < len(order.Products); i++ {
-		var orderProduct domain.OrderProduct
-		orderProduct.OrderID = order.ID
-		orderProduct.PriceAtPurchase = order.Products[i].PriceAtPurchase
-		orderProduct.ProductID = order.Products[i].ProductID
-		orderProduct.Quantity = order.Products[i].Quantity
-		orderProduct.PriceAtPurchase = order.Products[i].PriceAtPurchase
-		db_order.Products = append(db_order.Products, orderProduct)
-	}
-	err := usecase.repository.CreateOrder(db_order)
-	if err != nil {
-		return fmt.Errorf("failed to create order: %v", err)
+// Create Order - Prices the order from the current product prices and stores it with its products.
+// Prices sent by the client are only used to detect that the client saw stale prices. The order
+// waits in payment_pending until the payment authorized here is captured.
+func (usecase *usecase) CreateOrder(order domain.CreateOrderRequest) (domain.Order, error) {
+	if err := usecase.ensureHotelOpen(order.HotelID); err != nil {
+		return domain.Order{}, err
 	}
-	return nil
+
+	products, err := usecase.priceOrder(order)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	var dbOrder domain.Order
+	dbOrder.UserID = order.UserID
+	dbOrder.HotelID = order.HotelID
+	dbOrder.PhoneNumber = order.PhoneNumber
+	dbOrder.DriveThruCode = order.DriveThruCode
+	dbOrder.OrderStatus = domain.OrderPaymentPending
+	dbOrder.PaymentDueAt = usecase.paymentDueAt()
+	dbOrder.Products = products
+	for _, product := range products {
+		dbOrder.OrderTotal = dbOrder.OrderTotal.Add(product.PriceAtPurchase.Mul(product.Quantity))
+	}
+	if !order.OrderTotal.IsZero() && !matchesPrice(dbOrder.OrderTotal, order.OrderTotal) {
+		return domain.Order{}, domain.OrderPriceMismatch
+	}
+
+	created, err := usecase.repository.CreateOrder(dbOrder)
+	if err != nil {
+		return domain.Order{}, fmt.Errorf("failed to create order: %w", err)
+	}
+	return usecase.startPayment(created)
 }
 
-// Mark Order Completed - Marks an order as completed
-func (usecase *usecase) MarkOrderCompleted(orderID int) error {
-	err := usecase.repository.MarkOrderCompleted(orderID)
+// priceOrder builds the order products at their current price, rejecting products that are
+// unknown, sold by another hotel or listed twice, and client prices that do not match
+func (usecase *usecase) priceOrder(order domain.CreateOrderRequest) ([]domain.OrderProduct, error) {
+	if len(order.Products) == 0 {
+		return nil, domain.InvalidOrderProduct
+	}
+
+	productIDs := make([]int, 0, len(order.Products))
+	seen := make(map[int]bool, len(order.Products))
+	for _, product := range order.Products {
+		if product.Quantity <= 0 || seen[product.ProductID] {
+			return nil, domain.InvalidOrderProduct
+		}
+		seen[product.ProductID] = true
+		productIDs = append(productIDs, product.ProductID)
+	}
+
+	details, err := usecase.repository.GetProductDetails(productIDs)
 	if err != nil {
-		return fmt.Errorf("failed to mark order as completed: %v", err)
+		return nil, fmt.Errorf("failed to get product details: %v", err)
 	}
-	return nil
+	prices := make(map[int]domain.Money, len(details))
+	for _, product := range details {
+		if product.HotelID != order.HotelID {
+			continue
+		}
+		if !product.Available {
+			return nil, domain.ProductUnavailable
+		}
+		prices[int(product.ID)] = product.Price
+	}
+
+	orderProducts := make([]domain.OrderProduct, 0, len(order.Products))
+	for _, product := range order.Products {
+		price, ok := prices[product.ProductID]
+		if !ok {
+			return nil, domain.InvalidOrderProduct
+		}
+		if len(orderProducts) > 0 && !price.SameCurrency(orderProducts[0].PriceAtPurchase) {
+			return nil, domain.CurrencyMismatch
+		}
+		if !product.PriceAtPurchase.IsZero() && !matchesPrice(price, product.PriceAtPurchase) {
+			return nil, domain.OrderPriceMismatch
+		}
+		orderProducts = append(orderProducts, domain.OrderProduct{
+			ProductID:       product.ProductID,
+			Quantity:        product.Quantity,
+			PriceAtPurchase: price,
+		})
+	}
+	return orderProducts, nil
 }
 
-// Get User Orders - Fetches all orders for a user based on user ID
-func (usecase *usecase) GetUserOrders(userID int) ([]domain.OrderResponse, error) {
-	orders, err := usecase.repository.GetUserOrders(userID)
+// Get User Orders - Fetches a page of a user's orders, filtered by status and creation time
+func (usecase *usecase) GetUserOrders(query domain.OrderQuery, page domain.PageRequest) (domain.Page[domain.OrderResponse], error) {
+	if query.Status != "" && !query.Status.IsValid() {
+		return domain.Page[domain.OrderResponse]{}, domain.InvalidOrderStatus
+	}
+	options, err := listOptions(page, []string{domain.SortByID, domain.SortByCreatedAt}, domain.Sort{Field: domain.SortByID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user orders: %v", err)
+		return domain.Page[domain.OrderResponse]{}, err
 	}
+	query.ListOptions = options
 
-	var orderResponses []domain.OrderResponse
+	orders, err := usecase.repository.GetUserOrders(query)
+	if err != nil {
+		return domain.Page[domain.OrderResponse]{}, fmt.Errorf("failed to get user orders: %v", err)
+	}
+	ordersPage := newPage(orders, options,
+		func(order domain.Order) int { return order.ID },
+		func(order domain.Order, field string) string {
+			if field == domain.SortByCreatedAt {
+				return order.CreatedAt.UTC().Format(time.RFC3339Nano)
+			}
+			return ""
+		})
 
-	// Iterate over each order to build the OrderResponse
-	for _, order := range orders {
-		var productIDS []int
+	// Product details and hotels are read once for the whole page
+	var productIDs []int
+	seen := make(map[int]bool)
+	for _, order := range ordersPage.Items {
 		for _, item := range order.Products {
-			productIDS = append(productIDS, item.ProductID)
+			if !seen[item.ProductID] {
+				seen[item.ProductID] = true
+				productIDs = append(productIDs, item.ProductID)
+			}
 		}
-
-		// Get product details for the current order
-		products, err := usecase.repository.GetProductDetails(productIDS)
+	}
+	var products []domain.Product
+	if len(productIDs) > 0 {
+		if products, err = usecase.repository.GetProductDetails(productIDs); err != nil {
+			return domain.Page[domain.OrderResponse]{}, fmt.Errorf("failed to get product details: %v", err)
+		}
+	}
+	catalogue := make(map[int]domain.Product, len(products))
+	for _, product := range products {
+		catalogue[int(product.ID)] = product
+	}
+	hotelNames := make(map[int]string)
+	for _, order := range ordersPage.Items {
+		if _, ok := hotelNames[order.HotelID]; ok {
+			continue
+		}
+		hotel, err := usecase.repository.GetHotelByID(order.HotelID)
 		if err != nil {
-			return []domain.OrderResponse{}, fmt.Errorf("failed to get product details: %v", err)
+			continue
 		}
+		hotelNames[order.HotelID] = hotel.Name
+	}
 
-		var cartProducts []domain.UserCartProduct
-		for _, cartItem := range products {
-			var cartProduct domain.UserCartProduct
-			hotel, err := usecase.repository.GetHotelByID(cartItem.HotelID)
-			if err != nil {
-				continue
-			}
-			cartProduct.ID = cartItem.ID
-			cartProduct.Category = cartItem.Category
-			cartProduct.Name = cartItem.Name
-			cartProduct.HotelName = hotel.Name
-			cartProduct.Price = cartItem.Price
-			cartProduct.StockLeft = cartItem.StockLeft
-
-			cartProducts = append(cartProducts, cartProduct)
+	orderResponses := make([]domain.OrderResponse, 0, len(ordersPage.Items))
+
+	// Iterate over each order to build the OrderResponse
+	for _, order := range ordersPage.Items {
+		// Lines are what was bought, a product since removed from the menu keeps its line
+		lines := make([]domain.OrderLineResponse, 0, len(order.Products))
+		for _, item := range order.Products {
+			product := catalogue[item.ProductID]
+			lines = append(lines, domain.OrderLineResponse{
+				ProductID:       item.ProductID,
+				Name:            product.Name,
+				ImageURL:        product.ImageURL,
+				HotelName:       hotelNames[order.HotelID],
+				Quantity:        item.Quantity,
+				PriceAtPurchase: item.PriceAtPurchase,
+			})
 		}
 
 		// Build the OrderResponse for the current order
 		orderResponse := domain.OrderResponse{
 			ID:          order.ID,
+			UserID:      order.UserID,
+			HotelID:     order.HotelID,
 			PhoneNumber: order.PhoneNumber,
 			OrderTotal:  order.OrderTotal,
 			OrderStatus: order.OrderStatus,
 			IsDelivered: order.IsDelivered,
-			Products:    cartProducts,
+			CreatedAt:   order.CreatedAt,
+			UpdatedAt:   order.UpdatedAt,
+			Products:    lines,
 		}
 
 		// Append the constructed OrderResponse to the list
 		orderResponses = append(orderResponses, orderResponse)
 	}
 
-	return orderResponses, nil
+	return domain.Page[domain.OrderResponse]{Items: orderResponses, NextCursor: ordersPage.NextCursor}, nil
 }
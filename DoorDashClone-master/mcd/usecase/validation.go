@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"mcd/domain"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// Limits matching the column sizes of the users table
+const (
+	maxEmailLength       = 40
+	maxNameLength        = 20
+	minPhoneNumberLength = 10
+	maxPhoneNumberLength = 13
+	minPasswordLength    = 8
+	maxPasswordLength    = 72 // bcrypt ignores anything past 72 bytes
+)
+
+// validateEmail checks that the email is a bare address that fits the users table
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || len(email) > maxEmailLength {
+		return domain.InvalidEmail
+	}
+	return nil
+}
+
+// validatePhoneNumber accepts 10 to 13 characters of digits with an optional leading +
+func validatePhoneNumber(phoneNumber string) error {
+	if len(phoneNumber) < minPhoneNumberLength || len(phoneNumber) > maxPhoneNumberLength {
+		return domain.InvalidPhoneNumber
+	}
+	for i, r := range phoneNumber {
+		if i == 0 && r == '+' {
+			continue
+		}
+		if r < '0' || r > '9' {
+			return domain.InvalidPhoneNumber
+		}
+	}
+	return nil
+}
+
+// validateName checks that the name is present and fits the users table
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" || len(name) > maxNameLength {
+		return domain.InvalidName
+	}
+	return nil
+}
+
+// validatePassword checks the password policy applied to new passwords
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return domain.WeakPassword
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return domain.WeakPassword
+	}
+	return nil
+}
+
+// validateCreateUser runs every registration check and returns the first failure
+func (usecase *usecase) validateCreateUser(request domain.CreateUserRequest) error {
+	checks := []error{
+		validateEmail(request.Email),
+		validatePhoneNumber(request.PhoneNumber),
+		validateName(request.Name),
+		validatePassword(request.Password),
+	}
+	for _, err := range checks {
+		if err != nil {
+			return err
+		}
+	}
+	if request.Role != domain.RoleCustomer && request.Role != domain.RoleRestaurantOwner && request.Role != domain.RoleCourier {
+		return domain.InvalidRole
+	}
+
+	exists, err := usecase.repository.EmailExists(request.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return domain.EmailAlreadyExists
+	}
+	exists, err = usecase.repository.PhoneNumberExists(request.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return domain.PhoneAlreadyExists
+	}
+	return nil
+}
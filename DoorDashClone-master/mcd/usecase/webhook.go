@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mcd/domain"
+	"strings"
+)
+
+// HandlePaymentWebhook - Verifies a gateway event and applies it to its payment and order.
+// Events are recorded once applied, so a redelivered or replayed event ID is reported as a
+// duplicate and changes nothing. Applying an event twice is harmless as well, because every
+// change is conditional on the payment still being in the state the event moves it from.
+func (usecase *usecase) HandlePaymentWebhook(payload []byte, signature string) (bool, error) {
+	event, err := usecase.options.PaymentGateway.VerifyWebhook(payload, signature)
+	if err != nil {
+		var responseErr domain.ResponseError
+		if errors.As(err, &responseErr) {
+			return false, responseErr
+		}
+		return false, domain.InvalidWebhookPayload
+	}
+
+	seen, err := usecase.repository.WebhookEventExists(event.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up webhook event: %v", err)
+	}
+	if seen {
+		return true, nil
+	}
+
+	switch event.Type {
+	case domain.PaymentEventCaptured:
+		err = usecase.applyPaymentCaptured(event)
+	case domain.PaymentEventFailed:
+		err = usecase.applyPaymentFailed(event)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = usecase.repository.CreateWebhookEvent(domain.WebhookEvent{
+		EventID:       event.ID,
+		Type:          event.Type,
+		TransactionID: event.TransactionID,
+		Payload:       payload,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook event: %v", err)
+	}
+	return false, nil
+}
+
+// webhookPayment finds the payment an event is about
+func (usecase *usecase) webhookPayment(event domain.PaymentEvent) (domain.Payment, error) {
+	payment, err := usecase.repository.GetPaymentByTransactionID(event.TransactionID)
+	if err != nil {
+		return payment, domain.PaymentNotFound
+	}
+	return payment, nil
+}
+
+// applyPaymentCaptured places the order of a payment the gateway captured on its own. A
+// capture of another amount than the payment's is refused and logged for someone to look at,
+// the order would otherwise be placed for money that was not taken or keep money it was not owed.
+func (usecase *usecase) applyPaymentCaptured(event domain.PaymentEvent) error {
+	payment, err := usecase.webhookPayment(event)
+	if err != nil {
+		return err
+	}
+	if payment.Status != domain.PaymentAuthorized && payment.Status != domain.PaymentVoidPending && payment.Status != domain.PaymentVoided {
+		return nil
+	}
+	if event.Amount.Amount != payment.Amount.Amount || !strings.EqualFold(event.Amount.Currency, payment.Amount.Currency) {
+		log.Printf("Refusing capture event %s of %d %s for payment %d of %d %s", event.ID,
+			event.Amount.Amount, event.Amount.Currency, payment.ID, payment.Amount.Amount, payment.Amount.Currency)
+		return domain.WebhookAmountMismatch
+	}
+
+	err = usecase.repository.CapturePayment(payment.ID, domain.OrderStatusChange{
+		OrderID:    payment.OrderID,
+		FromStatus: domain.OrderPaymentPending,
+		ToStatus:   domain.OrderPlaced,
+		Reason:     "payment captured by the gateway",
+	}, chargeJournal(payment))
+	if errors.Is(err, domain.OrderStatusConflict) {
+		// Either the capture was already applied, or the order ended and the
+		// repository queued the captured money for refund
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record captured payment: %v", err)
+	}
+	return nil
+}
+
+// applyPaymentFailed cancels the unpaid order of a payment whose authorization failed,
+// which puts its reserved stock back on sale
+func (usecase *usecase) applyPaymentFailed(event domain.PaymentEvent) error {
+	payment, err := usecase.webhookPayment(event)
+	if err != nil {
+		return err
+	}
+	if payment.Status != domain.PaymentAuthorized {
+		return nil
+	}
+
+	err = usecase.repository.CancelOrder(domain.OrderStatusChange{
+		OrderID:    payment.OrderID,
+		FromStatus: domain.OrderPaymentPending,
+		ToStatus:   domain.OrderCancelled,
+		Reason:     "payment failed at the gateway",
+	})
+	if errors.Is(err, domain.OrderStatusConflict) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to cancel order: %v", err)
+	}
+	usecase.voidEndedPayment(payment.OrderID)
+	return nil
+}